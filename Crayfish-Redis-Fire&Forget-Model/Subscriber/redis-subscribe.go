@@ -0,0 +1,101 @@
+// Subscriber for the results the Fire&Forget publisher sends to the "optimization_results" channel.
+// Results are merged per job; since Pub/Sub drops whatever is published while nobody listens, every
+// result says how many its job publishes and a job that stays incomplete for -timeout is written
+// anyway, with the sub-populations that never arrived.
+//
+//	go run ./Subscriber -out results -redis-addr 127.0.0.1:6379
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"crayfish/aggregate"
+	"crayfish/cli"
+	"crayfish/config"
+	"crayfish/logging"
+	"crayfish/wire"
+)
+
+func main() {
+	expect := flag.Int("expect", 0, "results per job when a message doesn't say (older publishers; 0: -k)")
+	timeout := flag.Duration("timeout", 30*time.Second, "write an incomplete job after this long without a result of it")
+	out := flag.String("out", "results", "directory for the merged results (<job>.json)")
+	s := cli.DefaultSettings() // -redis-*, -result-channel and -log-*
+	if err := config.Load(flag.CommandLine, os.Args[1:], &s); err != nil {
+		log.Fatal(err)
+	}
+	cfg := s.Config
+	if err := logging.Setup(s.Logging); err != nil {
+		log.Fatal(err)
+	}
+	if *expect <= 0 {
+		*expect = s.Job.K
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	redisClient, err := cfg.Redis.Client()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = cfg.Retry.Retry(context.Background(), "Connecting to Redis", func() error {
+		return redisClient.Ping().Err()
+	})
+	if err != nil {
+		log.Fatal("Unbale to connect to Redis", err)
+	}
+	slog.Info("Connected to Redis server")
+
+	pubsub := redisClient.Subscribe(cfg.ResultChannel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(); err != nil { // Wait for the subscription before anything is published
+		log.Fatal(err)
+	}
+	slog.Info("Subscribed", "channel", cfg.ResultChannel)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	agg := aggregate.New(*expect, *timeout)
+	messages := pubsub.Channel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				log.Fatal("Subscription closed")
+			}
+			payload := []byte(msg.Payload)
+			result, err := wire.DecodeResult(payload, wire.Sniff(payload))
+			if err != nil {
+				slog.Error("Skipping an undecodable message", "err", err)
+				continue
+			}
+			logging.ForJob(result.JobID).Info("Result received", logging.KeySubPopulation, result.Index, "best_fitness", result.BestFitness)
+			if merged, done := agg.Add(result, time.Now()); done {
+				aggregate.WriteFile(*out, merged)
+			}
+
+		case now := <-ticker.C:
+			for _, merged := range agg.Expire(now) {
+				aggregate.WriteFile(*out, merged)
+			}
+
+		case <-interrupt: // Write what we have before leaving
+			for _, merged := range agg.Flush() {
+				aggregate.WriteFile(*out, merged)
+			}
+			return
+		}
+	}
+}
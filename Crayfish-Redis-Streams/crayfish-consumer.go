@@ -1,138 +1,196 @@
-package main
-
-import (
-	"fmt"
-	"log"
-	"math"
-	"strconv"
-	"strings"
-
-	"github.com/go-redis/redis"
-	"github.com/rs/xid"
-)
-
-func F6(x []float64) float64 {
-	var o float64
-	for _, value := range x {
-		o += math.Pow(math.Abs(value+0.5), 2)
-	}
-	return o
-}
-
-func parseStringSliceToFloatSlice(s string) ([]float64, error) {
-	// Remove the brackets [] from the string
-	s = strings.Trim(s, "[]")
-
-	// Split the string into a slice of strings
-	strs := strings.Split(s, " ")
-
-	// Convert each string in the slice to a float64
-	var result []float64
-	for _, str := range strs {
-		f, err := strconv.ParseFloat(str, 64)
-		if err != nil {
-			return nil, err
-		}
-		result = append(result, f)
-	}
-	return result, nil
-}
-
-func parseOptimizationResults(values map[string]interface{}) (bestFit float64, bestPos []float64, globalCov []float64) {
-	// Parse the values map to extract bestFit, bestPos, and globalCov
-	// Parse bestFit
-	bestFitStr := values["bestFitness"].(string)
-	bestFit, _ = strconv.ParseFloat(bestFitStr, 64)
-
-	// Parse bestPos
-	bestPosStr := values["bestPosition"].(string)
-	bestPos, _ = parseStringSliceToFloatSlice(bestPosStr)
-
-	// Parse globalCov
-	globalCovStr := values["globalCov"].(string)
-	globalCov, _ = parseStringSliceToFloatSlice(globalCovStr)
-	return
-}
-
-func updateOverallResults(overallBestFit *float64, overallBestPos *[]float64, overallGlobalCov *[]float64, bestFit float64, bestPos []float64, globalCov []float64) {
-	// Update overall best fitness and position
-	if bestFit < *overallBestFit {
-		*overallBestFit = bestFit
-		*overallBestPos = make([]float64, len(bestPos))
-		copy(*overallBestPos, bestPos)
-	}
-
-	// Accumulate global convergence values
-	if *overallGlobalCov == nil {
-		*overallGlobalCov = make([]float64, len(globalCov))
-	}
-	for i, cov := range globalCov {
-		(*overallGlobalCov)[i] += cov
-	}
-}
-
-func main() {
-
-	log.Println("Consumer Started")
-
-	redisClient := redis.NewClient(&redis.Options{ // Initialize Redis client
-		Addr: fmt.Sprintf("%s:%s", "127.0.0.1", "6379"),
-	})
-	_, err := redisClient.Ping().Result() // Ping Redis server
-	if err != nil {
-		log.Fatal("Unbale to connect to Redis", err)
-	}
-
-	log.Println("Connected to Redis server")
-
-	subject := "optimization_results"
-	consumersGroup := "optimization-consumer-group"
-
-	// Create consumer group to read from Redis' stream
-	err = redisClient.XGroupCreate(subject, consumersGroup, "0").Err()
-	if err != nil {
-		log.Println(err)
-	}
-	
-	uniqueID := xid.New().String() // Give each consumer a unique consumer ID
-
-	var (
-		overallBestFit   = math.Inf(1)
-		overallBestPos   []float64
-		overallGlobalCov []float64
-	)
-	messageCount := 0
-	totalWorkers := 4 // Number of sub-populations
-
-	for messageCount < totalWorkers {
-		entries, err := redisClient.XReadGroup(&redis.XReadGroupArgs{ // Read results using 'XReadGroup'
-			Group:    consumersGroup,
-			Consumer: uniqueID, // Use uniqueID here
-			Streams:  []string{subject, ">"},
-			Count:    2,
-			Block:    0,
-			NoAck:    false,
-		}).Result()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		// Iterate over each in the entry and process them
-		for _, message := range entries[0].Messages {
-			bestFit, bestPos, globalCov := parseOptimizationResults(message.Values)
-			updateOverallResults(&overallBestFit, &overallBestPos, &overallGlobalCov, bestFit, bestPos, globalCov)
-			redisClient.XAck(subject, consumersGroup, message.ID) // Acknowledge
-			messageCount++
-		}
-	}
-
-	// Average the global convergence values
-	for i := range overallGlobalCov {
-		overallGlobalCov[i] /= float64(totalWorkers)
-	}
-
-	fmt.Println("Overall Best Fitness:", overallBestFit)
-	fmt.Println("Overall Best Position:", overallBestPos)
-	fmt.Println("Overall Global Convergence:", overallGlobalCov)
-
-}
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"crayfish/cli"
+	"crayfish/config"
+	"crayfish/logging"
+	"crayfish/tracing"
+	"crayfish/wire"
+
+	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func F6(x []float64) float64 {
+	var o float64
+	for _, value := range x {
+		o += math.Pow(math.Abs(value+0.5), 2)
+	}
+	return o
+}
+
+const (
+	resultTimeout = 2 * time.Minute // Give up waiting for the stragglers after this long
+	maxMissing    = 1               // Sub-populations we may finish without (the result is then degraded)
+)
+
+func parseOptimizationResults(values map[string]interface{}) (index int, bestFit float64, bestPos []float64, globalCov []float64, err error) {
+	// Decode the entry (entries of the old publisher are still understood)
+	result, err := wire.ResultFromStream(values)
+	if err != nil {
+		return
+	}
+	return result.Index, result.BestFitness, result.BestPosition, result.GlobalConverge, nil
+}
+
+func updateOverallResults(overallBestFit *float64, overallBestPos *[]float64, overallGlobalCov *[]float64, bestFit float64, bestPos []float64, globalCov []float64) {
+	// Update overall best fitness and position
+	if bestFit < *overallBestFit {
+		*overallBestFit = bestFit
+		*overallBestPos = make([]float64, len(bestPos))
+		copy(*overallBestPos, bestPos)
+	}
+
+	// Accumulate global convergence values
+	if *overallGlobalCov == nil {
+		*overallGlobalCov = make([]float64, len(globalCov))
+	}
+	for i, cov := range globalCov {
+		(*overallGlobalCov)[i] += cov
+	}
+}
+
+func main() {
+
+	// -redis-* flags, CRAYFISH_REDIS_* or the redis section of -config, and -k (or job.k) results
+	s := cli.DefaultSettings()
+	if err := config.Load(flag.CommandLine, os.Args[1:], &s); err != nil {
+		log.Fatal(err)
+	}
+	cfg := s.Config
+	if err := logging.Setup(s.Logging); err != nil { // -log-level, -log-format, -worker-id
+		log.Fatal(err)
+	}
+	slog.Info("Consumer started")
+	if s.Tracing.Service == "" {
+		s.Tracing.Service = "crayfish-consumer"
+	}
+	if err := tracing.Setup(context.Background(), s.Tracing); err != nil { // -trace-*
+		log.Fatal(err)
+	}
+	defer tracing.Shutdown(context.Background())
+
+	redisClient, err := cfg.Redis.Client() // Initialize Redis client
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = cfg.Retry.Retry(context.Background(), "Connecting to Redis", func() error {
+		return redisClient.Ping().Err() // Ping Redis server
+	})
+	if err != nil {
+		log.Fatal("Unbale to connect to Redis", err)
+	}
+
+	slog.Info("Connected to Redis server")
+
+	subject := cfg.ResultChannel
+	consumersGroup := "optimization-consumer-group"
+
+	// Create consumer group to read from Redis' stream
+	err = redisClient.XGroupCreate(subject, consumersGroup, "0").Err()
+	if err != nil {
+		slog.Warn("Creating the consumer group", "group", consumersGroup, "err", err)
+	}
+	
+	uniqueID := logging.Worker() // Each consumer has a unique consumer ID (an xid), the one in its logs too
+
+	var (
+		overallBestFit   = math.Inf(1)
+		overallBestPos   []float64
+		overallGlobalCov []float64
+	)
+	messageCount := 0
+	totalWorkers := s.Job.K // Number of sub-populations
+	received := make(map[int]bool, totalWorkers)
+	deadline := time.Now().Add(resultTimeout)
+
+	failures := 0 // Consecutive failed reads, Redis may be restarting
+	for messageCount < totalWorkers && time.Now().Before(deadline) {
+		entries, err := redisClient.XReadGroup(&redis.XReadGroupArgs{ // Read results using 'XReadGroup'
+			Group:    consumersGroup,
+			Consumer: uniqueID, // Use uniqueID here
+			Streams:  []string{subject, ">"},
+			Count:    2,
+			Block:    time.Second, // Not forever, so a hung worker can't keep us here
+			NoAck:    false,
+		}).Result()
+		if err == redis.Nil {
+			failures = 0
+			continue // Nothing new this second
+		}
+		if err != nil {
+			failures++
+			delay := cfg.Retry.Delay(failures)
+			slog.Warn("Reading the results failed", "failures", failures, "err", err, "retry_in", delay)
+			time.Sleep(delay)
+			if strings.HasPrefix(err.Error(), "NOGROUP") { // Redis came back without the group
+				redisClient.XGroupCreateMkStream(subject, consumersGroup, "0")
+			}
+			continue
+		}
+		failures = 0
+
+		// Iterate over each in the entry and process them
+		for _, message := range entries[0].Messages {
+			// Continues the trace of the publisher, whose context is in the entry's fields
+			_, span := tracing.Start(tracing.Extract(context.Background(), tracing.FromFields(message.Values)), "aggregation",
+				trace.WithSpanKind(trace.SpanKindConsumer))
+			index, bestFit, bestPos, globalCov, err := parseOptimizationResults(message.Values)
+			if err != nil {
+				slog.Error("Skipping an undecodable result", "id", message.ID, "err", err)
+				span.End()
+				redisClient.XAck(subject, consumersGroup, message.ID)
+				continue
+			}
+			span.SetAttributes(attribute.Int("subpopulation", index))
+			if received[index] { // A duplicate (re-executed sub-population), the first result wins
+				span.End()
+				redisClient.XAck(subject, consumersGroup, message.ID)
+				continue
+			}
+			received[index] = true
+			slog.Info("Result received", logging.KeySubPopulation, index, "best_fitness", bestFit, "best_position", bestPos)
+			updateOverallResults(&overallBestFit, &overallBestPos, &overallGlobalCov, bestFit, bestPos, globalCov)
+			span.End()
+			redisClient.XAck(subject, consumersGroup, message.ID) // Acknowledge
+			messageCount++
+		}
+	}
+
+	if messageCount < totalWorkers {
+		var missing []int
+		for i := 0; i < totalWorkers; i++ {
+			if !received[i] {
+				missing = append(missing, i)
+			}
+		}
+		if len(missing) > maxMissing || messageCount == 0 {
+			log.Fatalf("Timed out after %s waiting for sub-populations %v", resultTimeout, missing)
+		}
+		slog.Warn("Degraded result: timed out waiting for sub-populations", "missing", missing)
+	}
+
+	// Average the global convergence values (over the results we got)
+	for i := range overallGlobalCov {
+		overallGlobalCov[i] /= float64(messageCount)
+	}
+
+	fmt.Println("Overall Best Fitness:", overallBestFit)
+	fmt.Println("Overall Best Position:", overallBestPos)
+	fmt.Println("Overall Global Convergence:", overallGlobalCov)
+	if messageCount < totalWorkers {
+		fmt.Printf("Degraded: %d of %d sub-populations\n", messageCount, totalWorkers)
+	}
+
+}
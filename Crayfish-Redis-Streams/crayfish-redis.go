@@ -1,241 +1,306 @@
-// This code runs perfectly fine (tested)
-
-package main
-
-import (
-	"fmt"
-	"log"
-	"math"
-	"math/rand"
-
-	"github.com/go-redis/redis"
-)
-
-// Benchmark function F6 - Boundary range [-100,100] --> will remove this and implement benchmark function
-func F6(x []float64) float64 {
-	var o float64
-	for _, value := range x {
-		o += math.Pow(math.Abs(value+0.5), 2)
-	}
-	return o
-}
-
-// Funtion to initialize and divide the population
-func initializePopulation(N, dim, k int, lb, ub []float64) [][][]float64 { // Instead of returning ([]byte, error)
-
-	// Initialize the population N x Dim matrix, X
-	X := make([][]float64, N)
-	for i := 0; i < N; i++ {
-		X[i] = make([]float64, dim)
-	}
-
-	for i := range X {
-		for j := range X[i] {
-			X[i][j] = rand.Float64()*(ub[0]-lb[0]) + lb[0]
-		}
-	}
-
-	// Split the population based on k
-	totalSize := len(X)
-	baseSubPopSize := totalSize / k
-	remainder := totalSize % k
-
-	Xsub := make([][][]float64, k)
-
-	startIndex := 0
-	for i := 0; i < k; i++ {
-		subPopSize := baseSubPopSize
-		if remainder > 0 { // In case the division is not even
-			subPopSize++ // Add one of the remaining individuals to this sub-population
-			remainder--
-		}
-		Xsub[i] = X[startIndex : startIndex+subPopSize]
-		startIndex += subPopSize
-	}
-
-	return Xsub
-
-}
-
-// Equation 4: Mathimatical model of crayfish intake
-func p_obj(x float64) float64 {
-	return 0.2 * (1 / (math.Sqrt(2*math.Pi) * 3)) * math.Exp(-math.Pow(x-25, 2)/(2*math.Pow(3, 2)))
-}
-
-func crayfish(T int, lb, ub []float64, X [][]float64) (x, y []float64) { // return bestFit, bestPos
-
-	N := len(X) // size of the sub-population
-	dim := len(X[0])
-
-	var (
-		globalCov   []float64 = make([]float64, T) // zero row vector of size T
-		BestFitness           = math.Inf(1)
-		BestPos     []float64 = make([]float64, dim)
-		fitnessF    []float64 = make([]float64, N)
-		GlobalPos   []float64 = make([]float64, dim)
-	)
-
-	for i := 0; i < N; i++ {
-		fitnessF[i] = F6(X[i]) // Get the fitness value from the benchmark function
-		if fitnessF[i] < BestFitness {
-			BestFitness = fitnessF[i]
-			copy(BestPos, X[i])
-		}
-	}
-
-	// Update best position to Global position
-	copy(GlobalPos, BestPos)
-	GlobalFitness := BestFitness
-
-	Xf := make([]float64, dim) // For Xshade -- array for the cave
-	Xfood := make([]float64, dim)
-
-	Xnew := make([][]float64, N) // Initializing a 2d array
-	for i := 0; i < N; i++ {
-		Xnew[i] = make([]float64, dim)
-	}
-
-	t := 0
-	for t < T {
-		//Decreasing curve --> Equation 7
-		C := 2 - (float64(t) / float64(T))
-		//Define the temprature from Equation 3
-		tmp := rand.Float64()*15 + 20
-
-		for i := 0; i < dim; i++ { // Calculating the Cave -> Xshade = XL + XG/2
-			Xf[i] = (BestPos[i] + GlobalPos[i]) / 2
-		}
-		copy(Xfood, BestPos) // copy the best position to the Xfood vector
-
-		for i := 0; i < N; i++ {
-			//Xnew[i] = make([]float64, dim) //--> took this part out
-			if tmp > 30 { // Summer resort stage
-				if rand.Float64() < 0.5 {
-					for j := 0; j < dim; j++ { // Equation 6
-						Xnew[i][j] = X[i][j] + C*rand.Float64()*(Xf[j]-X[i][j])
-					}
-				} else { // Competition Stage
-					for j := 0; j < dim; j++ {
-						z := rand.Intn(N) // Random crayfish
-						//z := math.Round(rand.Float64()*(N-1)) + 1 //--> or try this
-						Xnew[i][j] = X[i][j] - X[z][j] + Xf[j] // Equation 8
-					}
-				}
-			} else { // Foraging stage
-				P := 3 * rand.Float64() * fitnessF[i] / F6(Xfood)
-				if P > 2 {
-					//Food is broken down becuase it's too big
-					for j := 0; j < dim; j++ {
-						Xfood[j] *= math.Exp(-1 / P)
-						Xnew[i][j] = X[i][j] + math.Cos(2*math.Pi*rand.Float64())*Xfood[j]*p_obj(tmp) - math.Sin(2*math.Pi*rand.Float64())*Xfood[j]*p_obj(tmp)
-					} // ^^ Equation 13: crayfish foraging
-				} else {
-					for j := 0; j < dim; j++ { // The case where the food is a moderate size
-						Xnew[i][j] = (X[i][j]-Xfood[j])*p_obj(tmp) + p_obj(tmp)*rand.Float64()*X[i][j]
-					}
-				}
-			}
-		}
-
-		// Boundary conditions checks
-		for i := 0; i < N; i++ {
-			for j := 0; j < dim; j++ {
-				if len(ub) == 1 {
-					Xnew[i][j] = math.Min(ub[0], Xnew[i][j])
-					Xnew[i][j] = math.Max(lb[0], Xnew[i][j])
-				} else {
-					Xnew[i][j] = math.Min(ub[j], Xnew[i][j])
-					Xnew[i][j] = math.Max(lb[j], Xnew[i][j])
-				}
-			}
-		}
-
-		//Global update stuff
-		copy(GlobalPos, Xnew[0])
-		GlobalFitness = F6(GlobalPos)
-
-		for i := 0; i < N; i++ {
-			NewFitness := F6(Xnew[i])
-			if NewFitness < GlobalFitness {
-				GlobalFitness = NewFitness
-				copy(GlobalPos, Xnew[i])
-			}
-
-			// Update population to a new location
-			if NewFitness < fitnessF[i] {
-				fitnessF[i] = NewFitness
-				copy(X[i], Xnew[i])
-				if fitnessF[i] < BestFitness {
-					BestFitness = fitnessF[i]
-					copy(BestPos, X[i])
-				}
-			}
-		}
-
-		globalCov[t] = GlobalFitness
-
-		t++
-	}
-
-	return BestPos, globalCov
-}
-
-// Update the publish function to accept optimization results
-func publishOptimizationResults(client *redis.Client, bestPos []float64, bestFit float64, globalCov []float64) error {
-	log.Println("Publishing optimization results to Redis")
-
-	// Convert bestPos and globalCov to strings for Redis
-	bestPosStr := fmt.Sprintf("%v", bestPos)
-	globalCovStr := fmt.Sprintf("%v", globalCov)
-
-	err := client.XAdd(&redis.XAddArgs{ // XAdd method to add data to the Redis stream
-		Stream: "optimization_results", // Stream name
-		ID:     "",
-		Values: map[string]interface{}{
-			"bestPosition": bestPosStr,
-			"bestFitness":  bestFit,
-			"globalCov":    globalCovStr,
-		},
-	}).Err()
-
-	return err
-}
-
-func main() {
-
-	// Crayfish Initialization
-	N, K, T := 20, 4, 4
-	lb := []float64{-100.0}
-	ub := []float64{100.0}
-	dim := 3
-
-
-	// Redis Connection stuff
-	log.Println("Publisher Started")
-
-	redisClient := redis.NewClient(&redis.Options{ // Initialize Redis client and connect to the server at `127.0.0.1:6379'
-		Addr: fmt.Sprintf("%s:%s", "127.0.0.1", "6379"),
-	})
-	_, err := redisClient.Ping().Result() // Ping Redis server to check the connection
-	if err != nil {
-		log.Fatal("Unbale to connect to Redis", err)
-	}
-
-	log.Println("Connected to Redis server")
-
-	// intialize the split population of crayfish
-	X := initializePopulation(N, dim, K, lb, ub)
-
-	// Process each sub-population
-	for _, subPop := range X { // For each sub-population in X
-		bestPos, globalCov := crayfish(T, lb, ub, subPop) // This part will be parallel in Nuclio
-		bestFit := F6(bestPos)
-		// Publish result to Redis
-		err := publishOptimizationResults(redisClient, bestPos, bestFit, globalCov)
-		if err != nil {
-			log.Fatal("Failed to publish results to Redis.\n", err)
-		}
-
-	}
-
-}
+// This code runs perfectly fine (tested)
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+
+	"crayfish/benchmarks"
+	"crayfish/cli"
+	"crayfish/coa"
+	"crayfish/config"
+	"crayfish/logging"
+	"crayfish/tracing"
+	"crayfish/wire"
+
+	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Funtion to initialize and divide the population
+func initializePopulation(job config.Job, F func([]float64) float64, N, dim, k int, lb, ub []float64) [][][]float64 { // Instead of returning ([]byte, error)
+
+	// Initialize the population N x Dim matrix, X, with the job's initializer (-init, -warm-start)
+	seed := job.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	warm, err := job.WarmStartPoints()
+	if err != nil {
+		log.Fatal(err)
+	}
+	initializer, err := coa.NewInitializer(job.Init, seed, F, warm)
+	if err != nil {
+		log.Fatal(err)
+	}
+	X, err := initializer.Initialize(N, dim, lb, ub)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Split the population based on k
+	totalSize := len(X)
+	baseSubPopSize := totalSize / k
+	remainder := totalSize % k
+
+	Xsub := make([][][]float64, k)
+
+	startIndex := 0
+	for i := 0; i < k; i++ {
+		subPopSize := baseSubPopSize
+		if remainder > 0 { // In case the division is not even
+			subPopSize++ // Add one of the remaining individuals to this sub-population
+			remainder--
+		}
+		Xsub[i] = X[startIndex : startIndex+subPopSize]
+		startIndex += subPopSize
+	}
+
+	return Xsub
+
+}
+
+// Equation 4: Mathimatical model of crayfish intake
+func p_obj(x float64) float64 {
+	return 0.2 * (1 / (math.Sqrt(2*math.Pi) * 3)) * math.Exp(-math.Pow(x-25, 2)/(2*math.Pow(3, 2)))
+}
+
+func crayfish(T int, lb, ub []float64, X [][]float64, F benchmarks.FunctionType) (x, y []float64) { // return bestFit, bestPos
+
+	N := len(X) // size of the sub-population
+	dim := len(X[0])
+
+	var (
+		globalCov   []float64 = make([]float64, T) // zero row vector of size T
+		BestFitness           = math.Inf(1)
+		BestPos     []float64 = make([]float64, dim)
+		fitnessF    []float64 = make([]float64, N)
+		GlobalPos   []float64 = make([]float64, dim)
+	)
+
+	for i := 0; i < N; i++ {
+		fitnessF[i] = F(X[i]) // Get the fitness value from the benchmark function
+		if fitnessF[i] < BestFitness {
+			BestFitness = fitnessF[i]
+			copy(BestPos, X[i])
+		}
+	}
+
+	// Update best position to Global position
+	copy(GlobalPos, BestPos)
+	GlobalFitness := BestFitness
+
+	Xf := make([]float64, dim) // For Xshade -- array for the cave
+	Xfood := make([]float64, dim)
+
+	Xnew := make([][]float64, N) // Initializing a 2d array
+	for i := 0; i < N; i++ {
+		Xnew[i] = make([]float64, dim)
+	}
+
+	t := 0
+	for t < T {
+		//Decreasing curve --> Equation 7
+		C := 2 - (float64(t) / float64(T))
+		//Define the temprature from Equation 3
+		tmp := rand.Float64()*15 + 20
+
+		for i := 0; i < dim; i++ { // Calculating the Cave -> Xshade = XL + XG/2
+			Xf[i] = (BestPos[i] + GlobalPos[i]) / 2
+		}
+		copy(Xfood, BestPos) // copy the best position to the Xfood vector
+
+		for i := 0; i < N; i++ {
+			//Xnew[i] = make([]float64, dim) //--> took this part out
+			if tmp > 30 { // Summer resort stage
+				if rand.Float64() < 0.5 {
+					for j := 0; j < dim; j++ { // Equation 6
+						Xnew[i][j] = X[i][j] + C*rand.Float64()*(Xf[j]-X[i][j])
+					}
+				} else { // Competition Stage
+					for j := 0; j < dim; j++ {
+						z := rand.Intn(N) // Random crayfish
+						//z := math.Round(rand.Float64()*(N-1)) + 1 //--> or try this
+						Xnew[i][j] = X[i][j] - X[z][j] + Xf[j] // Equation 8
+					}
+				}
+			} else { // Foraging stage
+				P := 3 * rand.Float64() * fitnessF[i] / F(Xfood)
+				if P > 2 {
+					//Food is broken down becuase it's too big
+					for j := 0; j < dim; j++ {
+						Xfood[j] *= math.Exp(-1 / P)
+						Xnew[i][j] = X[i][j] + math.Cos(2*math.Pi*rand.Float64())*Xfood[j]*p_obj(tmp) - math.Sin(2*math.Pi*rand.Float64())*Xfood[j]*p_obj(tmp)
+					} // ^^ Equation 13: crayfish foraging
+				} else {
+					for j := 0; j < dim; j++ { // The case where the food is a moderate size
+						Xnew[i][j] = (X[i][j]-Xfood[j])*p_obj(tmp) + p_obj(tmp)*rand.Float64()*X[i][j]
+					}
+				}
+			}
+		}
+
+		// Boundary conditions checks
+		for i := 0; i < N; i++ {
+			for j := 0; j < dim; j++ {
+				if len(ub) == 1 {
+					Xnew[i][j] = math.Min(ub[0], Xnew[i][j])
+					Xnew[i][j] = math.Max(lb[0], Xnew[i][j])
+				} else {
+					Xnew[i][j] = math.Min(ub[j], Xnew[i][j])
+					Xnew[i][j] = math.Max(lb[j], Xnew[i][j])
+				}
+			}
+		}
+
+		//Global update stuff
+		copy(GlobalPos, Xnew[0])
+		GlobalFitness = F(GlobalPos)
+
+		for i := 0; i < N; i++ {
+			NewFitness := F(Xnew[i])
+			if NewFitness < GlobalFitness {
+				GlobalFitness = NewFitness
+				copy(GlobalPos, Xnew[i])
+			}
+
+			// Update population to a new location
+			if NewFitness < fitnessF[i] {
+				fitnessF[i] = NewFitness
+				copy(X[i], Xnew[i])
+				if fitnessF[i] < BestFitness {
+					BestFitness = fitnessF[i]
+					copy(BestPos, X[i])
+				}
+			}
+		}
+
+		globalCov[t] = GlobalFitness
+
+		t++
+	}
+
+	return BestPos, globalCov
+}
+
+// Update the publish function to accept optimization results
+func publishOptimizationResults(ctx context.Context, client redis.UniversalClient, stream string, index int, bestPos []float64, bestFit float64, globalCov []float64) error {
+	slog.Debug("Publishing the result to Redis", logging.KeySubPopulation, index)
+
+	// Encode the result with the shared schema (crayfish-core/wire/SCHEMA.md)
+	values, err := wire.ResultStreamValues(wire.Result{
+		Index:          index,
+		BestPosition:   bestPos,
+		BestFitness:    bestFit,
+		GlobalConverge: globalCov,
+	}, wire.ContentTypeJSON)
+	if err != nil {
+		return err
+	}
+	tracing.AddFields(values, tracing.Inject(ctx)) // traceparent and tracestate, for the consumer's span
+
+	err = client.XAdd(&redis.XAddArgs{ // XAdd method to add data to the Redis stream
+		Stream: stream, // Stream name
+		ID:     "",
+		Values: values,
+	}).Err()
+
+	return err
+}
+
+func main() {
+
+	// The job (-f, -n, -k, -t, -dim, -lb, -ub or the job section of -config) and the Redis connection
+	// (the -redis-* flags, CRAYFISH_REDIS_* or the redis section)
+	s := cli.DefaultSettings()
+	s.Job.N, s.Job.T, s.Job.Dim = 20, 4, 3
+	if err := config.Load(flag.CommandLine, os.Args[1:], &s); err != nil {
+		log.Fatal(err)
+	}
+	cfg, job := s.Config, s.Job
+	if err := logging.Setup(s.Logging); err != nil { // -log-level, -log-format
+		log.Fatal(err)
+	}
+	N, K, T := job.N, job.K, job.T
+
+	specs, err := benchmarks.Get(job.Benchmark)
+	if err != nil {
+		log.Fatal(err)
+	}
+	F, lb, ub := specs.Function, specs.LB, specs.UB
+	if len(job.LB) > 0 {
+		lb, ub = job.LB, job.UB
+	}
+	dim := specs.Dim
+	if job.Dim > 0 {
+		dim = job.Dim
+	}
+
+	slog.Info("Publisher started")
+
+	redisClient, err := cfg.Redis.Client() // 127.0.0.1:6379 unless configured otherwise
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = cfg.Retry.Retry(context.Background(), "Connecting to Redis", func() error {
+		return redisClient.Ping().Err() // Ping Redis server to check the connection
+	})
+	if err != nil {
+		log.Fatal("Unbale to connect to Redis ", err)
+	}
+
+	slog.Info("Connected to Redis server")
+
+	// -trace-* flags or the tracing section
+	if s.Tracing.Service == "" {
+		s.Tracing.Service = "crayfish-redis"
+	}
+	if err := tracing.Setup(context.Background(), s.Tracing); err != nil {
+		log.Fatal(err)
+	}
+	defer tracing.Shutdown(context.Background())
+	ctx, span := tracing.Start(context.Background(), "job", trace.WithAttributes(
+		attribute.String("benchmark", job.Benchmark), attribute.Int("subpopulations", K)))
+	defer span.End()
+
+	// intialize the split population of crayfish
+	_, initSpan := tracing.Start(ctx, "population.init")
+	X := initializePopulation(job, F, N, dim, K, lb, ub)
+	initSpan.End()
+
+	// Process each sub-population. A result Redis doesn't take even after the retries is kept and
+	// sent again once the others are out.
+	var pending []func() error
+	for i, subPop := range X { // For each sub-population in X
+		i := i
+		runCtx, run := tracing.Start(ctx, "subpopulation.run", trace.WithAttributes(attribute.Int("subpopulation", i)))
+		bestPos, globalCov := crayfish(T, lb, ub, subPop, F) // This part will be parallel in Nuclio
+		bestFit := F(bestPos)
+		run.SetAttributes(attribute.Float64("best_fitness", bestFit))
+		slog.Info("Sub-population solved", logging.KeySubPopulation, i, "best_fitness", bestFit, "best_position", bestPos)
+		run.End()
+		// Publish result to Redis
+		publish := func() error {
+			return publishOptimizationResults(runCtx, redisClient, cfg.ResultChannel, i, bestPos, bestFit, globalCov)
+		}
+		err := cfg.Retry.Retry(context.Background(), fmt.Sprintf("Publishing result %d", i), publish)
+		if err != nil {
+			slog.Warn("Keeping the result for later", logging.KeySubPopulation, i, "err", err)
+			pending = append(pending, publish)
+		}
+	}
+	for _, publish := range pending {
+		if err := cfg.Retry.Retry(context.Background(), "Publishing a pending result", publish); err != nil {
+			log.Fatal("Failed to publish results to Redis.\n", err)
+		}
+	}
+
+}
@@ -3,11 +3,22 @@ module redis-test
 go 1.21.1
 
 require (
+	crayfish v0.0.0
 	github.com/go-redis/redis v6.15.9+incompatible
-	github.com/rs/xid v1.5.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
 )
 
 require (
-	github.com/onsi/ginkgo v1.16.5 // indirect
-	github.com/onsi/gomega v1.30.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/nxadm/tail v1.4.8 // indirect
+	github.com/rs/xid v1.5.0 // indirect
+	github.com/streadway/amqp v1.1.0 // indirect
+	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace crayfish => ../crayfish-core
@@ -0,0 +1,206 @@
+// Package aggregate merges the results of a job as they arrive from a channel that may lose some
+// (Redis Pub/Sub drops every message published while nobody listens). Results are grouped by job,
+// duplicates are dropped, and a job is merged once all of its results are in, or once it has been
+// quiet for too long, in which case the merge lists the sub-populations that never arrived. A merged
+// job is remembered for a while, so its late results are dropped as duplicates instead of reopening it.
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"crayfish/coa"
+	"crayfish/logging"
+	"crayfish/wire"
+)
+
+// How long a merged job is remembered when the aggregator has no Timeout
+const defaultForget = 10 * time.Minute
+
+// Merged is the combined result of a job
+type Merged struct {
+	JobID          string    `json:"jobId"`
+	Expected       int       `json:"expected"` // 0 when neither the results nor the aggregator knew
+	Received       int       `json:"received"`
+	Missing        []int     `json:"missing,omitempty"` // Indexes that never arrived
+	Duplicates     int       `json:"duplicates,omitempty"`
+	Complete       bool      `json:"complete"`
+	BestFitness    float64   `json:"bestFitness"`
+	BestPosition   []float64 `json:"bestPosition"`
+	Violation      float64   `json:"violation,omitempty"` // Of the constraints at BestPosition, 0 when feasible
+	GlobalConverge []float64 `json:"globalConverge"`      // Averaged over the received results, like the Streams consumer
+	First          time.Time `json:"first"`
+	Last           time.Time `json:"last"`
+}
+
+// Aggregator collects results per job
+type Aggregator struct {
+	Expected int           // Results per job when they don't say (legacy publishers), 0: unknown
+	Timeout  time.Duration // Merge an incomplete job after this long without a result of it
+	Late     int           // Results of jobs already merged, dropped as duplicates
+
+	jobs   map[string]*job
+	merged map[string]time.Time // Tombstones: when each job recently merged was
+}
+
+type job struct {
+	expected   int
+	results    map[int]wire.Result
+	duplicates int
+	first      time.Time
+	last       time.Time
+}
+
+func New(expected int, timeout time.Duration) *Aggregator {
+	return &Aggregator{Expected: expected, Timeout: timeout, jobs: make(map[string]*job), merged: make(map[string]time.Time)}
+}
+
+// Add takes a result in; when it completes its job the merge is returned and the job forgotten
+func (a *Aggregator) Add(r wire.Result, now time.Time) (Merged, bool) {
+	if _, gone := a.merged[r.JobID]; gone {
+		a.Late++
+		return Merged{}, false
+	}
+	j, ok := a.jobs[r.JobID]
+	if !ok {
+		j = &job{expected: a.Expected, results: make(map[int]wire.Result), first: now}
+		a.jobs[r.JobID] = j
+	}
+	j.last = now
+	if r.Workers > 0 {
+		j.expected = r.Workers
+	}
+
+	if _, dup := j.results[r.Index]; dup {
+		j.duplicates++
+		return Merged{}, false
+	}
+	j.results[r.Index] = r
+
+	if j.expected > 0 && len(j.results) >= j.expected {
+		return a.close(r.JobID, j, now), true
+	}
+	return Merged{}, false
+}
+
+// Expire merges (incomplete) the jobs that have been quiet for longer than Timeout, and forgets the
+// jobs merged longer ago than that
+func (a *Aggregator) Expire(now time.Time) []Merged {
+	var expired []Merged
+	for id, j := range a.jobs {
+		if a.Timeout > 0 && now.Sub(j.last) > a.Timeout {
+			expired = append(expired, a.close(id, j, now))
+		}
+	}
+	forget := a.Timeout
+	if forget <= 0 {
+		forget = defaultForget
+	}
+	for id, at := range a.merged {
+		if now.Sub(at) > forget {
+			delete(a.merged, id)
+		}
+	}
+	return expired
+}
+
+// Flush merges every job still open (e.g. on shutdown)
+func (a *Aggregator) Flush() []Merged {
+	var flushed []Merged
+	for id, j := range a.jobs {
+		flushed = append(flushed, a.close(id, j, j.last))
+	}
+	return flushed
+}
+
+// Merge a job and leave a tombstone in its place
+func (a *Aggregator) close(id string, j *job, now time.Time) Merged {
+	delete(a.jobs, id)
+	a.merged[id] = now
+	return merge(id, j)
+}
+
+// Closed reports whether a job was merged recently, its results now being late
+func (a *Aggregator) Closed(jobID string) bool {
+	_, ok := a.merged[jobID]
+	return ok
+}
+
+// Pending is the number of jobs still waiting for results
+func (a *Aggregator) Pending() int {
+	return len(a.jobs)
+}
+
+func merge(id string, j *job) Merged {
+	m := Merged{
+		JobID:       id,
+		Expected:    j.expected,
+		Received:    len(j.results),
+		Duplicates:  j.duplicates,
+		BestFitness: math.Inf(1),
+		Violation:   math.Inf(1),
+		First:       j.first,
+		Last:        j.last,
+	}
+
+	for _, r := range j.results {
+		if coa.Better(r.BestFitness, r.Violation, m.BestFitness, m.Violation) {
+			m.BestFitness, m.Violation = r.BestFitness, r.Violation
+			m.BestPosition = r.BestPosition
+		}
+		// Accumulate global convergence values
+		if len(r.GlobalConverge) > len(m.GlobalConverge) {
+			m.GlobalConverge = append(m.GlobalConverge, make([]float64, len(r.GlobalConverge)-len(m.GlobalConverge))...)
+		}
+		for i, cov := range r.GlobalConverge {
+			m.GlobalConverge[i] += cov
+		}
+	}
+	for i := range m.GlobalConverge {
+		m.GlobalConverge[i] /= float64(m.Received)
+	}
+	if m.Received == 0 {
+		m.Violation = 0
+	}
+
+	for i := 0; i < j.expected; i++ {
+		if _, ok := j.results[i]; !ok {
+			m.Missing = append(m.Missing, i)
+		}
+	}
+	m.Complete = j.expected > 0 && len(m.Missing) == 0
+	return m
+}
+
+// WriteFile writes the merged job to <dir>/<job>.json and prints it, as the consumers of Pub/Sub
+// results do with every job they merge
+func WriteFile(dir string, m Merged) {
+	name := m.JobID
+	if name == "" { // Publishers that don't send a job ID
+		name = fmt.Sprintf("job-%s", m.First.Format("20060102-150405"))
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		logging.ForJob(m.JobID).Error("Encoding the job failed", "err", err)
+		return
+	}
+	path := filepath.Join(dir, filepath.Base(name)+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		logging.ForJob(m.JobID).Error("Writing the job failed", "err", err)
+		return
+	}
+
+	if !m.Complete {
+		logging.ForJob(m.JobID).Warn("Job incomplete", "received", m.Received, "expected", m.Expected, "missing", m.Missing)
+	}
+	fmt.Println("Job:", m.JobID, "written to", path)
+	fmt.Println("Overall Best Fitness:", m.BestFitness)
+	fmt.Println("Overall Best Position:", m.BestPosition)
+	if m.Violation > 0 {
+		fmt.Println("Constraint violation:", m.Violation)
+	}
+}
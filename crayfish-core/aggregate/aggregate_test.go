@@ -0,0 +1,189 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"crayfish/wire"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func result(job string, index, workers int, fitness float64, cov ...float64) wire.Result {
+	return wire.Result{JobID: job, Index: index, Workers: workers, BestFitness: fitness, BestPosition: []float64{fitness}, GlobalConverge: cov}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected int // Of the aggregator
+		results  []wire.Result
+		done     int // Index of the result completing the job, -1 for none
+		want     Merged
+	}{
+		{
+			name:    "complete",
+			results: []wire.Result{result("a", 1, 2, 3), result("a", 0, 2, 1)},
+			done:    1,
+			want:    Merged{JobID: "a", Expected: 2, Received: 2, Complete: true, BestFitness: 1, BestPosition: []float64{1}},
+		},
+		{
+			name:    "duplicate",
+			results: []wire.Result{result("a", 0, 2, 1), result("a", 0, 2, 1), result("a", 1, 2, 2)},
+			done:    2,
+			want:    Merged{JobID: "a", Expected: 2, Received: 2, Duplicates: 1, Complete: true, BestFitness: 1, BestPosition: []float64{1}},
+		},
+		{
+			name:     "legacy publishers",
+			expected: 2,
+			results:  []wire.Result{result("a", 0, 0, 5), result("a", 1, 0, 4)},
+			done:     1,
+			want:     Merged{JobID: "a", Expected: 2, Received: 2, Complete: true, BestFitness: 4, BestPosition: []float64{4}},
+		},
+		{
+			name:    "unknown size",
+			results: []wire.Result{result("a", 0, 0, 5), result("a", 1, 0, 4)},
+			done:    -1,
+		},
+		{
+			name:    "other job",
+			results: []wire.Result{result("a", 0, 2, 1), result("b", 1, 2, 1)},
+			done:    -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(tt.expected, time.Minute)
+			for i, r := range tt.results {
+				got, done := a.Add(r, t0)
+				if done != (i == tt.done) {
+					t.Fatalf("result %d: done = %v", i, done)
+				}
+				if !done {
+					continue
+				}
+				got.First, got.Last = time.Time{}, time.Time{}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("merged %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// A result arriving after its job was merged is late: dropped, not a new job to expire
+func TestAddLate(t *testing.T) {
+	a := New(0, time.Minute)
+	a.Add(result("a", 0, 2, 1), t0)
+	if _, done := a.Add(result("a", 1, 2, 1), t0); !done {
+		t.Fatal("the job didn't complete")
+	}
+	for _, r := range []wire.Result{result("a", 1, 2, 1), result("a", 0, 2, 1)} {
+		if _, done := a.Add(r, t0.Add(time.Second)); done {
+			t.Error("a late result completed the job again")
+		}
+	}
+	if a.Late != 2 || a.Pending() != 0 || !a.Closed("a") {
+		t.Errorf("late %d, pending %d, closed %v; want 2, 0, true", a.Late, a.Pending(), a.Closed("a"))
+	}
+	if expired := a.Expire(t0.Add(30 * time.Second)); len(expired) != 0 {
+		t.Errorf("expired %+v, want nothing", expired)
+	}
+
+	// Forgotten after Timeout, the job ID can be used again
+	a.Expire(t0.Add(2 * time.Minute))
+	if a.Closed("a") {
+		t.Error("the tombstone outlived Timeout")
+	}
+	a.Add(result("a", 0, 1, 1), t0.Add(2*time.Minute))
+	if a.Late != 2 {
+		t.Errorf("late %d after the tombstone went, want 2", a.Late)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	a := New(0, time.Minute)
+	a.Add(result("quiet", 2, 4, 3), t0)
+	a.Add(result("quiet", 0, 4, 2), t0.Add(10*time.Second))
+	a.Add(result("busy", 0, 2, 1), t0.Add(50*time.Second))
+
+	if expired := a.Expire(t0.Add(60 * time.Second)); len(expired) != 0 {
+		t.Fatalf("expired %+v too early", expired)
+	}
+	expired := a.Expire(t0.Add(71 * time.Second))
+	if len(expired) != 1 {
+		t.Fatalf("expired %d jobs, want 1", len(expired))
+	}
+	want := Merged{JobID: "quiet", Expected: 4, Received: 2, Missing: []int{1, 3}, BestFitness: 2, BestPosition: []float64{2},
+		First: t0, Last: t0.Add(10 * time.Second)}
+	if !reflect.DeepEqual(expired[0], want) {
+		t.Errorf("expired %+v, want %+v", expired[0], want)
+	}
+	if a.Pending() != 1 {
+		t.Errorf("pending %d, want 1", a.Pending())
+	}
+
+	// A straggler of the expired job doesn't reopen it
+	a.Add(result("quiet", 1, 4, 0), t0.Add(80*time.Second))
+	if a.Late != 1 || a.Pending() != 1 {
+		t.Errorf("late %d, pending %d; want 1, 1", a.Late, a.Pending())
+	}
+
+	if flushed := a.Flush(); len(flushed) != 1 || flushed[0].JobID != "busy" || a.Pending() != 0 {
+		t.Errorf("flushed %+v, want busy alone", flushed)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	j := &job{expected: 3, results: map[int]wire.Result{
+		0: result("a", 0, 3, 4, 8, 4),
+		2: result("a", 2, 3, 2, 4, 2, 1),
+	}}
+	infeasible := result("a", 1, 3, -1)
+	infeasible.Violation = 0.5
+	j.results[1] = infeasible
+
+	m := merge("a", j)
+	if !m.Complete || m.Received != 3 || m.Missing != nil {
+		t.Errorf("complete %v, received %d, missing %v; want true, 3, none", m.Complete, m.Received, m.Missing)
+	}
+	if m.BestFitness != 2 || m.Violation != 0 || !reflect.DeepEqual(m.BestPosition, []float64{2}) {
+		t.Errorf("best %v at %v (violation %v), want the feasible 2", m.BestFitness, m.BestPosition, m.Violation)
+	}
+	if want := []float64{4, 2, 1.0 / 3}; !reflect.DeepEqual(m.GlobalConverge, want) { // Averaged over the 3 results
+		t.Errorf("convergence %v, want %v", m.GlobalConverge, want)
+	}
+
+	empty := merge("b", &job{expected: 2, results: map[int]wire.Result{}})
+	if empty.Complete || empty.Violation != 0 || !reflect.DeepEqual(empty.Missing, []int{0, 1}) {
+		t.Errorf("empty merge %+v", empty)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	jobs := []struct {
+		merged Merged
+		file   string
+	}{
+		{Merged{JobID: "run-1", Received: 2, Expected: 2, Complete: true, BestFitness: 0.5, BestPosition: []float64{1}}, "run-1.json"},
+		{Merged{JobID: "../away", Received: 1, Expected: 2, Missing: []int{1}}, "away.json"}, // Kept in dir
+		{Merged{First: t0, BestPosition: []float64{}}, "job-20240101-000000.json"},
+	}
+	for _, j := range jobs {
+		WriteFile(dir, j.merged)
+		data, err := os.ReadFile(filepath.Join(dir, j.file))
+		if err != nil {
+			t.Errorf("%q: %v", j.merged.JobID, err)
+			continue
+		}
+		var got Merged
+		if err := json.Unmarshal(data, &got); err != nil || !reflect.DeepEqual(got, j.merged) {
+			t.Errorf("%s: %+v (%v), want %+v", j.file, got, err, j.merged)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package backoff retries the operations that talk to Redis or RabbitMQ with exponentially growing,
+// jittered delays, so a broker blip delays a run instead of ending it and a crowd of workers that
+// lost the same broker doesn't hammer it back in lockstep.
+package backoff
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy of the delays between attempts: Initial, then Multiplier times the previous one up to Max.
+// A Jitter fraction of every delay is drawn at random.
+type Policy struct {
+	Initial    time.Duration `yaml:"initial"`
+	Max        time.Duration `yaml:"max"`
+	Multiplier float64       `yaml:"multiplier"`
+	Jitter     float64       `yaml:"jitter"`   // 0: fixed delays, 1: anywhere between 0 and the delay
+	Attempts   int           `yaml:"attempts"` // 0: until the context is done
+}
+
+// Default is about two minutes of attempts, 100ms apart at first and 30s apart at most
+func Default() Policy {
+	return Policy{
+		Initial:    100 * time.Millisecond,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.5,
+		Attempts:   12,
+	}
+}
+
+// Delay before the given attempt (1 is the first retry)
+func (p Policy) Delay(attempt int) time.Duration {
+	if attempt < 1 || p.Initial <= 0 {
+		return 0
+	}
+	multiplier := max(p.Multiplier, 1)
+	d := float64(p.Initial) * math.Pow(multiplier, float64(attempt-1))
+	if p.Max > 0 {
+		d = min(d, float64(p.Max))
+	}
+	jitter := min(max(p.Jitter, 0), 1)
+	d -= jitter * d * rand.Float64()
+	return time.Duration(d)
+}
+
+// Retry calls op until it succeeds, returns a Permanent error, the attempts are used up or ctx is
+// done. Failures are logged with what, e.g. "publishing result 3".
+func (p Policy) Retry(ctx context.Context, what string, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		var perm *permanent
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if p.Attempts > 0 && attempt >= p.Attempts {
+			return fmt.Errorf("%s: giving up after %d attempts: %w", what, attempt, err)
+		}
+
+		delay := p.Delay(attempt)
+		slog.Warn(what+" failed", "attempt", attempt, "err", err, "retry_in", delay.Round(time.Millisecond))
+		if ctxErr := Sleep(ctx, delay); ctxErr != nil {
+			return fmt.Errorf("%s: %w (last error: %s)", what, ctxErr, err)
+		}
+	}
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type permanent struct {
+	err error
+}
+
+func (p *permanent) Error() string { return p.err.Error() }
+
+func (p *permanent) Unwrap() error { return p.err }
+
+// Permanent marks an error that retrying won't fix (e.g. an unroutable message), Retry returns it
+// right away
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanent{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var perm *permanent
+	return errors.As(err, &perm)
+}
+
+// RegisterFlags binds the policy to -<prefix>initial, -<prefix>max and -<prefix>attempts
+func (p *Policy) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	fs.DurationVar(&p.Initial, prefix+"initial", p.Initial, "first delay between attempts to reach the broker")
+	fs.DurationVar(&p.Max, prefix+"max", p.Max, "longest delay between attempts to reach the broker")
+	fs.IntVar(&p.Attempts, prefix+"attempts", p.Attempts, "attempts to reach the broker before giving up (0: forever)")
+}
@@ -0,0 +1,112 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	want := []time.Duration{0, 100, 200, 400, 800, 1000, 1000} // Milliseconds, from attempt 0
+	for attempt, w := range want {
+		if got := p.Delay(attempt); got != w*time.Millisecond {
+			t.Errorf("attempt %d: %v, want %v", attempt, got, w*time.Millisecond)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		want   time.Duration // Of the fourth attempt
+	}{
+		{"no initial delay", Policy{Max: time.Second, Multiplier: 2}, 0},
+		{"no cap", Policy{Initial: time.Second, Multiplier: 3}, 27 * time.Second},
+		{"multiplier below 1", Policy{Initial: time.Second, Multiplier: 0.5}, time.Second},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Delay(4); got != tt.want {
+			t.Errorf("%s: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A Jitter fraction of the delay is drawn: the delays fall between (1-Jitter) times the delay and
+// the delay itself, and vary
+func TestDelayJitter(t *testing.T) {
+	for _, jitter := range []float64{0.25, 0.5, 1, 3} {
+		p := Policy{Initial: time.Second, Max: 4 * time.Second, Multiplier: 2, Jitter: jitter}
+		lo := time.Duration((1 - min(jitter, 1)) * float64(4*time.Second))
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 1000; i++ {
+			d := p.Delay(5) // Capped at 4s
+			if d < lo || d > 4*time.Second {
+				t.Fatalf("jitter %g: delay %v outside [%v, 4s]", jitter, d, lo)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 100 {
+			t.Errorf("jitter %g: %d different delays of 1000", jitter, len(seen))
+		}
+	}
+	if d := (Policy{Initial: time.Second, Multiplier: 2, Jitter: -1}).Delay(2); d != 2*time.Second {
+		t.Errorf("negative jitter: %v, want 2s", d)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	p := Policy{Initial: time.Microsecond, Max: time.Millisecond, Multiplier: 2, Attempts: 4}
+	errBlip := errors.New("blip")
+
+	calls := 0
+	err := p.Retry(ctx, "flaky", func() error {
+		if calls++; calls < 3 {
+			return errBlip
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("succeeding at the third attempt: %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = p.Retry(ctx, "down", func() error { calls++; return errBlip })
+	if !errors.Is(err, errBlip) || calls != 4 || !strings.Contains(err.Error(), "down: giving up after 4 attempts") {
+		t.Errorf("always failing: %v after %d calls, want 4", err, calls)
+	}
+
+	calls = 0
+	err = p.Retry(ctx, "refused", func() error { calls++; return Permanent(errBlip) })
+	if err != errBlip || calls != 1 || IsPermanent(err) {
+		t.Errorf("permanent: %v after %d calls, want the error unwrapped at once", err, calls)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	forever := Policy{Initial: time.Hour, Multiplier: 2}
+	calls = 0
+	err = forever.Retry(cancelled, "cancelled", func() error { calls++; return errBlip })
+	if !errors.Is(err, context.Canceled) || calls != 1 || !strings.Contains(err.Error(), "blip") {
+		t.Errorf("cancelled: %v after %d calls", err, calls)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	errRefused := errors.New("refused")
+	if Permanent(nil) != nil {
+		t.Error("Permanent(nil) isn't nil")
+	}
+	wrapped := Permanent(errRefused)
+	if !IsPermanent(wrapped) || !errors.Is(wrapped, errRefused) || wrapped.Error() != "refused" {
+		t.Errorf("%v: permanent %v", wrapped, IsPermanent(wrapped))
+	}
+	if IsPermanent(errRefused) {
+		t.Error("a plain error is permanent")
+	}
+	if err := Sleep(context.Background(), time.Microsecond); err != nil {
+		t.Errorf("Sleep: %v", err)
+	}
+}
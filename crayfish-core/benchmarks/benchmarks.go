@@ -0,0 +1,288 @@
+// Package benchmarks holds the test functions of the COA paper and a registry to look them up by name
+package benchmarks
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FunctionType for functions F1, F2, ..., F18
+type FunctionType func([]float64) float64
+
+// FunctionData stores the function along with its bounds and dimension
+type FunctionData struct {
+	Function FunctionType
+	LB       []float64
+	UB       []float64
+	Dim      int
+}
+
+// Registered benchmarks (made all the dimension 500 to match the original paper)
+var registry = map[string]FunctionData{
+	"F1":  {F1, []float64{-100.0}, []float64{100.0}, 500},
+	"F2":  {F2, []float64{-10.0}, []float64{10.0}, 500},
+	"F3":  {F3, []float64{-100.0}, []float64{100.0}, 500},
+	"F4":  {F4, []float64{-100.0}, []float64{100.0}, 500},
+	"F5":  {F5, []float64{-30.0}, []float64{30.0}, 500},
+	"F6":  {F6, []float64{-100.0}, []float64{100.0}, 500},
+	"F7":  {F7, []float64{-1.28}, []float64{1.28}, 500},
+	"F8":  {F8, []float64{-500.0}, []float64{500.0}, 500},
+	"F9":  {F9, []float64{-32.0}, []float64{32.0}, 500},
+	"F10": {F10, []float64{-32.0}, []float64{32.0}, 500},
+	"F11": {F11, []float64{-600.0}, []float64{600.0}, 500},
+	"F16": {F16, []float64{-5.0}, []float64{5.0}, 500},
+	"F17": {F17, []float64{-5.0}, []float64{5.0}, 500},
+	"F18": {F18, []float64{-2.0}, []float64{2.0}, 500},
+
+	// Constrained engineering design problems (see GetConstrained), of a fixed dimension
+	"pressure-vessel": {PressureVessel{}.Objective, []float64{0, 0, 10, 10}, []float64{99, 99, 200, 200}, 4},
+	"welded-beam":     {WeldedBeam{}.Objective, []float64{0.1, 0.1, 0.1, 0.1}, []float64{2, 10, 10, 2}, 4},
+	"spring":          {Spring{}.Objective, []float64{0.05, 0.25, 2}, []float64{2, 1.3, 15}, 3},
+
+	// Multi-objective benchmarks (see GetMulti) by the sum of their objectives
+	"ZDT1":  {sum(ZDT1), []float64{0}, []float64{1}, 30},
+	"ZDT2":  {sum(ZDT2), []float64{0}, []float64{1}, 30},
+	"ZDT3":  {sum(ZDT3), []float64{0}, []float64{1}, 30},
+	"ZDT4":  {sum(ZDT4), []float64{0, -5, -5, -5, -5, -5, -5, -5, -5, -5}, []float64{1, 5, 5, 5, 5, 5, 5, 5, 5, 5}, 10},
+	"ZDT6":  {sum(ZDT6), []float64{0}, []float64{1}, 10},
+	"DTLZ1": {sum(DTLZ1), []float64{0}, []float64{1}, 7},
+	"DTLZ2": {sum(DTLZ2), []float64{0}, []float64{1}, 12},
+}
+
+// Get returns the selected benchmark function
+func Get(name string) (FunctionData, error) {
+	f, ok := registry[name]
+	if !ok {
+		return FunctionData{}, fmt.Errorf("benchmarks: function %q does not exist", name)
+	}
+	return f, nil
+}
+
+// Names lists the registered benchmarks in order (F1, F2, ..., F18, then the others by name)
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, errA := strconv.Atoi(strings.TrimPrefix(names[i], "F"))
+		b, errB := strconv.Atoi(strings.TrimPrefix(names[j], "F"))
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		if errA != nil {
+			return names[i] < names[j]
+		}
+		return a < b
+	})
+	return names
+}
+
+// Dimension of a run of the benchmark asking for dim: the benchmark's when dim is 0, or when its
+// bounds are given per dimension (the engineering problems only have that many variables)
+func (f FunctionData) Dimension(dim int) int {
+	if dim <= 0 || len(f.LB) > 1 {
+		return f.Dim
+	}
+	return dim
+}
+
+// Cigar benchmark
+func BentCigarFunction(x []float64) float64 {
+	if len(x) == 0 {
+		panic("Input slice x must contain at least one element.")
+	}
+	// Initialize sum by squaring the first element x1^(2).
+	sum := x[0] * x[0]
+
+	// Calculate the summation for the squares of the rest of the elements, each multiplied by 10^6
+	for i := 1; i < len(x); i++ {
+		sum += 1e6 * x[i] * x[i] // This is part of the summation from i=2 to the dimension of x.
+	}
+	return sum
+}
+
+// Benchmark function F1 - Boundary range [-100,100]
+func F1(x []float64) float64 {
+	sum := 0.0
+	for _, value := range x {
+		sum += value * value
+	}
+	return sum
+}
+
+// Benchmark function F2 - Boundary range [-10, 10]
+func F2(x []float64) float64 {
+	sum := 0.0
+	product := 1.0
+
+	for _, value := range x {
+		absValue := math.Abs(value)
+		sum += absValue
+		product *= absValue
+	}
+	return sum + product
+}
+
+// Benchmark function F3 - Boundary range [-100,100]
+func F3(x []float64) float64 {
+	dim := len(x)
+	o := 0.0
+
+	for i := 1; i <= dim; i++ {
+		sum := 0.0
+		for j := 0; j < i; j++ {
+			sum += x[j]
+		}
+		o += sum * sum
+	}
+	return o
+}
+
+// Benchmark function F4 - Boundary range [-100, 100]
+func F4(x []float64) float64 {
+	maxVal := math.Abs(x[0])
+	for _, value := range x {
+		absVal := math.Abs(value)
+		if absVal > maxVal {
+			maxVal = absVal
+		}
+	}
+	return maxVal
+}
+
+// Benchmark function F5 - Boundary range [-30,30]
+func F5(x []float64) float64 {
+	dim := len(x)
+	o := 0.0
+
+	for i := 0; i < dim-1; i++ {
+		o += 100*math.Pow(x[i+1]-math.Pow(x[i], 2), 2) + math.Pow(x[i]-1, 2)
+	}
+	return o
+}
+
+// Benchmark function F6 - Boundary range [-100,100]
+func F6(x []float64) float64 {
+	var o float64
+	for _, value := range x {
+		o += math.Pow(math.Abs(value+0.5), 2)
+	}
+	return o
+}
+
+// Benchmark function F7 - Boundary range [-1.28, 1.28]
+func F7(x []float64) float64 {
+	//dim := len(x)
+	var o float64
+
+	for i, value := range x {
+		o += float64(i+1) * math.Pow(value, 4)
+	}
+
+	o += rand.Float64() // Adding a random number
+	return o
+}
+
+// Benchmark function F8 - Boundary range [-500, 500]
+func F8(vec []float64) float64 {
+	sum := 0.0
+	for _, xi := range vec {
+		//sum += (-xi * math.Sin(math.Sqrt(math.Abs(xi))))
+		sum += (-xi * math.Sin(math.Sqrt(math.Abs(xi))))
+	}
+	//return 418.9829*float64(len(vec)) - sum
+	return sum
+}
+
+// Benchmark function F9 - Boundary range [-5.12, 5.12]
+func F9(x []float64) float64 {
+	dim := len(x)
+	o := 0.0
+
+	for _, element := range x {
+		o += math.Pow(element, 2) - 10*math.Cos(2*math.Pi*element)
+	}
+	o += 10 * float64(dim)
+
+	return o
+}
+
+// Benchmark function F10 - Boundary range [-32, 32]
+func F10(x []float64) float64 {
+	dim := len(x)
+	sumOfSquares := 0.0
+	sumOfCos := 0.0
+
+	for _, value := range x {
+		sumOfSquares += value * value
+		sumOfCos += math.Cos(2 * math.Pi * value)
+	}
+
+	eq1 := -20 * math.Exp(-0.2*math.Sqrt(sumOfSquares/float64(dim)))
+	eq2 := -math.Exp(sumOfCos / float64(dim))
+	o := eq1 + eq2 + 20 + math.Exp(1)
+
+	return o
+}
+
+// Benchmark function F10 - Boundar range [-600,600]
+func F11(x []float64) float64 {
+	//dim := len(x)
+	sumOfSquares := 0.0
+	productOfCos := 1.0
+
+	for i, value := range x {
+		sumOfSquares += value * value
+		productOfCos *= math.Cos(value / math.Sqrt(float64(i+1)))
+	}
+
+	o := sumOfSquares/4000 - productOfCos + 1
+
+	return o
+}
+
+// Benchmark function F16 - Bound range [-5, 5]
+func F16(x []float64) float64 {
+	if len(x) < 2 { // Error check
+		return 0.0
+	}
+	x1 := x[0] // x(1) in Matlab
+	x2 := x[1] // x(2) in Matlab
+
+	return 4*math.Pow(x1, 2) - 2.1*math.Pow(x1, 4) + math.Pow(x1, 6)/3 + x1*x2 - 4*math.Pow(x2, 2) + 4*math.Pow(x2, 4)
+}
+
+// Benchmark function F16 - Boundary range [-5,5]
+func F17(x []float64) float64 {
+	if len(x) < 2 {
+		return 0.0 // Error check for these*
+	}
+	x1 := x[0] //*
+	x2 := x[1]
+
+	pi := math.Pi
+	eq1 := x2 - (x1*x1)*5.1/(4*pi*pi) + (5/pi)*x1 - 6
+	eq2 := 10*(1-1/(8*pi))*math.Cos(x1) + 10
+
+	return eq1*eq1 + eq2
+}
+
+// Benchmark function F18 - Boundary range [-2, 2]
+func F18(x []float64) float64 {
+	if len(x) < 2 {
+		return 0.0
+	}
+
+	x1 := x[0]
+	x2 := x[1]
+
+	eq1 := 1 + (x1+x2+1)*(x1+x2+1)*(19-14*x1+3*x1*x1-14*x2+6*x1*x2+3*x2*x2)
+	eq2 := 30 + (2*x1-3*x2)*(2*x1-3*x2)*(18-32*x1+12*x1*x1+48*x2-36*x1*x2+27*x2*x2)
+
+	return eq1 * eq2
+}
@@ -0,0 +1,111 @@
+package benchmarks
+
+import "math"
+
+// Constrained is a problem with constraints besides its bounds: minimize Objective(x) subject to
+// g(x) <= 0 and h(x) = 0 for every value of the vectors Constraints returns
+type Constrained interface {
+	Objective(x []float64) float64
+	Constraints(x []float64) (g, h []float64)
+}
+
+// Problem makes a Constrained out of plain functions, e.g. for a problem of one's own
+type Problem struct {
+	F FunctionType
+	G func(x []float64) (g, h []float64)
+}
+
+func (p Problem) Objective(x []float64) float64 { return p.F(x) }
+
+func (p Problem) Constraints(x []float64) (g, h []float64) { return p.G(x) }
+
+// Registered constrained benchmarks, also in the registry with their objective, bounds and dimension
+var constrained = map[string]Constrained{
+	"pressure-vessel": PressureVessel{},
+	"welded-beam":     WeldedBeam{},
+	"spring":          Spring{},
+}
+
+// GetConstrained returns the constraints of the selected benchmark, false when it has none
+func GetConstrained(name string) (Constrained, bool) {
+	c, ok := constrained[name]
+	return c, ok
+}
+
+// PressureVessel is the design of a cylindrical vessel capped by hemispherical heads at the least
+// cost of material, forming and welding: x = (shell thickness, head thickness, inner radius, length
+// of the cylinder). Best known cost about 5885.33 with continuous thicknesses.
+type PressureVessel struct{}
+
+func (PressureVessel) Objective(x []float64) float64 {
+	return 0.6224*x[0]*x[2]*x[3] + 1.7781*x[1]*x[2]*x[2] + 3.1661*x[0]*x[0]*x[3] + 19.84*x[0]*x[0]*x[2]
+}
+
+func (PressureVessel) Constraints(x []float64) (g, h []float64) {
+	return []float64{
+		-x[0] + 0.0193*x[2],  // Shell thick enough for the radius
+		-x[1] + 0.00954*x[2], // Heads too
+		-math.Pi*x[2]*x[2]*x[3] - 4.0/3*math.Pi*x[2]*x[2]*x[2] + 1296000, // Volume of at least 750 ft³
+		x[3] - 240, // Length
+	}, nil
+}
+
+// WeldedBeam is the design of a beam welded to a support at the least fabrication cost, for a load
+// of 6000 lb at 14 in: x = (weld thickness h, weld length l, bar height t, bar thickness b), bounded
+// in shear stress, bending stress, buckling load and end deflection. Best known cost about 1.724852.
+type WeldedBeam struct{}
+
+const (
+	beamLoad       = 6000.0 // P, lb
+	beamLength     = 14.0   // L, in
+	beamE          = 30e6   // Young's modulus, psi
+	beamG          = 12e6   // Shear modulus, psi
+	beamMaxShear   = 13600.0
+	beamMaxStress  = 30000.0
+	beamMaxDeflect = 0.25
+)
+
+func (WeldedBeam) Objective(x []float64) float64 {
+	return 1.10471*x[0]*x[0]*x[1] + 0.04811*x[2]*x[3]*(14+x[1])
+}
+
+func (WeldedBeam) Constraints(x []float64) (g, h []float64) {
+	tau1 := beamLoad / (math.Sqrt2 * x[0] * x[1])
+	M := beamLoad * (beamLength + x[1]/2)
+	R := math.Sqrt(x[1]*x[1]/4 + math.Pow((x[0]+x[2])/2, 2))
+	J := 2 * math.Sqrt2 * x[0] * x[1] * (x[1]*x[1]/12 + math.Pow((x[0]+x[2])/2, 2))
+	tau2 := M * R / J
+	tau := math.Sqrt(tau1*tau1 + 2*tau1*tau2*x[1]/(2*R) + tau2*tau2)
+	sigma := 6 * beamLoad * beamLength / (x[3] * x[2] * x[2])
+	delta := 4 * beamLoad * math.Pow(beamLength, 3) / (beamE * math.Pow(x[2], 3) * x[3])
+	buckling := 4.013 * beamE * math.Sqrt(x[2]*x[2]*math.Pow(x[3], 6)/36) / (beamLength * beamLength) *
+		(1 - x[2]/(2*beamLength)*math.Sqrt(beamE/(4*beamG)))
+	return []float64{
+		tau - beamMaxShear,
+		sigma - beamMaxStress,
+		x[0] - x[3], // The weld is no thicker than the bar
+		0.10471*x[0]*x[0] + 0.04811*x[2]*x[3]*(14+x[1]) - 5,
+		0.125 - x[0],
+		delta - beamMaxDeflect,
+		beamLoad - buckling,
+	}, nil
+}
+
+// Spring is the design of a tension/compression spring of the least weight: x = (wire diameter d,
+// mean coil diameter D, active coils N), bounded in deflection, shear stress, surge frequency and
+// outer diameter. Best known weight about 0.012665.
+type Spring struct{}
+
+func (Spring) Objective(x []float64) float64 {
+	return (x[2] + 2) * x[1] * x[0] * x[0]
+}
+
+func (Spring) Constraints(x []float64) (g, h []float64) {
+	d, D, N := x[0], x[1], x[2]
+	return []float64{
+		1 - D*D*D*N/(71785*math.Pow(d, 4)),
+		(4*D*D-d*D)/(12566*(D*d*d*d-math.Pow(d, 4))) + 1/(5108*d*d) - 1,
+		1 - 140.45*d/(D*D*N),
+		(d+D)/1.5 - 1,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package benchmarks
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// The best known designs are feasible at the published cost. They sit on the boundary of their
+// active constraints and are published rounded, so a constraint may be off by a rounding's worth.
+func TestConstrainedOptima(t *testing.T) {
+	tests := []struct {
+		name  string
+		x     []float64
+		cost  float64
+		slack float64 // Largest g(x) the rounding of x explains
+	}{
+		{"pressure-vessel", []float64{0.7781686, 0.3846491, 40.3196187, 200}, 5885.3328, 1e-2}, // The volume is in the millions
+		{"welded-beam", []float64{0.205730, 3.470489, 9.036624, 0.205730}, 1.724852, 1e-6},
+		{"spring", []float64{0.051690, 0.356750, 11.287126}, 0.012665, 1e-4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := GetConstrained(tt.name)
+			if !ok {
+				t.Fatal("not a constrained benchmark")
+			}
+			specs, err := Get(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for j, v := range tt.x {
+				if v < specs.LB[j] || v > specs.UB[j] {
+					t.Errorf("x[%d] = %g out of the bounds [%g, %g]", j, v, specs.LB[j], specs.UB[j])
+				}
+			}
+			if f := p.Objective(tt.x); math.Abs(f-tt.cost) > 1e-5*tt.cost {
+				t.Errorf("cost %.7g, published %.7g", f, tt.cost)
+			}
+			if f := specs.Function(tt.x); f != p.Objective(tt.x) {
+				t.Errorf("the registry's function gives %g, the objective %g", f, p.Objective(tt.x))
+			}
+			g, h := p.Constraints(tt.x)
+			for i, gi := range g {
+				if gi > tt.slack {
+					t.Errorf("g%d = %g > 0", i+1, gi)
+				}
+			}
+			if len(h) != 0 {
+				t.Errorf("%d equality constraints, want none", len(h))
+			}
+
+			// The design is on the boundary: anything a bit smaller (and cheaper) is infeasible
+			smaller := make([]float64, len(tt.x))
+			for j, v := range tt.x {
+				smaller[j] = 0.99 * v
+			}
+			g, _ = p.Constraints(smaller)
+			if p.Objective(smaller) >= p.Objective(tt.x) || slices.Max(g) <= tt.slack {
+				t.Errorf("a 1%% smaller design is cheaper and still feasible: g = %v", g)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package benchmarks
+
+import "math"
+
+// MultiFunctionType for the multi-objective benchmarks: every objective at x, all to minimize
+type MultiFunctionType func([]float64) []float64
+
+// MultiObjective is a multi-objective benchmark: its objectives, how many there are, the reference
+// point of the hypervolume and a sample of the true Pareto front for the IGD
+type MultiObjective struct {
+	Objectives MultiFunctionType
+	M          int
+	Reference  []float64
+	Front      func() [][]float64
+}
+
+// Registered multi-objective benchmarks, also in the registry with the sum of their objectives
+var multi = map[string]MultiObjective{
+	"ZDT1":  {ZDT1, 2, []float64{1.1, 1.1}, func() [][]float64 { return zdtFront(func(f float64) float64 { return 1 - math.Sqrt(f) }, 0) }},
+	"ZDT2":  {ZDT2, 2, []float64{1.1, 1.1}, func() [][]float64 { return zdtFront(func(f float64) float64 { return 1 - f*f }, 0) }},
+	"ZDT3":  {ZDT3, 2, []float64{1.1, 1.1}, zdt3Front},
+	"ZDT4":  {ZDT4, 2, []float64{1.1, 1.1}, func() [][]float64 { return zdtFront(func(f float64) float64 { return 1 - math.Sqrt(f) }, 0) }},
+	"ZDT6":  {ZDT6, 2, []float64{1.1, 1.1}, func() [][]float64 { return zdtFront(func(f float64) float64 { return 1 - f*f }, 0.2807753191) }},
+	"DTLZ1": {DTLZ1, 3, []float64{1, 1, 1}, func() [][]float64 { return simplex(3, 30, 0.5) }},
+	"DTLZ2": {DTLZ2, 3, []float64{1.1, 1.1, 1.1}, dtlz2Front},
+}
+
+// GetMulti returns the objectives of the selected benchmark, false when it has a single one
+func GetMulti(name string) (MultiObjective, bool) {
+	m, ok := multi[name]
+	return m, ok
+}
+
+// Sum of the objectives, the one number the parts that need one (e.g. opposition-based
+// initialization, the best position of a result) use for a multi-objective benchmark
+func sum(f MultiFunctionType) FunctionType {
+	return func(x []float64) float64 {
+		var s float64
+		for _, v := range f(x) {
+			s += v
+		}
+		return s
+	}
+}
+
+// Mean of x[from:]
+func tail(x []float64, from int) float64 {
+	var s float64
+	for _, v := range x[from:] {
+		s += v
+	}
+	return s / float64(len(x)-from)
+}
+
+// ZDT1: convex front f2 = 1 - sqrt(f1), boundary range [0, 1]
+func ZDT1(x []float64) []float64 {
+	g := 1 + 9*tail(x, 1)
+	return []float64{x[0], g * (1 - math.Sqrt(x[0]/g))}
+}
+
+// ZDT2: concave front f2 = 1 - f1², boundary range [0, 1]
+func ZDT2(x []float64) []float64 {
+	g := 1 + 9*tail(x, 1)
+	return []float64{x[0], g * (1 - math.Pow(x[0]/g, 2))}
+}
+
+// ZDT3: front of five disconnected pieces, boundary range [0, 1]
+func ZDT3(x []float64) []float64 {
+	g := 1 + 9*tail(x, 1)
+	return []float64{x[0], g * (1 - math.Sqrt(x[0]/g) - x[0]/g*math.Sin(10*math.Pi*x[0]))}
+}
+
+// ZDT4: the front of ZDT1 behind 21⁹ local fronts, x1 in [0, 1] and the others in [-5, 5]
+func ZDT4(x []float64) []float64 {
+	g := 1 + 10*float64(len(x)-1)
+	for _, v := range x[1:] {
+		g += v*v - 10*math.Cos(4*math.Pi*v)
+	}
+	return []float64{x[0], g * (1 - math.Sqrt(x[0]/g))}
+}
+
+// ZDT6: concave front with its solutions thinning out toward f1 = 1, boundary range [0, 1]
+func ZDT6(x []float64) []float64 {
+	f1 := 1 - math.Exp(-4*x[0])*math.Pow(math.Sin(6*math.Pi*x[0]), 6)
+	g := 1 + 9*math.Pow(tail(x, 1), 0.25)
+	return []float64{f1, g * (1 - math.Pow(f1/g, 2))}
+}
+
+// DTLZ1: three objectives on the plane f1 + f2 + f3 = 0.5, behind 11^k - 1 local fronts, boundary
+// range [0, 1]
+func DTLZ1(x []float64) []float64 {
+	g := 0.0
+	for _, v := range x[2:] {
+		g += (v-0.5)*(v-0.5) - math.Cos(20*math.Pi*(v-0.5))
+	}
+	g = 100 * (float64(len(x)-2) + g)
+	return []float64{
+		0.5 * x[0] * x[1] * (1 + g),
+		0.5 * x[0] * (1 - x[1]) * (1 + g),
+		0.5 * (1 - x[0]) * (1 + g),
+	}
+}
+
+// DTLZ2: three objectives on the unit sphere, boundary range [0, 1]
+func DTLZ2(x []float64) []float64 {
+	g := 0.0
+	for _, v := range x[2:] {
+		g += (v - 0.5) * (v - 0.5)
+	}
+	a, b := x[0]*math.Pi/2, x[1]*math.Pi/2
+	return []float64{
+		(1 + g) * math.Cos(a) * math.Cos(b),
+		(1 + g) * math.Cos(a) * math.Sin(b),
+		(1 + g) * math.Sin(a),
+	}
+}
+
+// 1000 points (f1, front(f1)) with f1 evenly spread over [from, 1]
+func zdtFront(front func(float64) float64, from float64) [][]float64 {
+	points := make([][]float64, 1000)
+	for i := range points {
+		f1 := from + (1-from)*float64(i)/float64(len(points)-1)
+		points[i] = []float64{f1, front(f1)}
+	}
+	return points
+}
+
+// The non-dominated points of 1 - sqrt(f1) - f1 sin(10π f1)
+func zdt3Front() [][]float64 {
+	all := zdtFront(func(f float64) float64 { return 1 - math.Sqrt(f) - f*math.Sin(10*math.Pi*f) }, 0)
+	var front [][]float64
+	best := math.Inf(1)
+	for _, p := range all { // From f1 = 0 up, a point is kept when it beats every f2 to its left
+		if p[1] < best {
+			best = p[1]
+			front = append(front, p)
+		}
+	}
+	return front
+}
+
+// The points of the simplex of m coordinates summing to total with h divisions per coordinate
+// (Das and Dennis)
+func simplex(m, h int, total float64) [][]float64 {
+	var points [][]float64
+	var walk func(prefix []int, left int)
+	walk = func(prefix []int, left int) {
+		if len(prefix) == m-1 {
+			p := make([]float64, m)
+			for i, k := range append(prefix, left) {
+				p[i] = total * float64(k) / float64(h)
+			}
+			points = append(points, p)
+			return
+		}
+		for k := 0; k <= left; k++ {
+			walk(append(prefix[:len(prefix):len(prefix)], k), left-k)
+		}
+	}
+	walk(nil, h)
+	return points
+}
+
+// The simplex points pushed onto the unit sphere
+func dtlz2Front() [][]float64 {
+	points := simplex(3, 30, 1)
+	for _, p := range points {
+		norm := math.Sqrt(p[0]*p[0] + p[1]*p[1] + p[2]*p[2])
+		for i := range p {
+			p[i] /= norm
+		}
+	}
+	return points
+}
@@ -0,0 +1,184 @@
+// Package broker hides the message transport behind one interface, so the same publisher and worker
+// code runs over Redis Pub/Sub, Redis Streams, RabbitMQ or an in-process queue (for tests).
+package broker
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"crayfish/backoff"
+	"crayfish/config"
+	"crayfish/tracing"
+	"crayfish/wire"
+)
+
+// Broker moves sub-population tasks to the workers and their results back. Redis Streams, RabbitMQ
+// and the memory broker deliver at least once; Redis Pub/Sub at most once: a message nobody is
+// subscribed to, or that its consumer nacks, is gone.
+type Broker interface {
+	PublishTask(ctx context.Context, t wire.Task) error
+	// ConsumeTasks delivers tasks until ctx is cancelled, every delivery has to be acked or nacked
+	ConsumeTasks(ctx context.Context) (<-chan TaskDelivery, error)
+	PublishResult(ctx context.Context, r wire.Result) error
+	ConsumeResults(ctx context.Context) (<-chan ResultDelivery, error)
+	Close() error
+}
+
+// Acknowledger settles a delivery. Nack with requeue hands it to another consumer, without requeue
+// it is dropped (or dead-lettered where the transport supports it). Redis Pub/Sub drops it either way.
+type Acknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+}
+
+// ResultOutbox is a broker whose results wait in an outbox while it is unreachable (see
+// Config.PendingResults). PublishResultThen calls done once r has really been published, or dropped
+// with the error, so a worker settles its task only when the result can no longer be lost with the
+// process; it returns an error, without calling done, when r is neither published nor kept. A
+// result can still be published twice, when a send the broker took is reported failed and retried.
+type ResultOutbox interface {
+	PublishResultThen(ctx context.Context, r wire.Result, done func(error)) error
+}
+
+// JobResults is a broker that gives the results of a job a destination of their own, so that the
+// coordinators of concurrent jobs never see each other's results. ConsumeJobResults delivers the
+// results published to replyTo, which the job's tasks carry in wire.Task.ReplyTo, until ctx is
+// cancelled; the destination is removed then, later results to it are dropped.
+type JobResults interface {
+	ConsumeJobResults(ctx context.Context, jobID string) (results <-chan ResultDelivery, replyTo string, err error)
+}
+
+// How long the destination of a job's results outlives a coordinator that went away without
+// removing it
+const replyTTL = time.Hour
+
+// Depther is a broker that can tell how many tasks wait in its queue (see metrics.WatchDepth)
+type Depther interface {
+	TaskDepth(ctx context.Context) (int, error)
+}
+
+// TaskDelivery is a task received from the broker
+type TaskDelivery struct {
+	Task  wire.Task
+	Trace map[string]string // Trace context it was published in, see tracing.Extract
+	Acknowledger
+}
+
+// ResultDelivery is a result received from the broker
+type ResultDelivery struct {
+	Result wire.Result
+	Trace  map[string]string
+	Acknowledger
+}
+
+// The trace context a message carries: the one it already has (a requeued message) or the caller's
+func traceOf(ctx context.Context, trace map[string]string) map[string]string {
+	if trace != nil {
+		return trace
+	}
+	return tracing.Inject(ctx)
+}
+
+// Transports understood by New
+const (
+	KindMemory       = "memory"
+	KindRedisPubSub  = "redis-pubsub"
+	KindRedisStreams = "redis-streams"
+	KindRabbitMQ     = "rabbitmq"
+)
+
+// Config selects and configures the transport. It is also the layout of the configuration file
+// (see config.Load): the keys are the yaml tags.
+type Config struct {
+	Kind        string `yaml:"broker"`
+	ContentType string `yaml:"content_type"` // wire.ContentTypeJSON or wire.ContentTypeMsgpack
+
+	Redis         config.Redis `yaml:"redis"`
+	TaskChannel   string       `yaml:"task_channel"`   // Pub/Sub channel or stream of the tasks
+	ResultChannel string       `yaml:"result_channel"` // Pub/Sub channel or stream of the results
+	Group         string       `yaml:"group"`          // Streams consumer group of the workers (results use Group+"-results")
+
+	AMQP     config.AMQP `yaml:"amqp"`
+	Patterns []string    `yaml:"patterns"` // Binding patterns of the worker pool, the tasks no pool's patterns match when empty
+	Prefetch int         `yaml:"prefetch"`
+
+	Retry          backoff.Policy `yaml:"retry"`           // Connecting, reconnecting and publishing tasks
+	PendingResults int            `yaml:"pending_results"` // Results kept while the broker is unreachable, 0: none
+}
+
+// DefaultConfig uses the names the existing programs use (the "optimization_results" channel/stream)
+func DefaultConfig() Config {
+	return Config{
+		Kind:          KindRedisStreams,
+		ContentType:   wire.ContentTypeJSON,
+		Redis:         config.DefaultRedis(),
+		TaskChannel:   "crayfish_tasks",
+		ResultChannel: "optimization_results",
+		Group:         "crayfish-workers",
+		AMQP:          config.DefaultAMQP(),
+		Prefetch:      1,
+		Retry:         backoff.Default(),
+
+		PendingResults: 1000,
+	}
+}
+
+// RegisterFlags binds the configuration to command line flags (-broker selects the transport)
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Kind, "broker", c.Kind, "transport: memory, redis-pubsub, redis-streams or rabbitmq")
+	fs.StringVar(&c.ContentType, "content-type", c.ContentType, "wire encoding: application/json or application/msgpack")
+	c.Redis.RegisterFlags(fs)
+	fs.StringVar(&c.TaskChannel, "task-channel", c.TaskChannel, "Redis channel/stream of the tasks")
+	fs.StringVar(&c.ResultChannel, "result-channel", c.ResultChannel, "Redis channel/stream of the results")
+	fs.StringVar(&c.Group, "group", c.Group, "Redis Streams consumer group")
+	c.AMQP.RegisterFlags(fs)
+	fs.IntVar(&c.Prefetch, "prefetch", c.Prefetch, "RabbitMQ prefetch count")
+	c.Retry.RegisterFlags(fs, "retry-")
+	fs.IntVar(&c.PendingResults, "pending-results", c.PendingResults, "results kept for later while the broker is unreachable (0: fail right away)")
+	fromFile := true // The first -pattern replaces the file's patterns, the next ones add to it
+	fs.Func("pattern", "RabbitMQ binding pattern of the worker pool (repeatable), e.g. coa.*.d500", func(s string) error {
+		if fromFile {
+			c.Patterns, fromFile = nil, false
+		}
+		c.Patterns = append(c.Patterns, s)
+		return nil
+	})
+}
+
+// New connects to the configured transport
+func New(c Config) (Broker, error) {
+	if c.ContentType == "" {
+		c.ContentType = wire.ContentTypeJSON
+	}
+	switch strings.ToLower(c.Kind) {
+	case KindMemory:
+		return NewMemory(), nil
+	case KindRedisPubSub:
+		return NewRedisPubSub(c)
+	case KindRedisStreams:
+		return NewRedisStreams(c)
+	case KindRabbitMQ:
+		return NewRabbitMQ(c)
+	}
+	return nil, fmt.Errorf("broker: unknown transport %q", c.Kind)
+}
+
+// Acknowledger for transports without acknowledgements (Pub/Sub). A nacked message is dropped even
+// when requeue is asked for: publishing it again would hand it to every subscriber, not just one,
+// and a message that always fails would go round forever.
+type noAck struct {
+	kind string // task or result, for the log
+}
+
+func (a noAck) Ack() error { return nil }
+
+func (a noAck) Nack(requeue bool) error {
+	if requeue {
+		slog.Warn("broker: Redis Pub/Sub can't redeliver, dropping the " + a.kind)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package broker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/wire"
+)
+
+// Memory is an in-process broker on channels, for tests and single-machine runs. Consumers compete
+// for the messages like workers on a shared queue.
+type Memory struct {
+	tasks   chan wire.Task
+	results chan wire.Result
+
+	mu      sync.Mutex
+	replies map[string]chan wire.Result // Results of the jobs being consumed on their own, by job
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// Messages a Memory broker holds before publishing blocks
+const memoryBuffer = 1024
+
+func NewMemory() *Memory {
+	return &Memory{
+		tasks:   make(chan wire.Task, memoryBuffer),
+		results: make(chan wire.Result, memoryBuffer),
+		replies: make(map[string]chan wire.Result),
+		done:    make(chan struct{}),
+	}
+}
+
+func (m *Memory) PublishTask(ctx context.Context, t wire.Task) error {
+	t.Trace = traceOf(ctx, t.Trace)
+	select {
+	case m.tasks <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Memory) PublishResult(ctx context.Context, r wire.Result) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	results := m.results
+	if r.ReplyTo != "" {
+		m.mu.Lock()
+		results = m.replies[r.ReplyTo]
+		m.mu.Unlock()
+		if results == nil {
+			slog.Warn("broker: dropping the result of a job nobody waits for", logging.KeyJob, r.JobID, logging.KeySubPopulation, r.Index)
+			return nil
+		}
+	}
+	select {
+	case results <- r:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Memory) ConsumeTasks(ctx context.Context) (<-chan TaskDelivery, error) {
+	out := make(chan TaskDelivery)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case t := <-m.tasks:
+				d := TaskDelivery{Task: t, Trace: t.Trace, Acknowledger: &memoryAck{requeue: func() { m.tasks <- t }}}
+				select {
+				case out <- d:
+				case <-ctx.Done():
+					m.tasks <- t // Nobody took it, put it back
+					return
+				}
+			case <-ctx.Done():
+				return
+			case <-m.done:
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (m *Memory) ConsumeResults(ctx context.Context) (<-chan ResultDelivery, error) {
+	return m.consumeResults(ctx, m.results, func() {}), nil
+}
+
+// ConsumeJobResults consumes the results of one job, replyTo is the job's ID
+func (m *Memory) ConsumeJobResults(ctx context.Context, jobID string) (<-chan ResultDelivery, string, error) {
+	results := make(chan wire.Result, memoryBuffer)
+	m.mu.Lock()
+	m.replies[jobID] = results
+	m.mu.Unlock()
+	return m.consumeResults(ctx, results, func() {
+		m.mu.Lock()
+		delete(m.replies, jobID)
+		m.mu.Unlock()
+	}), jobID, nil
+}
+
+// Deliver the results of the channel until ctx is cancelled, then call stop
+func (m *Memory) consumeResults(ctx context.Context, results chan wire.Result, stop func()) <-chan ResultDelivery {
+	out := make(chan ResultDelivery)
+	go func() {
+		defer close(out)
+		defer stop()
+		for {
+			select {
+			case r := <-results:
+				d := ResultDelivery{Result: r, Trace: r.Trace, Acknowledger: &memoryAck{requeue: func() { results <- r }}}
+				select {
+				case out <- d:
+				case <-ctx.Done():
+					results <- r
+					return
+				}
+			case <-ctx.Done():
+				return
+			case <-m.done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// TaskDepth is the number of tasks in the queue
+func (m *Memory) TaskDepth(ctx context.Context) (int, error) {
+	return len(m.tasks), nil
+}
+
+// Close stops the consumers, messages still queued are dropped
+func (m *Memory) Close() error {
+	m.closeOnce.Do(func() { close(m.done) })
+	return nil
+}
+
+type memoryAck struct {
+	requeue func()
+}
+
+func (a *memoryAck) Ack() error { return nil }
+
+func (a *memoryAck) Nack(requeue bool) error {
+	if requeue {
+		metrics.Redeliveries.WithLabelValues(KindMemory).Inc()
+		go a.requeue() // Don't block the consumer on a full queue
+	}
+	return nil
+}
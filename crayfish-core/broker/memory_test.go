@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"crayfish/wire"
+)
+
+// The results of a job reach its own consumer and nobody else's; once it is gone they are dropped
+func TestMemoryJobResults(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	m := NewMemory()
+	defer m.Close()
+
+	shared, err := m.ConsumeResults(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobCtx, stop := context.WithCancel(ctx)
+	own, replyTo, err := m.ConsumeJobResults(jobCtx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.PublishResult(ctx, wire.Result{JobID: "job", Index: 1, ReplyTo: replyTo}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.PublishResult(ctx, wire.Result{JobID: "other", Index: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		results <-chan ResultDelivery
+		index   int
+	}{
+		{"job", own, 1},
+		{"shared", shared, 2},
+	} {
+		select {
+		case d := <-tt.results:
+			d.Ack()
+			if d.Result.Index != tt.index {
+				t.Errorf("%s results: got result %d, want %d", tt.name, d.Result.Index, tt.index)
+			}
+		case <-ctx.Done():
+			t.Fatalf("%s results: nothing received", tt.name)
+		}
+	}
+
+	stop()
+	for range own { // Closed once the consumer stopped
+	}
+	if err := m.PublishResult(ctx, wire.Result{JobID: "job", Index: 3, ReplyTo: replyTo}); err != nil {
+		t.Errorf("publishing to a gone job: %v, want the result dropped", err)
+	}
+	select {
+	case d := <-shared:
+		t.Errorf("result %d of the gone job reached the shared results", d.Result.Index)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,157 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"crayfish/backoff"
+	"crayfish/logging"
+	"crayfish/wire"
+)
+
+// ErrOutboxFull is returned by PublishResult when the broker has been unreachable for so long that
+// Config.PendingResults results are already waiting for it
+var ErrOutboxFull = errors.New("broker: too many results are waiting for the broker")
+
+// outbox keeps the results that couldn't be published while the broker was unreachable and sends
+// them again, oldest first, once it answers. Without it a worker that loses its connection right
+// after solving a sub-population would have to throw the work away.
+//
+// Delivery is at least once: a send that failed after the broker took the result (a timeout on the
+// confirm, a connection lost before the reply) is sent again, and a worker that dies before acking
+// the task it solved has it solved again elsewhere. The coordinator keeps the first result of every
+// sub-population and acks the others.
+type outbox struct {
+	limit int
+	retry backoff.Policy
+	send  func(wire.Result) error
+
+	mu      sync.Mutex
+	pending []pendingResult
+	wake    chan struct{}
+
+	flushing sync.Mutex // One flush at a time, so nothing is sent twice
+	stop     context.CancelFunc
+}
+
+// A result waiting for the broker, done (when set) is told once it left or was dropped
+type pendingResult struct {
+	r    wire.Result
+	done func(error)
+}
+
+func newOutbox(limit int, retry backoff.Policy, send func(wire.Result) error) *outbox {
+	ctx, stop := context.WithCancel(context.Background())
+	o := &outbox{limit: limit, retry: retry, send: send, wake: make(chan struct{}, 1), stop: stop}
+	go o.run(ctx)
+	return o
+}
+
+// publish sends r, or keeps it for later when the failure isn't r's fault. done, when set, is called
+// once r is published or dropped; not when publish fails, r then being neither.
+func (o *outbox) publish(r wire.Result, done func(error)) error {
+	if o.len() == 0 { // Otherwise the broker is known to be away, queue behind the others
+		err := o.send(r)
+		if err == nil && done != nil {
+			done(nil)
+		}
+		if err == nil || o.limit <= 0 || backoff.IsPermanent(err) {
+			return err
+		}
+		slog.Warn("broker: keeping the result until the broker is back", logging.KeyJob, r.JobID, logging.KeySubPopulation, r.Index, "err", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.pending) >= o.limit {
+		return ErrOutboxFull
+	}
+	o.pending = append(o.pending, pendingResult{r, done})
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (o *outbox) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Send the pending results whenever some are added, backing off while the broker is away
+func (o *outbox) run(ctx context.Context) {
+	for {
+		select {
+		case <-o.wake:
+		case <-ctx.Done():
+			return
+		}
+		for attempt := 1; ; attempt++ {
+			left, err := o.flush()
+			if left == 0 {
+				break
+			}
+			delay := o.retry.Delay(attempt)
+			slog.Warn("broker: results still waiting", "results", left, "err", err, "retry_in", delay.Round(time.Millisecond))
+			if backoff.Sleep(ctx, delay) != nil {
+				return
+			}
+		}
+	}
+}
+
+// Send the pending results in order until one fails, returns how many are left
+func (o *outbox) flush() (int, error) {
+	o.flushing.Lock()
+	defer o.flushing.Unlock()
+	for {
+		o.mu.Lock()
+		if len(o.pending) == 0 {
+			o.mu.Unlock()
+			return 0, nil
+		}
+		p := o.pending[0]
+		o.mu.Unlock()
+
+		err := o.send(p.r)
+		if err != nil && !backoff.IsPermanent(err) {
+			return o.len(), err
+		}
+		if err != nil {
+			slog.Error("broker: dropping a result", logging.KeyJob, p.r.JobID, logging.KeySubPopulation, p.r.Index, "err", err)
+		}
+		o.mu.Lock()
+		o.pending = o.pending[1:]
+		o.mu.Unlock()
+		if p.done != nil {
+			p.done(err)
+		}
+	}
+}
+
+// close stops the background sends after a last attempt, reporting the results that never left
+// (their done is told so)
+func (o *outbox) close() error {
+	o.stop()
+	left, err := o.flush()
+	if left == 0 {
+		return nil
+	}
+	err = fmt.Errorf("broker: %d results were never published: %w", left, err)
+	o.mu.Lock()
+	pending := o.pending
+	o.pending = nil
+	o.mu.Unlock()
+	for _, p := range pending {
+		if p.done != nil {
+			p.done(err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package broker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"crayfish/backoff"
+	"crayfish/wire"
+)
+
+// A result kept while the broker is away is only reported done once it was really sent
+func TestOutboxDoneAfterFlush(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		down = true
+		sent []int
+	)
+	send := func(r wire.Result) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			return errors.New("connection refused")
+		}
+		sent = append(sent, r.Index)
+		return nil
+	}
+	o := newOutbox(10, backoff.Policy{Initial: time.Millisecond, Max: time.Millisecond}, send)
+	defer o.close()
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		if err := o.publish(wire.Result{Index: i}, func(err error) { done <- err }); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("done called (%v) while the broker is down", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("done: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the outbox never flushed")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 || sent[0] != 0 || sent[1] != 1 {
+		t.Errorf("sent %v, want [0 1]", sent)
+	}
+}
+
+// Results still waiting when the outbox closes are reported done with an error
+func TestOutboxCloseReportsLeftovers(t *testing.T) {
+	o := newOutbox(10, backoff.Policy{Initial: time.Hour, Max: time.Hour}, func(wire.Result) error {
+		return errors.New("connection refused")
+	})
+	var got error
+	if err := o.publish(wire.Result{}, func(err error) { got = err }); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.close(); err == nil {
+		t.Error("close: want an error for the result never published")
+	}
+	if got == nil {
+		t.Error("done: want an error for the result never published")
+	}
+}
@@ -0,0 +1,522 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"crayfish/backoff"
+	"crayfish/config"
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/tracing"
+	"crayfish/wire"
+
+	"github.com/streadway/amqp"
+)
+
+// Names shared with the programs in go-rabbitmq-broker
+const (
+	amqpTaskQueue          = "testQueue"
+	amqpResultQueue        = "resultQueue"
+	amqpDeadLetterExchange = "crayfish-dlx"
+	amqpDeadLetterQueue    = "testQueue.dead"
+	amqpTaskExchange       = "crayfish-tasks"
+	amqpUnroutedExchange   = "crayfish-tasks.unrouted" // Alternate exchange of the tasks no pattern matches
+)
+
+// errUnroutable is the error of a message no queue took
+var errUnroutable = errors.New("broker: no queue is bound")
+
+// RabbitMQ publishes tasks to the topic exchange (routing key "<algorithm>.<function>.d<dim>") with
+// publisher confirms, consumes them from the worker pool's durable queue with manual acks and
+// collects the results on a durable result queue. A lost connection is dialed again with backoff,
+// the consumers are registered again on the new one and results wait in an outbox meanwhile.
+type RabbitMQ struct {
+	dial        config.AMQP
+	retry       backoff.Policy
+	contentType string
+	patterns    []string
+	prefetch    int
+
+	publishRetries int
+	confirmTimeout time.Duration
+
+	ctx  context.Context // Done once the broker is closed
+	stop context.CancelFunc
+
+	connMu sync.Mutex
+	conn   *amqp.Connection
+
+	mu       sync.Mutex    // Serializes publishes so every confirm matches its message
+	pubCh    *amqp.Channel // nil after it was lost, a new one is opened by the next publish
+	confirms chan amqp.Confirmation
+	returns  chan amqp.Return
+	tag      uint64
+
+	outbox *outbox
+}
+
+func NewRabbitMQ(c Config) (*RabbitMQ, error) {
+	b := &RabbitMQ{
+		dial:           c.AMQP,
+		retry:          c.Retry,
+		contentType:    c.ContentType,
+		patterns:       c.Patterns,
+		prefetch:       c.Prefetch,
+		publishRetries: max(c.AMQP.PublishRetries, 1),
+		confirmTimeout: c.AMQP.ConfirmTimeout,
+	}
+	if b.confirmTimeout <= 0 {
+		b.confirmTimeout = 5 * time.Second
+	}
+	b.ctx, b.stop = context.WithCancel(context.Background())
+
+	if err := b.retry.Retry(b.ctx, "broker: connecting to RabbitMQ", b.connect); err != nil {
+		b.stop()
+		return nil, fmt.Errorf("broker: failed to connect to RabbitMQ: %w", err)
+	}
+	b.outbox = newOutbox(c.PendingResults, c.Retry, b.sendResult)
+	go b.supervise()
+	return b, nil
+}
+
+// Dial and open the publishing channel
+func (b *RabbitMQ) connect() error {
+	conn, err := b.dial.Dial()
+	if err != nil {
+		return err
+	}
+	ch, err := openPublisher(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	b.connMu.Lock()
+	b.conn = conn
+	b.connMu.Unlock()
+	b.mu.Lock()
+	b.usePublisher(ch)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *RabbitMQ) connection() *amqp.Connection {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	return b.conn
+}
+
+// Dial again, with backoff and for as long as it takes, whenever the connection is lost
+func (b *RabbitMQ) supervise() {
+	forever := b.retry
+	forever.Attempts = 0
+	for {
+		lost := b.connection().NotifyClose(make(chan *amqp.Error, 1))
+		select {
+		case err := <-lost:
+			if b.ctx.Err() != nil {
+				return
+			}
+			slog.Warn("broker: lost the RabbitMQ connection", "err", err)
+		case <-b.ctx.Done():
+			return
+		}
+
+		if err := forever.Retry(b.ctx, "broker: reconnecting to RabbitMQ", b.connect); err != nil {
+			if b.ctx.Err() == nil { // Not closed meanwhile but refused for good
+				slog.Error("broker: giving up on RabbitMQ", "err", err)
+			}
+			return
+		}
+		slog.Info("broker: reconnected to RabbitMQ")
+	}
+}
+
+// Declare the exchanges and queues and open a channel in confirm mode for the publishes
+func openPublisher(conn *amqp.Connection) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := declare(ch); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	if err = ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+func declare(ch *amqp.Channel) error {
+	if err := ch.ExchangeDeclare(amqpDeadLetterExchange, "fanout", true, false, false, false, nil); err != nil {
+		return err
+	}
+	if _, err := ch.QueueDeclare(amqpDeadLetterQueue, true, false, false, false, nil); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(amqpDeadLetterQueue, "", amqpDeadLetterExchange, false, nil); err != nil {
+		return err
+	}
+	if err := ch.ExchangeDeclare(amqpUnroutedExchange, "fanout", true, false, false, false, nil); err != nil {
+		return err
+	}
+	taskArgs := amqp.Table{"alternate-exchange": amqpUnroutedExchange}
+	if err := ch.ExchangeDeclare(amqpTaskExchange, "topic", true, false, false, false, taskArgs); err != nil {
+		return taskExchangeError(err)
+	}
+	_, err := ch.QueueDeclare(amqpResultQueue, true, false, false, false, nil)
+	return err
+}
+
+// RabbitMQ refuses (406 PRECONDITION_FAILED) to declare an existing exchange with other arguments:
+// the task exchange of a version without worker pools has no alternate exchange. No retry fixes
+// that, the exchange has to be deleted once.
+func taskExchangeError(err error) error {
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed {
+		return backoff.Permanent(fmt.Errorf("broker: exchange %q was declared by an older version without the alternate exchange %q, "+
+			"delete it once (rabbitmqadmin delete exchange name=%s): %w", amqpTaskExchange, amqpUnroutedExchange, amqpTaskExchange, err))
+	}
+	return err
+}
+
+// Publish on ch from now on (b.mu held)
+func (b *RabbitMQ) usePublisher(ch *amqp.Channel) {
+	if b.pubCh != nil {
+		b.pubCh.Close()
+	}
+	b.pubCh = ch
+	b.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	b.returns = ch.NotifyReturn(make(chan amqp.Return, 1))
+	b.tag = 0
+}
+
+// Publish a persistent message and wait for the broker to confirm it, with backoff while the
+// connection is down
+func (b *RabbitMQ) publish(ctx context.Context, exchange, key, contentType string, headers amqp.Table, body []byte) error {
+	return b.retry.Retry(ctx, "broker: publishing to "+key, func() error {
+		return b.publishOnce(exchange, key, contentType, headers, body)
+	})
+}
+
+// Publish and wait for the confirm, nacks and timeouts are retried right away
+func (b *RabbitMQ) publishOnce(exchange, key, contentType string, headers amqp.Table, body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pubCh == nil {
+		ch, err := openPublisher(b.connection())
+		if err != nil {
+			return err
+		}
+		b.usePublisher(ch)
+	}
+
+	msg := amqp.Publishing{Headers: headers, ContentType: contentType, DeliveryMode: amqp.Persistent, Body: body}
+	for attempt := 1; attempt <= b.publishRetries; attempt++ {
+		if err := b.pubCh.Publish(exchange, key, true, false, msg); err != nil {
+			b.pubCh.Close()
+			b.pubCh = nil
+			return err
+		}
+		b.tag++
+
+		acked, returned, closed := b.waitConfirm(b.tag)
+		if returned {
+			return backoff.Permanent(fmt.Errorf("%w to %q for routing key %q", errUnroutable, exchange, key))
+		}
+		if acked {
+			return nil
+		}
+		if closed {
+			b.pubCh = nil
+			return fmt.Errorf("broker: channel closed before the message to %q was confirmed", key)
+		}
+		time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+	}
+	return fmt.Errorf("broker: message to %q was not confirmed after %d attempts", key, b.publishRetries)
+}
+
+func (b *RabbitMQ) waitConfirm(tag uint64) (acked, returned, closed bool) {
+	timeout := time.After(b.confirmTimeout)
+	for {
+		select {
+		case <-b.returns:
+			returned = true
+		case c, ok := <-b.confirms:
+			if !ok {
+				return false, returned, true
+			}
+			if c.DeliveryTag == tag {
+				return c.Ack, returned, false
+			}
+		case <-timeout:
+			return false, returned, false
+		}
+	}
+}
+
+// TaskRoutingKey is the topic a task is published under, e.g. "coa.F6.d500", from its header: the
+// seed form carries no sub-population to measure
+func TaskRoutingKey(t wire.Task) string {
+	algorithm := t.Algorithm
+	if algorithm == "" {
+		algorithm = wire.AlgorithmCOA
+	}
+	dim := t.Dim
+	if dim == 0 && len(t.SubPopulation) > 0 {
+		dim = len(t.SubPopulation[0])
+	}
+	return fmt.Sprintf("%s.%s.d%d", algorithm, t.Function, dim)
+}
+
+func (b *RabbitMQ) PublishTask(ctx context.Context, t wire.Task) error {
+	headers := tracing.Table(traceOf(ctx, t.Trace))
+	t.Trace = nil // In the headers instead
+	body, err := wire.EncodeTask(t, b.contentType)
+	if err != nil {
+		return err
+	}
+	return b.publish(ctx, amqpTaskExchange, TaskRoutingKey(t), b.contentType, headers, body)
+}
+
+func (b *RabbitMQ) PublishResult(ctx context.Context, r wire.Result) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	return b.outbox.publish(r, nil)
+}
+
+func (b *RabbitMQ) PublishResultThen(ctx context.Context, r wire.Result, done func(error)) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	return b.outbox.publish(r, done)
+}
+
+func (b *RabbitMQ) sendResult(r wire.Result) error {
+	headers := tracing.Table(r.Trace)
+	r.Trace = nil
+	body, err := wire.EncodeResult(r, b.contentType)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	if r.ReplyTo == "" {
+		return b.publishOnce("", amqpResultQueue, b.contentType, headers, body)
+	}
+	err = b.publishOnce("", r.ReplyTo, b.contentType, headers, body)
+	if errors.Is(err, errUnroutable) { // The job's queue was deleted with its coordinator
+		slog.Warn("broker: dropping the result of a job nobody waits for", logging.KeyJob, r.JobID, logging.KeySubPopulation, r.Index)
+		return nil
+	}
+	return err
+}
+
+// Open a consuming channel on the queue (declared by declare first, when given)
+func (b *RabbitMQ) openConsumer(queue string, declare func(*amqp.Channel) error) (*amqp.Channel, <-chan amqp.Delivery, error) {
+	ch, err := b.connection().Channel()
+	if err != nil {
+		return nil, nil, err
+	}
+	if declare != nil {
+		if err = declare(ch); err != nil {
+			ch.Close()
+			return nil, nil, err
+		}
+	}
+	if err = ch.Qos(b.prefetch, 0, false); err != nil {
+		ch.Close()
+		return nil, nil, err
+	}
+	msgs, err := ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, nil, err
+	}
+	return ch, msgs, nil
+}
+
+// Hand every delivery of the queue to deliver until ctx is cancelled. A consumer whose channel or
+// connection is lost is registered again once the connection is back; the deliveries it hadn't
+// acked are redelivered by RabbitMQ.
+func (b *RabbitMQ) consume(ctx context.Context, queue string, declare func(*amqp.Channel) error, deliver func(amqp.Delivery), stop func()) error {
+	ch, msgs, err := b.openConsumer(queue, declare)
+	if err != nil {
+		return err
+	}
+
+	forever := b.retry
+	forever.Attempts = 0
+	go func() {
+		defer stop()
+		for {
+			drain(ctx, msgs, deliver)
+			ch.Close()
+			if ctx.Err() != nil || b.ctx.Err() != nil {
+				return
+			}
+
+			slog.Warn("broker: consumer lost its channel, registering it again", "queue", queue)
+			err := forever.Retry(ctx, "broker: consuming "+queue, func() error {
+				if b.ctx.Err() != nil {
+					return backoff.Permanent(b.ctx.Err())
+				}
+				var err error
+				ch, msgs, err = b.openConsumer(queue, declare)
+				return err
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Deliver until the channel closes or ctx is cancelled
+func drain(ctx context.Context, msgs <-chan amqp.Delivery, deliver func(amqp.Delivery)) {
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			if msg.Redelivered {
+				metrics.Redeliveries.WithLabelValues(KindRabbitMQ).Inc()
+			}
+			deliver(msg)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Queue of the worker pool (see go-rabbitmq-broker/crayfish-consumer.go), named after its patterns
+func (b *RabbitMQ) poolQueue() string {
+	if len(b.patterns) == 0 {
+		return amqpTaskQueue
+	}
+	return amqpTaskQueue + "." + strings.Join(b.patterns, "+")
+}
+
+// Declare the worker pool's queue and bind its patterns. The default pool takes the tasks no other
+// pool's pattern matches, from the task exchange's alternate exchange: bound to "#" it would get a
+// copy of the tasks of the other pools too.
+func (b *RabbitMQ) declarePool(ch *amqp.Channel) error {
+	args := amqp.Table{"x-dead-letter-exchange": amqpDeadLetterExchange}
+	if _, err := ch.QueueDeclare(b.poolQueue(), true, false, false, false, args); err != nil {
+		return err
+	}
+	if len(b.patterns) == 0 {
+		if err := ch.QueueUnbind(b.poolQueue(), "#", amqpTaskExchange, nil); err != nil { // Bound so by older versions
+			return err
+		}
+		return ch.QueueBind(b.poolQueue(), "", amqpUnroutedExchange, false, nil)
+	}
+	for _, pattern := range b.patterns {
+		if err := ch.QueueBind(b.poolQueue(), pattern, amqpTaskExchange, false, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *RabbitMQ) ConsumeTasks(ctx context.Context) (<-chan TaskDelivery, error) {
+	out := make(chan TaskDelivery)
+	err := b.consume(ctx, b.poolQueue(), b.declarePool, func(msg amqp.Delivery) {
+		t, err := wire.DecodeTask(msg.Body, msg.ContentType)
+		if err != nil {
+			metrics.DecodeErrors.WithLabelValues(KindRabbitMQ, "task").Inc()
+			msg.Reject(false) // To the dead-letter exchange
+			return
+		}
+		select {
+		case out <- TaskDelivery{Task: t, Trace: tracing.FromTable(msg.Headers), Acknowledger: amqpAck{msg}}:
+		case <-ctx.Done():
+			msg.Nack(false, true)
+		}
+	}, func() { close(out) })
+	return out, err
+}
+
+func (b *RabbitMQ) ConsumeResults(ctx context.Context) (<-chan ResultDelivery, error) {
+	return b.consumeResults(ctx, amqpResultQueue, nil, func() {})
+}
+
+// ConsumeJobResults consumes the queue "resultQueue.<jobID>", which is deleted once ctx is cancelled
+// and expires an hour after its last consumer otherwise
+func (b *RabbitMQ) ConsumeJobResults(ctx context.Context, jobID string) (<-chan ResultDelivery, string, error) {
+	queue := amqpResultQueue + "." + jobID
+	args := amqp.Table{"x-expires": replyTTL.Milliseconds()}
+	out, err := b.consumeResults(ctx, queue, func(ch *amqp.Channel) error {
+		_, err := ch.QueueDeclare(queue, true, false, false, false, args)
+		return err
+	}, func() {
+		if b.ctx.Err() != nil {
+			return // Closed, the queue expires
+		}
+		ch, err := b.connection().Channel()
+		if err == nil {
+			_, err = ch.QueueDelete(queue, false, false, false)
+			ch.Close()
+		}
+		if err != nil {
+			slog.Warn("broker: deleting the results of the job failed", "queue", queue, "err", err)
+		}
+	})
+	return out, queue, err
+}
+
+// Deliver the results of the queue until ctx is cancelled, then call stop
+func (b *RabbitMQ) consumeResults(ctx context.Context, queue string, declare func(*amqp.Channel) error, stop func()) (<-chan ResultDelivery, error) {
+	out := make(chan ResultDelivery)
+	err := b.consume(ctx, queue, declare, func(msg amqp.Delivery) {
+		r, err := wire.DecodeResult(msg.Body, msg.ContentType)
+		if err != nil {
+			metrics.DecodeErrors.WithLabelValues(KindRabbitMQ, "result").Inc()
+			msg.Reject(false)
+			return
+		}
+		select {
+		case out <- ResultDelivery{Result: r, Trace: tracing.FromTable(msg.Headers), Acknowledger: amqpAck{msg}}:
+		case <-ctx.Done():
+			msg.Nack(false, true)
+		}
+	}, func() {
+		stop()
+		close(out)
+	})
+	return out, err
+}
+
+// TaskDepth is the number of ready messages in the worker pool's queue
+func (b *RabbitMQ) TaskDepth(ctx context.Context) (int, error) {
+	ch, err := b.connection().Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+	q, err := ch.QueueInspect(b.poolQueue())
+	if err != nil {
+		return 0, err
+	}
+	return q.Messages, nil
+}
+
+// Close sends what the outbox still holds and disconnects
+func (b *RabbitMQ) Close() error {
+	b.stop()
+	return errors.Join(b.outbox.close(), b.connection().Close())
+}
+
+type amqpAck struct {
+	msg amqp.Delivery
+}
+
+func (a amqpAck) Ack() error { return a.msg.Ack(false) }
+
+func (a amqpAck) Nack(requeue bool) error { return a.msg.Nack(false, requeue) }
@@ -0,0 +1,48 @@
+package broker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"crayfish/backoff"
+	"crayfish/wire"
+
+	"github.com/streadway/amqp"
+)
+
+func TestTaskRoutingKey(t *testing.T) {
+	tests := []struct {
+		name string
+		task wire.Task
+		want string
+	}{
+		{"sub-population", wire.Task{Function: "F6", SubPopulation: [][]float64{make([]float64, 500)}}, "coa.F6.d500"},
+		{"seed form", wire.Task{Function: "F6", Size: 10, Dim: 500, PopulationSize: 100}, "coa.F6.d500"},
+		{"header over rows", wire.Task{Function: "F1", Dim: 30, SubPopulation: [][]float64{make([]float64, 30)}}, "coa.F1.d30"},
+		{"algorithm", wire.Task{Algorithm: "pso", Function: "F1", Dim: 30}, "pso.F1.d30"},
+		{"empty", wire.Task{Function: "F1"}, "coa.F1.d0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TaskRoutingKey(tt.task); got != tt.want {
+				t.Errorf("TaskRoutingKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// The task exchange of an older version can't be redeclared with the alternate exchange, nothing to retry
+func TestTaskExchangeError(t *testing.T) {
+	refused := &amqp.Error{Code: amqp.PreconditionFailed, Reason: "PRECONDITION_FAILED - inequivalent arg 'alternate-exchange'"}
+	err := taskExchangeError(refused)
+	if !backoff.IsPermanent(err) || !errors.Is(err, refused) || !strings.Contains(err.Error(), "delete exchange name=crayfish-tasks") {
+		t.Errorf("406: %v, want a permanent error telling to delete the exchange", err)
+	}
+
+	for _, other := range []error{&amqp.Error{Code: amqp.ChannelError}, amqp.ErrClosed, errors.New("dial tcp: refused")} {
+		if err := taskExchangeError(other); err != other {
+			t.Errorf("%v became %v", other, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"crayfish/backoff"
+	"crayfish/metrics"
+	"crayfish/wire"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisPubSub is the fire-and-forget transport, at most once: messages published while nobody is
+// subscribed are lost, there is nothing to acknowledge and nacked messages are dropped. The subscriptions are renewed by the client whenever it
+// reconnects; results published while Redis is away wait in an outbox.
+type RedisPubSub struct {
+	client      redis.UniversalClient
+	contentType string
+	tasks       string
+	results     string
+	retry       backoff.Policy
+	outbox      *outbox
+}
+
+func NewRedisPubSub(c Config) (*RedisPubSub, error) {
+	client, err := c.Redis.Client()
+	if err != nil {
+		return nil, err
+	}
+	if err := pingRedis(client, c.Retry); err != nil {
+		client.Close()
+		return nil, err
+	}
+	b := &RedisPubSub{client: client, contentType: c.ContentType, tasks: c.TaskChannel, results: c.ResultChannel, retry: c.Retry}
+	b.outbox = newOutbox(c.PendingResults, c.Retry, b.sendResult)
+	return b, nil
+}
+
+func (b *RedisPubSub) publish(channel string, payload []byte) error {
+	return b.client.Publish(channel, payload).Err()
+}
+
+func (b *RedisPubSub) PublishTask(ctx context.Context, t wire.Task) error {
+	t.Trace = traceOf(ctx, t.Trace) // No headers, the context travels in the payload
+	payload, err := wire.EncodeTask(t, b.contentType)
+	if err != nil {
+		return err
+	}
+	return b.retry.Retry(ctx, fmt.Sprintf("broker: publishing task %d", t.Index), func() error {
+		return b.publish(b.tasks, payload)
+	})
+}
+
+func (b *RedisPubSub) PublishResult(ctx context.Context, r wire.Result) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	return b.outbox.publish(r, nil)
+}
+
+func (b *RedisPubSub) PublishResultThen(ctx context.Context, r wire.Result, done func(error)) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	return b.outbox.publish(r, done)
+}
+
+func (b *RedisPubSub) sendResult(r wire.Result) error {
+	payload, err := wire.EncodeResult(r, b.contentType)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	channel := b.results
+	if r.ReplyTo != "" {
+		channel = r.ReplyTo
+	}
+	return b.publish(channel, payload)
+}
+
+// Subscribe to a channel and hand every payload to deliver until ctx is cancelled, stop is called
+// once no more payloads follow
+func (b *RedisPubSub) subscribe(ctx context.Context, channel string, deliver func([]byte), stop func()) error {
+	sub := b.client.Subscribe(channel)
+	if _, err := sub.Receive(); err != nil { // Wait for the subscription to be confirmed
+		sub.Close()
+		return err
+	}
+
+	go func() {
+		defer stop()
+		defer sub.Close()
+		msgs := sub.Channel()
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				deliver([]byte(msg.Payload))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *RedisPubSub) ConsumeTasks(ctx context.Context) (<-chan TaskDelivery, error) {
+	out := make(chan TaskDelivery)
+	err := b.subscribe(ctx, b.tasks, func(payload []byte) {
+		t, err := wire.DecodeTask(payload, wire.Sniff(payload))
+		if err != nil {
+			slog.Error("broker: dropping an undecodable task", "err", err)
+			metrics.DecodeErrors.WithLabelValues(KindRedisPubSub, "task").Inc()
+			return
+		}
+		select {
+		case out <- TaskDelivery{Task: t, Trace: t.Trace, Acknowledger: noAck{kind: "task"}}:
+		case <-ctx.Done():
+		}
+	}, func() { close(out) })
+	return out, err
+}
+
+func (b *RedisPubSub) ConsumeResults(ctx context.Context) (<-chan ResultDelivery, error) {
+	return b.consumeResults(ctx, b.results)
+}
+
+// ConsumeJobResults subscribes to "<results>:<jobID>", the channel is gone with its last subscriber
+func (b *RedisPubSub) ConsumeJobResults(ctx context.Context, jobID string) (<-chan ResultDelivery, string, error) {
+	channel := b.results + ":" + jobID
+	out, err := b.consumeResults(ctx, channel)
+	return out, channel, err
+}
+
+func (b *RedisPubSub) consumeResults(ctx context.Context, channel string) (<-chan ResultDelivery, error) {
+	out := make(chan ResultDelivery)
+	err := b.subscribe(ctx, channel, func(payload []byte) {
+		r, err := wire.DecodeResult(payload, wire.Sniff(payload))
+		if err != nil {
+			slog.Error("broker: dropping an undecodable result", "err", err)
+			metrics.DecodeErrors.WithLabelValues(KindRedisPubSub, "result").Inc()
+			return
+		}
+		select {
+		case out <- ResultDelivery{Result: r, Trace: r.Trace, Acknowledger: noAck{kind: "result"}}:
+		case <-ctx.Done():
+		}
+	}, func() { close(out) })
+	return out, err
+}
+
+func (b *RedisPubSub) Close() error {
+	return errors.Join(b.outbox.close(), b.client.Close())
+}
@@ -0,0 +1,283 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"crayfish/backoff"
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/tracing"
+	"crayfish/wire"
+
+	"github.com/go-redis/redis"
+)
+
+// RedisStreams keeps the messages in streams read through consumer groups, so they wait for a
+// consumer and stay pending until acknowledged. The client reconnects by itself; failed reads and
+// task publishes are retried with backoff and results wait in an outbox while Redis is away.
+type RedisStreams struct {
+	client      redis.UniversalClient
+	contentType string
+	tasks       string
+	results     string
+	group       string
+	consumer    string // Unique name of this process in the consumer groups
+	retry       backoff.Policy
+	outbox      *outbox
+}
+
+// How long a read blocks before checking whether the consumer was cancelled
+const streamsBlock = time.Second
+
+func NewRedisStreams(c Config) (*RedisStreams, error) {
+	client, err := c.Redis.Client()
+	if err != nil {
+		return nil, err
+	}
+	if err := pingRedis(client, c.Retry); err != nil {
+		client.Close()
+		return nil, err
+	}
+	b := &RedisStreams{
+		client:      client,
+		contentType: c.ContentType,
+		tasks:       c.TaskChannel,
+		results:     c.ResultChannel,
+		group:       c.Group,
+		consumer:    logging.Worker(), // The worker's ID in the logs too
+		retry:       c.Retry,
+	}
+	b.outbox = newOutbox(c.PendingResults, c.Retry, b.sendResult)
+	return b, nil
+}
+
+// Wait for Redis to answer, a server that is still starting is given the retry policy's time
+func pingRedis(client redis.UniversalClient, retry backoff.Policy) error {
+	err := retry.Retry(context.Background(), "connecting to Redis", func() error {
+		return client.Ping().Err()
+	})
+	if err != nil {
+		return fmt.Errorf("broker: unable to connect to Redis: %w", err)
+	}
+	return nil
+}
+
+func (b *RedisStreams) add(stream string, values map[string]interface{}) error {
+	return b.client.XAdd(&redis.XAddArgs{Stream: stream, ID: "*", Values: values}).Err()
+}
+
+func (b *RedisStreams) PublishTask(ctx context.Context, t wire.Task) error {
+	trace := traceOf(ctx, t.Trace)
+	t.Trace = nil // In the entry's fields instead
+	values, err := wire.TaskStreamValues(t, b.contentType)
+	if err != nil {
+		return err
+	}
+	tracing.AddFields(values, trace)
+	return b.retry.Retry(ctx, fmt.Sprintf("broker: publishing task %d", t.Index), func() error {
+		return b.add(b.tasks, values)
+	})
+}
+
+func (b *RedisStreams) PublishResult(ctx context.Context, r wire.Result) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	return b.outbox.publish(r, nil)
+}
+
+func (b *RedisStreams) PublishResultThen(ctx context.Context, r wire.Result, done func(error)) error {
+	r.Trace = traceOf(ctx, r.Trace)
+	return b.outbox.publish(r, done)
+}
+
+func (b *RedisStreams) sendResult(r wire.Result) error {
+	trace := r.Trace
+	r.Trace = nil
+	values, err := wire.ResultStreamValues(r, b.contentType)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	tracing.AddFields(values, trace)
+	if r.ReplyTo == "" {
+		return b.add(b.results, values)
+	}
+	// A job's own stream expires if its coordinator went away without deleting it
+	pipe := b.client.TxPipeline()
+	pipe.XAdd(&redis.XAddArgs{Stream: r.ReplyTo, ID: "*", Values: values})
+	pipe.Expire(r.ReplyTo, replyTTL)
+	_, err = pipe.Exec()
+	return err
+}
+
+// Create the consumer group (and the stream) unless it exists already
+func (b *RedisStreams) createGroup(stream, group string) error {
+	err := b.client.XGroupCreateMkStream(stream, group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// Acknowledger of a stream entry, a dropped entry is moved to "<stream>:dead"
+type streamAck struct {
+	b      *RedisStreams
+	stream string
+	group  string
+	msg    redis.XMessage
+}
+
+func (a *streamAck) Ack() error {
+	return a.b.client.XAck(a.stream, a.group, a.msg.ID).Err()
+}
+
+func (a *streamAck) Nack(requeue bool) error {
+	target := a.stream
+	if !requeue {
+		target = a.stream + ":dead"
+	} else {
+		metrics.Redeliveries.WithLabelValues(KindRedisStreams).Inc()
+	}
+	if err := a.b.add(target, a.msg.Values); err != nil {
+		return err
+	}
+	return a.Ack()
+}
+
+// Read new entries of the stream as a member of the group and hand them to deliver until ctx is cancelled
+func (b *RedisStreams) read(ctx context.Context, stream, group string, deliver func(redis.XMessage, *streamAck), stop func()) error {
+	if err := b.createGroup(stream, group); err != nil {
+		return err
+	}
+
+	go func() {
+		defer stop()
+		failures := 0
+		for ctx.Err() == nil {
+			entries, err := b.client.XReadGroup(&redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: b.consumer,
+				Streams:  []string{stream, ">"},
+				Count:    1,
+				Block:    streamsBlock,
+			}).Result()
+			if err == redis.Nil {
+				failures = 0
+				continue // Nothing new yet
+			}
+			if err != nil {
+				failures++
+				delay := b.retry.Delay(failures)
+				slog.Warn("broker: reading the stream failed", "stream", stream, "failures", failures, "err", err, "retry_in", delay.Round(time.Millisecond))
+				if backoff.Sleep(ctx, delay) != nil {
+					return
+				}
+				if strings.HasPrefix(err.Error(), "NOGROUP") { // Redis came back without our data
+					if err := b.createGroup(stream, group); err != nil {
+						slog.Error("broker: recreating the group failed", "stream", stream, "group", group, "err", err)
+					}
+				}
+				continue
+			}
+			failures = 0
+			for _, entry := range entries {
+				for _, msg := range entry.Messages {
+					deliver(msg, &streamAck{b: b, stream: stream, group: group, msg: msg})
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (b *RedisStreams) ConsumeTasks(ctx context.Context) (<-chan TaskDelivery, error) {
+	out := make(chan TaskDelivery)
+	err := b.read(ctx, b.tasks, b.group, func(msg redis.XMessage, ack *streamAck) {
+		t, err := wire.TaskFromStream(msg.Values)
+		if err != nil {
+			slog.Error("broker: dead-lettering an undecodable task", "id", msg.ID, "err", err)
+			metrics.DecodeErrors.WithLabelValues(KindRedisStreams, "task").Inc()
+			ack.Nack(false)
+			return
+		}
+		select {
+		case out <- TaskDelivery{Task: t, Trace: tracing.FromFields(msg.Values), Acknowledger: ack}:
+		case <-ctx.Done():
+		}
+	}, func() { close(out) })
+	return out, err
+}
+
+func (b *RedisStreams) ConsumeResults(ctx context.Context) (<-chan ResultDelivery, error) {
+	return b.consumeResults(ctx, b.results, func() {})
+}
+
+// ConsumeJobResults reads the stream "<results>:<jobID>", which is deleted once ctx is cancelled
+func (b *RedisStreams) ConsumeJobResults(ctx context.Context, jobID string) (<-chan ResultDelivery, string, error) {
+	stream := b.results + ":" + jobID
+	out, err := b.consumeResults(ctx, stream, func() {
+		if err := b.client.Del(stream).Err(); err != nil {
+			slog.Warn("broker: deleting the results of the job failed", "stream", stream, "err", err)
+		}
+	})
+	if err == nil {
+		err = b.client.Expire(stream, replyTTL).Err()
+	}
+	return out, stream, err
+}
+
+// Deliver the results of the stream until ctx is cancelled, then call stop
+func (b *RedisStreams) consumeResults(ctx context.Context, stream string, stop func()) (<-chan ResultDelivery, error) {
+	out := make(chan ResultDelivery)
+	err := b.read(ctx, stream, b.group+"-results", func(msg redis.XMessage, ack *streamAck) {
+		r, err := wire.ResultFromStream(msg.Values)
+		if err != nil {
+			slog.Error("broker: dead-lettering an undecodable result", "id", msg.ID, "err", err)
+			metrics.DecodeErrors.WithLabelValues(KindRedisStreams, "result").Inc()
+			ack.Nack(false)
+			return
+		}
+		select {
+		case out <- ResultDelivery{Result: r, Trace: tracing.FromFields(msg.Values), Acknowledger: ack}:
+		case <-ctx.Done():
+		}
+	}, func() {
+		stop()
+		close(out)
+	})
+	return out, err
+}
+
+// TaskDepth is the number of tasks the workers' group hasn't acknowledged: the entries it has yet to
+// read (the group's lag, Redis 7 and later) and the ones read but pending
+func (b *RedisStreams) TaskDepth(ctx context.Context) (int, error) {
+	cmd := redis.NewSliceCmd("XINFO", "GROUPS", b.tasks) // No XINFO in this client version
+	b.client.Process(cmd)
+	groups, err := cmd.Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, g := range groups {
+		fields, _ := g.([]interface{})
+		info := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			if key, ok := fields[i].(string); ok {
+				info[key] = fields[i+1]
+			}
+		}
+		if info["name"] != b.group {
+			continue
+		}
+		pending, _ := info["pending"].(int64)
+		lag, _ := info["lag"].(int64) // nil when Redis can't tell, the pending entries are all we know
+		return int(pending + lag), nil
+	}
+	return 0, nil
+}
+
+func (b *RedisStreams) Close() error {
+	return errors.Join(b.outbox.close(), b.client.Close())
+}
@@ -0,0 +1,147 @@
+// Package checkpoint saves coa.Checkpoints in files or Redis keys, so a run can stop (e.g. when a
+// serverless invocation runs out of time) and continue later, in another process, exactly where it
+// stopped. Checkpoints are MessagePack, which keeps every float64 bit for bit (Inf included).
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"crayfish/coa"
+	"crayfish/config"
+
+	"github.com/go-redis/redis"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrNotFound is returned by Load when there is no checkpoint under the key
+var ErrNotFound = errors.New("checkpoint: not found")
+
+// Store keeps checkpoints by key (e.g. "<job>-<index>")
+type Store interface {
+	Save(ctx context.Context, key string, cp *coa.Checkpoint) error
+	Load(ctx context.Context, key string) (*coa.Checkpoint, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the store at location: "redis" for the configured Redis, "redis://host:port" for
+// another server with the same settings, anything else is a directory
+func Open(location string, rc config.Redis) (Store, error) {
+	if addr, ok := strings.CutPrefix(location, "redis://"); ok || location == "redis" {
+		client, err := rc.WithAddr(addr).Client()
+		if err != nil {
+			return nil, err
+		}
+		return NewRedis(client), nil
+	}
+	if err := os.MkdirAll(location, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir{Path: location}, nil
+}
+
+func encode(cp *coa.Checkpoint) ([]byte, error) {
+	return msgpack.Marshal(cp)
+}
+
+func decode(data []byte) (*coa.Checkpoint, error) {
+	cp := new(coa.Checkpoint)
+	if err := msgpack.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// Dir keeps each checkpoint in the file <key>.ckpt of a directory
+type Dir struct {
+	Path string
+}
+
+func (d *Dir) file(key string) string {
+	return filepath.Join(d.Path, filepath.Base(key)+".ckpt")
+}
+
+// Save writes a temporary file and renames it, a crash never leaves half a checkpoint
+func (d *Dir) Save(ctx context.Context, key string, cp *coa.Checkpoint) error {
+	data, err := encode(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(d.Path, filepath.Base(key)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), d.file(key))
+}
+
+func (d *Dir) Load(ctx context.Context, key string) (*coa.Checkpoint, error) {
+	data, err := os.ReadFile(d.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+func (d *Dir) Delete(ctx context.Context, key string) error {
+	err := os.Remove(d.file(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// How long a checkpoint stays in Redis after it was saved
+const redisTTL = 24 * time.Hour
+
+// Redis keeps each checkpoint in the key "crayfish:checkpoint:<key>"
+type Redis struct {
+	client redis.UniversalClient
+}
+
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client}
+}
+
+func redisKey(key string) string {
+	return "crayfish:checkpoint:" + key
+}
+
+func (r *Redis) Save(ctx context.Context, key string, cp *coa.Checkpoint) error {
+	data, err := encode(cp)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(redisKey(key), data, redisTTL).Err()
+}
+
+func (r *Redis) Load(ctx context.Context, key string) (*coa.Checkpoint, error) {
+	data, err := r.client.Get(redisKey(key)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	return r.client.Del(redisKey(key)).Err()
+}
@@ -0,0 +1,66 @@
+package checkpoint
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"crayfish/benchmarks"
+	"crayfish/coa"
+)
+
+// A run stopped every few iterations, saved, loaded and restored ends exactly where the same run
+// without stopping does: a Lévy flight draws more random numbers than COA, and IPOP grows the
+// population on every restart
+func TestResumeIsDeterministic(t *testing.T) {
+	const T, every = 200, 7
+	specs, err := benchmarks.Get("F5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb, ub := specs.LB, specs.UB
+	restart := coa.Restart{Strategy: coa.RestartIPOP, Stagnation: 10, MaxSize: 40}
+	start := func() *coa.Optimizer {
+		o := coa.NewSeededOptimizer(T, lb, ub, coa.SeededPopulation(5, 10, 6, lb, ub), specs.Function, 11)
+		o.Variant, o.Restart, o.RecordStages = coa.VariantLevy, restart, true
+		return o
+	}
+
+	whole := start()
+	whole.Run(T)
+	if len(whole.Restarts) < 2 || len(whole.X) == 10 {
+		t.Fatalf("%d restarts, %d crayfish: the test doesn't cover IPOP", len(whole.Restarts), len(whole.X))
+	}
+
+	ctx := context.Background()
+	store := &Dir{Path: t.TempDir()}
+	o, resumes := start(), 0
+	for !o.Done() {
+		o.Run(every)
+		cp, err := o.Checkpoint("F5")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Save(ctx, "job-0", cp); err != nil {
+			t.Fatal(err)
+		}
+		if cp, err = store.Load(ctx, "job-0"); err != nil {
+			t.Fatal(err)
+		}
+		if o, err = coa.RestoreOptimizer(cp, specs.Function); err != nil {
+			t.Fatal(err)
+		}
+		o.Variant, o.Restart, o.RecordStages = coa.VariantLevy, restart, true // Not saved, as the handler does
+		resumes++
+	}
+
+	if o.BestFitness != whole.BestFitness || !reflect.DeepEqual(o.BestPos, whole.BestPos) {
+		t.Errorf("after %d resumes the best is %g, without stopping %g", resumes, o.BestFitness, whole.BestFitness)
+	}
+	if !reflect.DeepEqual(o.GlobalCov, whole.GlobalCov) || !reflect.DeepEqual(o.X, whole.X) {
+		t.Error("the resumed run's convergence or crayfish differ")
+	}
+	if !reflect.DeepEqual(o.Restarts, whole.Restarts) || !reflect.DeepEqual(o.Stages, whole.Stages) {
+		t.Errorf("restarts %+v, want %+v", o.Restarts, whole.Restarts)
+	}
+}
@@ -0,0 +1,211 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"crayfish/benchmarks"
+	"crayfish/coa"
+)
+
+// crayfish bench [F1 F6 ...]: run COA on the benchmarks (all of them by default) in this process,
+// -runs times each from consecutive seeds, and print the statistics of the best fitness, the
+// iterations run (fewer than T once the population collapses, see the param min_diversity), the
+// diversity the populations end with and the evaluations of the benchmark they took. The statistics
+// of the constrained benchmarks are those of the best positions whatever their violation, Feasible
+// counts the runs that found a feasible one. -variants runs each of the variants from the same
+// seeds, to compare them. The multi-objective benchmarks get a table of their own, with the size,
+// hypervolume and IGD of the Pareto fronts.
+func bench(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	repeat := fs.Int("runs", 5, "runs per benchmark")
+	compare := fs.String("variants", "", "comma-separated variants to compare from the same seeds, or all (default: -variant)")
+	s, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	if *repeat <= 0 {
+		return fmt.Errorf("bench: need -runs > 0, got %d", *repeat)
+	}
+	j := s.Job
+	params, err := coa.ParseParams(j.Params)
+	if err != nil {
+		return err
+	}
+	restart := coa.Restart(j.Restart)
+	if err := restart.Validate(); err != nil {
+		return err
+	}
+	constraints := coa.Constraints(j.Constraints)
+	if err := constraints.Validate(); err != nil {
+		return err
+	}
+	pareto := coa.Pareto(j.Pareto)
+	if err := pareto.Validate(); err != nil {
+		return err
+	}
+	warm, err := j.WarmStartPoints()
+	if err != nil {
+		return err
+	}
+	variants := []string{j.Variant}
+	switch {
+	case *compare == "all":
+		variants = coa.VariantNames()
+	case *compare != "":
+		variants = strings.Split(*compare, ",")
+	}
+	for i, v := range variants {
+		variants[i] = strings.TrimSpace(v)
+		if err := coa.ValidateVariant(variants[i]); err != nil {
+			return err
+		}
+		if variants[i] == "" {
+			variants[i] = coa.VariantCOA
+		}
+	}
+	all := fs.Args()
+	if len(all) == 0 {
+		all = benchmarks.Names()
+	}
+	var names, multi []string
+	for _, name := range all {
+		if _, ok := benchmarks.GetMulti(name); ok {
+			multi = append(multi, name)
+		} else {
+			names = append(names, name)
+		}
+	}
+	seed := j.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(names) > 0 {
+		fmt.Fprintln(w, "Function\tVariant\tDim\tRuns\tFeasible\tBest\tMean\tStd\tWorst\tIterations\tEvaluations\tDiversity\tRestarts\tMean time")
+	}
+	for _, name := range names {
+		specs, err := benchmarks.Get(name)
+		if err != nil {
+			return err
+		}
+		problem, _ := benchmarks.GetConstrained(name)
+		dim, lb, ub := specs.Dimension(j.Dim), j.LB, j.UB
+		if len(lb) == 0 {
+			lb, ub = specs.LB, specs.UB
+		}
+
+		for _, variant := range variants {
+			fitness := make([]float64, 0, *repeat)
+			var iterations, evaluations, diversity, restarts float64 // Means of the iterations run, the evaluations, the final diversity and the restarts
+			feasible := 0
+			start := time.Now()
+			for r := 0; r < *repeat; r++ {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				runSeed := seed + int64(r)
+				initializer, err := coa.NewInitializer(j.Init, runSeed, specs.Function, warm)
+				if err != nil {
+					return err
+				}
+				X, err := initializer.Initialize(j.N, dim, lb, ub)
+				if err != nil {
+					return err
+				}
+				o := coa.NewSeededOptimizer(j.T, lb, ub, X, specs.Function, runSeed)
+				o.Params = params
+				o.Restart = restart
+				o.Variant = variant
+				o.Constrain(problem, constraints)
+				o.Run(j.T)
+				if o.BestViolation == 0 {
+					feasible++
+				}
+				fitness = append(fitness, o.BestFitness)
+				iterations += float64(o.Iteration) / float64(*repeat)
+				evaluations += float64(o.Counters.Evaluations) / float64(*repeat)
+				diversity += coa.MeasureDiversity(o.X, lb, ub).Relative / float64(*repeat)
+				restarts += float64(len(o.Restarts)) / float64(*repeat)
+			}
+			elapsed := time.Since(start) / time.Duration(*repeat)
+
+			best, worst, mean, std := stats(fitness)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%g\t%g\t%g\t%g\t%g\t%.0f\t%.3g\t%.3g\t%s\n", name, variant, dim, *repeat, feasible, best, mean, std, worst, iterations,
+				evaluations, diversity, restarts, elapsed.Round(time.Millisecond))
+		}
+	}
+	if err := w.Flush(); err != nil || len(multi) == 0 {
+		return err
+	}
+
+	if len(names) > 0 {
+		fmt.Println()
+	}
+	fmt.Fprintln(w, "Function\tObjectives\tDim\tRuns\tFront\tHypervolume\tStd\tIGD\tStd\tEvaluations\tMean time")
+	for _, name := range multi {
+		specs, err := benchmarks.Get(name)
+		if err != nil {
+			return err
+		}
+		problem, _ := benchmarks.GetMulti(name)
+		dim, lb, ub := specs.Dimension(j.Dim), j.LB, j.UB
+		if len(lb) == 0 {
+			lb, ub = specs.LB, specs.UB
+		}
+
+		hypervolume, igd := make([]float64, 0, *repeat), make([]float64, 0, *repeat)
+		var size, evaluations float64 // Means of the size of the fronts and of the evaluations
+		start := time.Now()
+		for r := 0; r < *repeat; r++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			runSeed := seed + int64(r)
+			initializer, err := coa.NewInitializer(j.Init, runSeed, specs.Function, warm)
+			if err != nil {
+				return err
+			}
+			X, err := initializer.Initialize(j.N, dim, lb, ub)
+			if err != nil {
+				return err
+			}
+			o := coa.NewMultiOptimizer(j.T, lb, ub, X, problem.Objectives, pareto, rand.New(coa.NewSource(runSeed)))
+			o.Params = params
+			o.Run(j.T)
+			front := o.Archive.Front(problem)
+			hypervolume = append(hypervolume, front.Hypervolume)
+			igd = append(igd, front.IGD)
+			size += float64(len(front.Objectives)) / float64(*repeat)
+			evaluations += float64(o.Counters.Evaluations) / float64(*repeat)
+		}
+		elapsed := time.Since(start) / time.Duration(*repeat)
+
+		_, _, hvMean, hvStd := stats(hypervolume)
+		_, _, igdMean, igdStd := stats(igd)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.1f\t%.4g\t%.2g\t%.4g\t%.2g\t%.0f\t%s\n", name, problem.M, dim, *repeat, size,
+			hvMean, hvStd, igdMean, igdStd, evaluations, elapsed.Round(time.Millisecond))
+	}
+	return w.Flush()
+}
+
+// Best, worst, mean and standard deviation of the fitness values
+func stats(fitness []float64) (best, worst, mean, std float64) {
+	best, worst = math.Inf(1), math.Inf(-1)
+	for _, f := range fitness {
+		best, worst = math.Min(best, f), math.Max(worst, f)
+		mean += f
+	}
+	mean /= float64(len(fitness))
+	for _, f := range fitness {
+		std += (f - mean) * (f - mean)
+	}
+	return best, worst, mean, math.Sqrt(std / float64(len(fitness)))
+}
@@ -0,0 +1,183 @@
+// Package cli is the crayfish command: every program of the repository (coordinator, worker,
+// serverless handler, result consumer, local benchmarks) as a subcommand, all configured the same
+// way through flags, a YAML or JSON file and CRAYFISH_* variables (see package config), instead of
+// constants edited in main().
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"crayfish/benchmarks"
+	"crayfish/broker"
+	"crayfish/coa"
+	"crayfish/config"
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/tracing"
+)
+
+// Settings are the broker, the job (the "job:" section of the configuration file), where the
+// metrics are served, where the spans go and how much is logged
+type Settings struct {
+	broker.Config `yaml:",inline"`
+	Job           config.Job     `yaml:"job"`
+	MetricsAddr   string         `yaml:"metrics_addr"` // Prometheus /metrics, off when empty
+	Tracing       tracing.Config `yaml:"tracing"`
+	Logging       logging.Config `yaml:"logging"`
+}
+
+// DefaultSettings are broker.DefaultConfig, config.DefaultJob, tracing.DefaultConfig and
+// logging.DefaultConfig
+func DefaultSettings() Settings {
+	return Settings{
+		Config:  broker.DefaultConfig(),
+		Job:     config.DefaultJob(),
+		Tracing: tracing.DefaultConfig(),
+		Logging: logging.DefaultConfig(),
+	}
+}
+
+// RegisterFlags binds the broker, the job, the tracing and the logs to flags
+func (s *Settings) RegisterFlags(fs *flag.FlagSet) {
+	s.Config.RegisterFlags(fs)
+	s.Job.RegisterFlags(fs)
+	fs.StringVar(&s.MetricsAddr, "metrics-addr", s.MetricsAddr, "serve Prometheus metrics on this address, e.g. :9090 (empty: off)")
+	s.Tracing.RegisterFlags(fs)
+	s.Logging.RegisterFlags(fs)
+}
+
+// How often the depth of the task queue is sampled
+const depthInterval = 15 * time.Second
+
+type command struct {
+	name, summary string
+	run           func(ctx context.Context, fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"run", "run a job in this process: in-memory broker, one worker per sub-population", runLocal},
+	{"publish", "coordinate a job over the broker (workers: crayfish work)", publish},
+	{"work", "solve the tasks of the broker and publish the results", work},
+	{"consume", "merge the results of the broker per job and write them out", consume},
+	{"bench", "run the benchmarks here and print a table", bench},
+	{"serve", "serve the serverless handler over HTTP", serve},
+}
+
+// Main runs the subcommand named by args[0] until it is done or interrupted
+func Main(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage(os.Stderr)
+		if len(args) == 0 {
+			return fmt.Errorf("no command given")
+		}
+		return nil
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		fs := flag.NewFlagSet("crayfish "+c.name, flag.ExitOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "usage: crayfish %s [flags]\n\n%s.\n\n", c.name, capitalize(c.summary))
+			fs.PrintDefaults()
+			fmt.Fprintln(fs.Output())
+			listChoices(fs.Output())
+		}
+		err := c.run(ctx, fs, args[1:])
+		if serr := tracing.Shutdown(context.Background()); serr != nil && err == nil {
+			err = serr
+		}
+		return err
+	}
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// Load the settings over their defaults, after the command registered its own flags on fs, set up
+// the logs, start serving the metrics and exporting the spans (flushed when Main returns)
+func load(fs *flag.FlagSet, args []string) (Settings, error) {
+	s := DefaultSettings()
+	if err := config.Load(fs, args, &s); err != nil {
+		return s, err
+	}
+	if err := logging.Setup(s.Logging); err != nil {
+		return s, err
+	}
+	if err := s.Job.Check(); err != nil {
+		return s, err
+	}
+	metrics.Serve(s.MetricsAddr)
+	if s.Tracing.Service == "" { // e.g. "crayfish-work"
+		s.Tracing.Service = strings.ReplaceAll(fs.Name(), " ", "-")
+	}
+	if err := tracing.Setup(context.Background(), s.Tracing); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// Sample the depth of the broker's task queue for the metrics, when it can tell
+func watchDepth(ctx context.Context, s Settings, b broker.Broker) {
+	if d, ok := b.(broker.Depther); ok && s.MetricsAddr != "" {
+		go metrics.WatchDepth(ctx, s.Kind, depthInterval, d.TaskDepth)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: crayfish <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run crayfish <command> -help for its flags. Every flag can also be set in a YAML or JSON file")
+	fmt.Fprintln(w, "(-config, see crayfish.example.yml) or a CRAYFISH_<KEY> variable.")
+	fmt.Fprintln(w)
+	listChoices(w)
+}
+
+// The benchmarks, parameters and variants the job settings accept
+func listChoices(w io.Writer) {
+	fmt.Fprintln(w, "Benchmarks (-f):")
+	for _, name := range benchmarks.Names() {
+		specs, _ := benchmarks.Get(name)
+		kind := ""
+		if _, ok := benchmarks.GetConstrained(name); ok {
+			kind = ", constrained"
+		}
+		if m, ok := benchmarks.GetMulti(name); ok {
+			kind = fmt.Sprintf(", %d objectives", m.M)
+		}
+		if len(specs.LB) > 1 { // Bounds per dimension
+			fmt.Fprintf(w, "  %s %v to %v%s\n", name, specs.LB, specs.UB, kind)
+			continue
+		}
+		fmt.Fprintf(w, "  %-4s [%g, %g]^%d%s\n", name, specs.LB[0], specs.UB[0], specs.Dim, kind)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Parameters of coa (-param name=value):")
+	defaults := coa.DefaultParams()
+	for _, name := range coa.ParamNames() {
+		fmt.Fprintf(w, "  %-10s %g\n", name, defaults.Get(name))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Variants of coa (-variant): %s\n", strings.Join(coa.VariantNames(), ", "))
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
+
+	"crayfish/aggregate"
+	"crayfish/broker"
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// crayfish consume: merge the results of the broker per job into <out>/<job>.json
+func consume(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	expect := fs.Int("expect", 0, "results per job when a result doesn't say (0: -k)")
+	timeout := fs.Duration("timeout", 30*time.Second, "write an incomplete job after this long without a result of it")
+	out := fs.String("out", "results", "directory for the merged results (<job>.json)")
+	s, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	if *expect <= 0 {
+		*expect = s.Job.K
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+
+	b, err := broker.New(s.Config)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	results, err := b.ConsumeResults(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Consuming results", "broker", s.Kind)
+
+	agg := aggregate.New(*expect, *timeout)
+	best := make(map[string]float64) // Of the open jobs, for the metrics
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case d, ok := <-results:
+			if !ok { // Cancelled: write what we have before leaving
+				for _, merged := range agg.Flush() {
+					metrics.BestFitness.DeleteLabelValues(merged.JobID)
+					aggregate.WriteFile(*out, merged)
+				}
+				return nil
+			}
+			d.Ack()
+			_, span := tracing.Start(tracing.Extract(ctx, d.Trace), "aggregation", trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(attribute.String("job.id", d.Result.JobID), attribute.Int("subpopulation", d.Result.Index)))
+			slog.Info("Result received", logging.KeyJob, d.Result.JobID, logging.KeySubPopulation, d.Result.Index,
+				logging.KeyEpoch, d.Result.Epoch, "best_fitness", d.Result.BestFitness)
+			if f, ok := best[d.Result.JobID]; !agg.Closed(d.Result.JobID) && (!ok || d.Result.BestFitness < f) {
+				best[d.Result.JobID] = d.Result.BestFitness
+				metrics.BestFitness.WithLabelValues(d.Result.JobID).Set(d.Result.BestFitness)
+			}
+			merged, done := agg.Add(d.Result, time.Now())
+			if done {
+				delete(best, merged.JobID)
+				metrics.BestFitness.DeleteLabelValues(merged.JobID) // Written out, the gauge would only grow
+				aggregate.WriteFile(*out, merged)
+			}
+			span.SetAttributes(attribute.Bool("job.complete", done))
+			span.End()
+
+		case now := <-ticker.C:
+			for _, merged := range agg.Expire(now) {
+				delete(best, merged.JobID)
+				metrics.BestFitness.DeleteLabelValues(merged.JobID)
+				aggregate.WriteFile(*out, merged)
+			}
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"crayfish/broker"
+	"crayfish/coa"
+	"crayfish/coordinator"
+	"crayfish/globalbest"
+	"crayfish/handler"
+	"crayfish/logging"
+	"crayfish/runs"
+	"crayfish/wire"
+)
+
+// Flags of a coordinated run besides the job's
+type coordinatorFlags struct {
+	c         coordinator.Config
+	bestAddr  string
+	db        string
+	compare   bool
+	workers   int
+	stages    string
+	diversity string
+	front     string
+}
+
+func (f *coordinatorFlags) register(fs *flag.FlagSet) {
+	c := &f.c
+	fs.StringVar(&c.JobID, "job", "", "job ID (generated when empty)")
+	fs.IntVar(&c.EpochLength, "epoch", 50, "iterations between synchronizations (0: one epoch)")
+	fs.BoolVar(&c.Async, "async", false, "asynchronous mode: share the global best through -best-addr instead of epochs")
+	fs.IntVar(&c.Chunk, "chunk", 10, "async: iterations between pulls of the global best")
+	fs.DurationVar(&c.MaxAge, "max-age", 0, "async: pull within a chunk once a worker's copy is older (0: no bound)")
+	fs.DurationVar(&c.TaskTimeout, "task-timeout", 0, "send a task again when its result takes longer (0: wait forever)")
+	fs.IntVar(&c.Retries, "retries", 2, "times a timed out task is sent again before it is given up")
+	fs.Float64Var(&c.SpeculatePercentile, "speculate", 0, "duplicate tasks slower than this percentile of the latencies, e.g. 0.9 (0: never)")
+	fs.IntVar(&c.MaxMissing, "max-missing", 0, "sub-populations an epoch may be finalized without (degraded), less than -k")
+	fs.StringVar(&f.bestAddr, "best-addr", "", "Redis holding the global best (default: the -redis-* one, in memory with -broker memory)")
+	fs.StringVar(&f.db, "db", "", "record the run in this history file (see cmd/crayfish-runs)")
+	fs.StringVar(&f.stages, "stages", "", "record what every iteration did (temperature, branches, food, acceptance, steps) in this CSV file")
+	fs.StringVar(&f.diversity, "diversity", "", "record the diversity of the islands and the whole population in this CSV file")
+	fs.StringVar(&f.front, "front", "", "multi-objective benchmarks: write the Pareto front (objectives, then position) to this CSV file")
+	fs.BoolVar(&f.compare, "compare", false, "run the job synchronously and asynchronously from the same seed and compare")
+}
+
+// The job settings of the coordinator
+func (f *coordinatorFlags) apply(s Settings) error {
+	j := s.Job
+	f.c.Algorithm = j.Algorithm
+	f.c.Variant = j.Variant
+	f.c.Function = j.Benchmark
+	f.c.N, f.c.K, f.c.T, f.c.Dim = j.N, j.K, j.T, j.Dim
+	f.c.LB, f.c.UB = j.LB, j.UB
+	f.c.Params = wire.Params(j.Params)
+	f.c.Restart = coa.Restart(j.Restart)
+	f.c.Constraints = coa.Constraints(j.Constraints)
+	f.c.Pareto = coa.Pareto(j.Pareto)
+	f.c.Seed = j.Seed
+	f.c.Init = j.Init
+	var err error
+	if f.c.WarmStart, err = j.WarmStartPoints(); err != nil {
+		return err
+	}
+	f.c.RecordStages = f.stages != ""
+	f.c.RecordDiversity = f.diversity != ""
+	if f.c.Seed == 0 {
+		f.c.Seed = time.Now().UnixNano()
+		slog.Info("Seed of the initial population", "seed", f.c.Seed)
+	}
+	return nil
+}
+
+// crayfish run: the whole job in this process, with one in-memory worker per sub-population
+func runLocal(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var f coordinatorFlags
+	f.register(fs)
+	s, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	s.Kind = broker.KindMemory
+	f.workers = s.Job.K
+	return f.coordinate(ctx, s)
+}
+
+// crayfish publish: coordinate a job over the configured broker
+func publish(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var f coordinatorFlags
+	f.register(fs)
+	fs.IntVar(&f.workers, "workers", 0, "in-process workers to start (for -broker memory)")
+	s, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+	return f.coordinate(ctx, s)
+}
+
+func (f *coordinatorFlags) coordinate(ctx context.Context, s Settings) error {
+	if err := f.apply(s); err != nil {
+		return err
+	}
+	c, cfg := f.c, s.Config
+
+	b, err := broker.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	if cfg.Kind == broker.KindMemory { // The in-process workers share the coordinator's store
+		c.Best = globalbest.NewMemory()
+	} else if c.Best, err = globalbest.DialRedis(cfg.Redis.WithAddr(f.bestAddr)); err != nil {
+		return err
+	}
+
+	var history *runs.Store
+	if f.db != "" {
+		if history, err = runs.Open(f.db); err != nil {
+			return err
+		}
+		defer history.Close()
+	}
+
+	watchDepth(ctx, s, b)
+	for i := 0; i < f.workers; i++ {
+		go handler.Work(ctx, b, handler.Env{T: c.T, Best: c.Best})
+	}
+
+	if !f.compare {
+		summary, err := coordinator.Run(ctx, b, c)
+		if err != nil {
+			return err
+		}
+		printSummary(summary)
+		record(history, c, cfg, summary)
+		if f.stages != "" {
+			if err := writeStages(f.stages, summary); err != nil {
+				return err
+			}
+		}
+		if f.diversity != "" {
+			if err := writeDiversity(f.diversity, summary); err != nil {
+				return err
+			}
+		}
+		if f.front != "" {
+			return writeFront(f.front, summary)
+		}
+		return nil
+	}
+
+	// Same seed, same benchmark, once per mode
+	jobID := c.JobID
+	var summaries [2]coordinator.Summary
+	for i, async := range []bool{false, true} {
+		c.Async = async
+		if jobID != "" {
+			c.JobID = fmt.Sprintf("%s-%s", jobID, mode(async))
+		}
+		if summaries[i], err = coordinator.Run(ctx, b, c); err != nil {
+			return err
+		}
+		record(history, c, cfg, summaries[i])
+	}
+
+	fmt.Printf("%-6s %-22s %-14s %s\n", "Mode", "Best Fitness", "Executed in", "Staleness")
+	for i, s := range summaries {
+		staleness := fmt.Sprintf("%d epochs", s.Epochs)
+		if s.Staleness != nil {
+			staleness = fmt.Sprintf("%d pulls, %.2f improvements missed on average (max %d), mean age %s (max %s)",
+				s.Staleness.Refreshes, s.Staleness.MeanMissed(), s.Staleness.MaxMissed,
+				s.Staleness.MeanAge().Round(time.Microsecond), s.Staleness.MaxAge.Round(time.Microsecond))
+		}
+		fmt.Printf("%-6s %-22g %-14s %s\n", mode(i == 1), s.BestFitness, s.Elapsed.Round(time.Millisecond), staleness)
+	}
+	return nil
+}
+
+func mode(async bool) string {
+	if async {
+		return "async"
+	}
+	return "sync"
+}
+
+// Keep the run in the history, when there is one
+func record(history *runs.Store, c coordinator.Config, cfg broker.Config, s coordinator.Summary) {
+	if history == nil {
+		return
+	}
+	params := runs.Params{"broker": cfg.Kind}
+	if c.Async {
+		params["chunk"] = fmt.Sprint(c.Chunk)
+		params["max-age"] = c.MaxAge.String()
+	} else {
+		params["epoch"] = fmt.Sprint(c.EpochLength)
+	}
+	if c.TaskTimeout > 0 {
+		params["task-timeout"] = c.TaskTimeout.String()
+	}
+	if c.SpeculatePercentile > 0 {
+		params["speculate"] = fmt.Sprint(c.SpeculatePercentile)
+	}
+	if c.Init != "" {
+		params["init"] = c.Init
+	}
+	if len(c.WarmStart) > 0 {
+		params["warm-start"] = fmt.Sprint(len(c.WarmStart), " points")
+	}
+	if c.Restart.Strategy != "" {
+		params["restart"] = c.Restart.Strategy
+		params["restart-stagnation"] = fmt.Sprint(c.Restart.Stagnation)
+	}
+	algorithm := "COA"
+	if c.Variant != "" && c.Variant != coa.VariantCOA {
+		algorithm += "-" + c.Variant
+	}
+	if c.Constraints.Handling != "" {
+		params["constraints"] = c.Constraints.Handling
+	}
+	if s.Front != nil {
+		algorithm = "MOCOA"
+		if c.Pareto.Pruning != "" {
+			params["archive-pruning"] = c.Pareto.Pruning
+		}
+	}
+	for name, value := range c.Params { // The algorithm parameters that were set
+		params[name] = fmt.Sprint(value)
+	}
+
+	r := &runs.Run{
+		JobID:          s.JobID,
+		Algorithm:      algorithm,
+		Mode:           mode(c.Async),
+		Function:       c.Function,
+		N:              c.N,
+		K:              c.K,
+		T:              c.T,
+		Dim:            len(s.BestPosition),
+		Seed:           c.Seed,
+		Params:         params,
+		Started:        time.Now().Add(-s.Elapsed),
+		Elapsed:        s.Elapsed,
+		BestFitness:    s.BestFitness,
+		BestPosition:   s.BestPosition,
+		Violation:      s.BestViolation,
+		GlobalConverge: s.GlobalConverge,
+		Degraded:       len(s.Degraded) > 0,
+	}
+	if s.Front != nil {
+		r.Front = &runs.Front{Size: len(s.Front.Objectives), Hypervolume: s.Front.Hypervolume, IGD: s.Front.IGD}
+	}
+	for island, events := range s.Restarts {
+		for _, e := range events {
+			r.Restarts = append(r.Restarts, runs.Restart{Island: island, Iteration: e.Iteration, Reason: e.Reason,
+				Size: e.Size, BestFitness: e.BestFitness})
+		}
+	}
+	if err := history.Save(r); err != nil {
+		logging.ForJob(s.JobID).Error("Recording the run failed", "err", err)
+		return
+	}
+	logging.ForJob(s.JobID).Info("Run recorded", "run", r.ID)
+}
+
+func printSummary(summary coordinator.Summary) {
+	fmt.Println("Job:", summary.JobID)
+	fmt.Println("Best Fitness:", summary.BestFitness)
+	fmt.Println("Best Position:", summary.BestPosition)
+	if summary.BestViolation > 0 {
+		fmt.Println("Constraint violation:", summary.BestViolation, "(no feasible position found)")
+	}
+	if f := summary.Front; f != nil {
+		fmt.Printf("Pareto front: %d positions, hypervolume %g, IGD %g (best position: the smallest sum of objectives)\n",
+			len(f.Objectives), f.Hypervolume, f.IGD)
+	}
+	if s := summary.Staleness; s != nil {
+		fmt.Println("Pulls of the global best:", s.Refreshes, "forced:", s.ForcedRefreshes, "pushes:", s.Pushes)
+		fmt.Println("Improvements missed per pull:", s.MeanMissed(), "max:", s.MaxMissed)
+		fmt.Println("Age of the copy at a pull:", s.MeanAge(), "max:", s.MaxAge)
+	} else {
+		fmt.Println("Epochs:", summary.Epochs)
+	}
+	if summary.Resent > 0 || summary.Speculated > 0 {
+		fmt.Println("Tasks sent again:", summary.Resent, "speculative duplicates:", summary.Speculated)
+	}
+	for _, d := range summary.Degraded {
+		fmt.Printf("Degraded: epoch %d without sub-populations %v\n", d.Epoch, d.Missing)
+	}
+	if summary.Stages != nil {
+		st := coa.SummarizeStages(summary.Stages...)
+		fmt.Printf("Moves: %.1f%% summer, %.1f%% competition, %.1f%% shredding, %.1f%% eating\n",
+			100*st.Summer, 100*st.Competition, 100*st.Shredding, 100*st.Eating)
+		fmt.Printf("Moves kept: %.1f%%, mean step: %g, iterations without a kept move: %d of %d\n",
+			100*st.Acceptance, st.StepMean, st.Stalled, st.Iterations)
+	}
+	if n := len(summary.Diversity); n > 0 {
+		d := summary.Diversity[n-1]
+		fmt.Printf("Diversity: %g of the bounds' diagonal, mean distance %g, %.1f%% exploration\n", d.Relative, d.Pairwise, d.Exploration)
+	}
+	for island, events := range summary.Restarts {
+		for _, e := range events {
+			fmt.Printf("Restart: island %d after iteration %d (%s), %d crayfish, best fitness %g\n",
+				island, e.Iteration, e.Reason, e.Size, e.BestFitness)
+		}
+	}
+	if summary.Collapsed {
+		fmt.Println("Population collapsed, the run ended before T")
+	}
+	fmt.Println("Executed in:", summary.Elapsed)
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"crayfish/coa"
+	"crayfish/coordinator"
+)
+
+// Write what every iteration of every island did as CSV, one row per island and iteration, next to
+// the best fitness of all islands at that iteration
+func writeStages(path string, s coordinator.Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"island", "iteration", "temperature", "c", "summer", "competition", "shredding", "eating",
+		"food_mean", "food_max", "accepted", "acceptance", "step_mean", "step_max", "variant", "global_best"})
+	g := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for island, stages := range s.Stages {
+		for _, st := range stages {
+			best := ""
+			if st.Iteration < len(s.GlobalConverge) {
+				best = g(s.GlobalConverge[st.Iteration])
+			}
+			w.Write([]string{strconv.Itoa(island), strconv.Itoa(st.Iteration), g(st.Temperature), g(st.C),
+				strconv.Itoa(st.Summer), strconv.Itoa(st.Competition), strconv.Itoa(st.Shredding), strconv.Itoa(st.Eating),
+				g(st.FoodMean), g(st.FoodMax), strconv.Itoa(st.Accepted), g(st.AcceptanceRate()), g(st.StepMean), g(st.StepMax), strconv.Itoa(st.Variant), best})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("cli: writing the stages to %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// Write the diversity as CSV, one row per island and iteration (island "all" for the whole
+// population after every epoch), with the standard deviation of every dimension in the last columns
+func writeDiversity(path string, s coordinator.Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dim := len(s.BestPosition)
+	header := []string{"island", "iteration", "pairwise", "centroid", "relative", "spread", "exploration", "exploitation"}
+	for j := 0; j < dim; j++ {
+		header = append(header, fmt.Sprintf("std_%d", j))
+	}
+	w := csv.NewWriter(f)
+	w.Write(header)
+	g := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	row := func(island string, d coa.Diversity) {
+		r := []string{island, strconv.Itoa(d.Iteration), g(d.Pairwise), g(d.Centroid), g(d.Relative), g(d.Spread),
+			g(d.Exploration), g(d.Exploitation)}
+		for _, std := range d.Std {
+			r = append(r, g(std))
+		}
+		w.Write(r)
+	}
+	for island, diversity := range s.IslandDiversity {
+		for _, d := range diversity {
+			row(strconv.Itoa(island), d)
+		}
+	}
+	for _, d := range s.Diversity {
+		row("all", d)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("cli: writing the diversity to %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// Write the Pareto front of a multi-objective run as CSV, one row per position: its objectives, then
+// its coordinates
+func writeFront(path string, s coordinator.Summary) error {
+	if s.Front == nil || len(s.Front.Objectives) == 0 {
+		return fmt.Errorf("cli: %s has a single objective, no front to write", s.JobID)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var header []string
+	for m := range s.Front.Objectives[0] {
+		header = append(header, fmt.Sprintf("f_%d", m+1))
+	}
+	for j := range s.Front.Positions[0] {
+		header = append(header, fmt.Sprintf("x_%d", j))
+	}
+	w := csv.NewWriter(f)
+	w.Write(header)
+	g := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	for i, objectives := range s.Front.Objectives {
+		var row []string
+		for _, v := range objectives {
+			row = append(row, g(v))
+		}
+		for _, v := range s.Front.Positions[i] {
+			row = append(row, g(v))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("cli: writing the front to %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net/http"
+
+	"crayfish/broker"
+	"crayfish/checkpoint"
+	"crayfish/globalbest"
+	"crayfish/handler"
+	"crayfish/metrics"
+)
+
+// Stores a worker brings to the tasks that need them
+type storeFlags struct {
+	bestAddr    string
+	checkpoints string
+}
+
+func (f *storeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.bestAddr, "best-addr", "", "Redis holding the global best of asynchronous tasks (default: the -redis-* one)")
+	fs.StringVar(&f.checkpoints, "checkpoints", "", "directory, \"redis\" or redis://host:port for the checkpoints of tasks that ask for them (empty: none)")
+}
+
+// The worker's environment, -t is the iterations of tasks that don't set them
+func (f *storeFlags) env(s Settings) (handler.Env, error) {
+	env := handler.Env{T: s.Job.T}
+	var err error
+	if env.Best, err = globalbest.DialRedis(s.Redis.WithAddr(f.bestAddr)); err != nil {
+		return env, err
+	}
+	if f.checkpoints != "" {
+		if env.Checkpoints, err = checkpoint.Open(f.checkpoints, s.Redis); err != nil {
+			return env, err
+		}
+	}
+	return env, nil
+}
+
+// crayfish work: consume tasks, run COA on them and publish the results
+func work(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var f storeFlags
+	f.register(fs)
+	s, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	b, err := broker.New(s.Config)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	env, err := f.env(s)
+	if err != nil {
+		return err
+	}
+	watchDepth(ctx, s, b)
+
+	slog.Info("Worker consuming tasks", "broker", s.Kind)
+	if err := handler.Work(ctx, b, env); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// crayfish serve: the local stand-in for the serverless platform's HTTP gateway
+func serve(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	var f storeFlags
+	f.register(fs)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	publish := fs.Bool("publish", false, "also publish every result to the broker")
+	s, err := load(fs, args)
+	if err != nil {
+		return err
+	}
+
+	env, err := f.env(s)
+	if err != nil {
+		return err
+	}
+	h := &handler.Handler{Env: env}
+	if *publish {
+		b, err := broker.New(s.Config)
+		if err != nil {
+			return err
+		}
+		defer b.Close()
+		h.Publish = b.PublishResult
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle("/metrics", metrics.Handler()) // Also here, the platform may only route this port
+	server := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	slog.Info("Handler listening", "addr", *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
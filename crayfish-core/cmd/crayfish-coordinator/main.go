@@ -0,0 +1,24 @@
+// Coordinator of a distributed run in synchronization epochs (workers: cmd/crayfish-worker), or
+// asynchronous with the global best shared through Redis.
+//
+//	go run ./cmd/crayfish-coordinator -broker rabbitmq -f F6 -n 600 -k 10 -t 500 -epoch 50
+//	go run ./cmd/crayfish-coordinator -broker rabbitmq -async -chunk 10 -max-age 200ms
+//	go run ./cmd/crayfish-coordinator -broker memory -workers 4   # everything in one process
+//	go run ./cmd/crayfish-coordinator -broker memory -workers 4 -compare   # sync vs async, same seed
+//	go run ./cmd/crayfish-coordinator -broker redis-streams -task-timeout 30s -speculate 0.9 -max-missing 1
+//
+// Same as crayfish publish (see cmd/crayfish), kept for the scripts that already run it.
+package main
+
+import (
+	"log"
+	"os"
+
+	"crayfish/cli"
+)
+
+func main() {
+	if err := cli.Main(append([]string{"publish"}, os.Args[1:]...)); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,21 @@
+// Local stand-in for the serverless platform: serves the sub-population handler over HTTP, the way
+// the platform's HTTP gateway would invoke it. Also the container's entrypoint (see Dockerfile).
+//
+//	go run ./cmd/crayfish-handler -addr :8080
+//	curl -d '{"function":"F6","t":100,"size":20,"dim":10,"seed":1}' -H 'Content-Type: application/json' localhost:8080
+//
+// Same as crayfish serve (see cmd/crayfish), kept for the scripts that already run it.
+package main
+
+import (
+	"log"
+	"os"
+
+	"crayfish/cli"
+)
+
+func main() {
+	if err := cli.Main(append([]string{"serve"}, os.Args[1:]...)); err != nil {
+		log.Fatal(err)
+	}
+}
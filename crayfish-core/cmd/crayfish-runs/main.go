@@ -0,0 +1,248 @@
+// Query the run history recorded with -db (see cmd/crayfish-coordinator).
+//
+//	go run ./cmd/crayfish-runs list -f F6 -dim 500 -since 2024-05-01
+//	go run ./cmd/crayfish-runs list -mode async -param chunk=10 -limit 5
+//	go run ./cmd/crayfish-runs show <id>
+//	go run ./cmd/crayfish-runs compare <id> <id> ...
+//	go run ./cmd/crayfish-runs delete <id>
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"crayfish/runs"
+)
+
+// Dates accepted by -since and -until
+var dateLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: crayfish-runs [-db file] list|show|compare|delete [flags] [ids]")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	db := flag.String("db", "crayfish-runs.db", "run history file")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+	}
+
+	store, err := runs.Open(*db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "list":
+		err = list(store, args)
+	case "show":
+		err = show(store, args)
+	case "compare":
+		err = compare(store, args)
+	case "delete":
+		for _, id := range args {
+			if err = store.Delete(id); err != nil {
+				break
+			}
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Repeatable -param key=value
+type paramFlag runs.Params
+
+func (p paramFlag) String() string { return "" }
+
+func (p paramFlag) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok {
+		return fmt.Errorf("want key=value, got %q", v)
+	}
+	p[key] = value
+	return nil
+}
+
+// Flag taking a date or a date and time
+type dateFlag struct{ t *time.Time }
+
+func (d dateFlag) String() string { return "" }
+
+func (d dateFlag) Set(v string) error {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			*d.t = t
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown date %q (use 2006-01-02, \"2006-01-02 15:04\" or RFC 3339)", v)
+}
+
+func list(store *runs.Store, args []string) error {
+	f := runs.Filter{Params: runs.Params{}}
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.StringVar(&f.Function, "f", "", "benchmark")
+	fs.StringVar(&f.Algorithm, "algorithm", "", "algorithm")
+	fs.StringVar(&f.Mode, "mode", "", "mode (sync, async)")
+	fs.IntVar(&f.N, "n", 0, "population size")
+	fs.IntVar(&f.K, "k", 0, "sub-populations")
+	fs.IntVar(&f.T, "t", 0, "iterations")
+	fs.IntVar(&f.Dim, "dim", 0, "dimension")
+	fs.Var(paramFlag(f.Params), "param", "other parameter, key=value (repeatable)")
+	fs.Var(dateFlag{&f.Since}, "since", "runs started at or after this date")
+	fs.Var(dateFlag{&f.Until}, "until", "runs started before this date")
+	fs.IntVar(&f.Limit, "limit", 20, "most recent runs only (0: all)")
+	fs.Parse(args)
+
+	found, err := store.List(f)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tStarted\tAlgorithm\tMode\tFunction\tN\tK\tT\tDim\tBest Fitness\tElapsed\tHost")
+	for _, r := range found {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%g\t%s\t%s\n",
+			r.ID, r.Started.Format("2006-01-02 15:04:05"), r.Algorithm, r.Mode, r.Function,
+			r.N, r.K, r.T, r.Dim, r.BestFitness, r.Elapsed.Round(time.Millisecond), r.Host)
+	}
+	return w.Flush()
+}
+
+func show(store *runs.Store, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the whole record as JSON")
+	fs.Parse(args)
+
+	for _, id := range fs.Args() {
+		r, err := store.Get(id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if *asJSON {
+			out, err := json.MarshalIndent(r, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(out))
+			continue
+		}
+		fmt.Println("Run:", r.ID, "job:", r.JobID)
+		fmt.Println("Started:", r.Started.Format(time.RFC3339), "on", r.Host, "took", r.Elapsed)
+		fmt.Printf("%s (%s) on %s: N=%d K=%d T=%d dim=%d seed=%d\n", r.Algorithm, r.Mode, r.Function, r.N, r.K, r.T, r.Dim, r.Seed)
+		for _, k := range sortedKeys(r.Params) {
+			fmt.Printf("  %s=%s\n", k, r.Params[k])
+		}
+		fmt.Println("Best Fitness:", r.BestFitness)
+		fmt.Println("Best Position:", r.BestPosition)
+		if r.Violation > 0 {
+			fmt.Println("Constraint violation:", r.Violation)
+		}
+		if r.Front != nil {
+			fmt.Printf("Pareto front: %d positions, hypervolume %g, IGD %g\n", r.Front.Size, r.Front.Hypervolume, r.Front.IGD)
+		}
+		fmt.Println("Convergence:", milestones(r.GlobalConverge))
+		for _, e := range r.Restarts {
+			fmt.Printf("Restart: island %d after iteration %d (%s), %d crayfish, best fitness %g\n",
+				e.Island, e.Iteration, e.Reason, e.Size, e.BestFitness)
+		}
+		if r.Degraded {
+			fmt.Println("Degraded: some sub-populations were missing")
+		}
+	}
+	return nil
+}
+
+// Fractions of the run at which compare and show sample the convergence curve
+var fractions = []float64{0.1, 0.25, 0.5, 0.75, 1}
+
+// Best fitness at 10%, 25%, 50%, 75% and 100% of the iterations
+func milestones(cov []float64) string {
+	if len(cov) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(fractions))
+	for i, f := range fractions {
+		t := max(int(f*float64(len(cov)))-1, 0)
+		parts[i] = fmt.Sprintf("%d:%g", t+1, cov[t])
+	}
+	return strings.Join(parts, " ")
+}
+
+func compare(store *runs.Store, ids []string) error {
+	if len(ids) < 2 {
+		return fmt.Errorf("compare needs at least two run IDs")
+	}
+	found := make([]runs.Run, len(ids))
+	for i, id := range ids {
+		r, err := store.Get(id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		found[i] = r
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	row := func(name string, value func(r runs.Run) string) {
+		fmt.Fprint(w, name)
+		for _, r := range found {
+			fmt.Fprint(w, "\t", value(r))
+		}
+		fmt.Fprintln(w)
+	}
+	row("Run", func(r runs.Run) string { return r.ID })
+	row("Started", func(r runs.Run) string { return r.Started.Format("2006-01-02 15:04:05") })
+	row("Algorithm", func(r runs.Run) string { return r.Algorithm + " " + r.Mode })
+	row("Function", func(r runs.Run) string { return r.Function })
+	row("N/K/T/dim", func(r runs.Run) string { return fmt.Sprintf("%d/%d/%d/%d", r.N, r.K, r.T, r.Dim) })
+	row("Seed", func(r runs.Run) string { return fmt.Sprint(r.Seed) })
+	row("Host", func(r runs.Run) string { return r.Host })
+	row("Elapsed", func(r runs.Run) string { return r.Elapsed.Round(time.Millisecond).String() })
+	row("Best Fitness", func(r runs.Run) string { return fmt.Sprintf("%g", r.BestFitness) })
+	for _, f := range fractions {
+		row(fmt.Sprintf("Best at %.0f%%", f*100), func(r runs.Run) string {
+			if len(r.GlobalConverge) == 0 {
+				return "-"
+			}
+			t := max(int(f*float64(len(r.GlobalConverge)))-1, 0)
+			return fmt.Sprintf("%g", r.GlobalConverge[t])
+		})
+	}
+
+	// The other parameters of any of the runs
+	all := runs.Params{}
+	for _, r := range found {
+		for k, v := range r.Params {
+			all[k] = v
+		}
+	}
+	for _, k := range sortedKeys(all) {
+		row(k, func(r runs.Run) string { return r.Params[k] })
+	}
+	return w.Flush()
+}
+
+func sortedKeys(p runs.Params) []string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
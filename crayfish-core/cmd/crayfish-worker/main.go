@@ -0,0 +1,20 @@
+// Broker worker: consumes sub-population tasks, runs COA on them and publishes the results.
+//
+//	go run ./cmd/crayfish-worker -broker redis-streams
+//	CRAYFISH_REDIS_PASSWORD=... go run ./cmd/crayfish-worker -config prod.yml
+//
+// Same as crayfish work (see cmd/crayfish), kept for the scripts that already run it.
+package main
+
+import (
+	"log"
+	"os"
+
+	"crayfish/cli"
+)
+
+func main() {
+	if err := cli.Main(append([]string{"work"}, os.Args[1:]...)); err != nil {
+		log.Fatal(err)
+	}
+}
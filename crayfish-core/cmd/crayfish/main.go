@@ -0,0 +1,23 @@
+// The crayfish command: every program as a subcommand, configured by flags or a YAML/JSON file.
+//
+//	go run ./cmd/crayfish run -f F6 -n 600 -k 10 -t 500 -dim 500
+//	go run ./cmd/crayfish publish -config prod.yml -async -chunk 10
+//	go run ./cmd/crayfish work -broker rabbitmq -pattern 'coa.*.d500'
+//	go run ./cmd/crayfish consume -broker redis-pubsub -out results
+//	go run ./cmd/crayfish bench -n 30 -t 500 -dim 30 -runs 10 F1 F6 F9
+//	go run ./cmd/crayfish serve -addr :8080
+//	go run ./cmd/crayfish -help   # lists the commands, benchmarks and parameters
+package main
+
+import (
+	"log"
+	"os"
+
+	"crayfish/cli"
+)
+
+func main() {
+	if err := cli.Main(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,186 @@
+package coa
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"crayfish/benchmarks"
+)
+
+// CheckpointVersion is the layout of Checkpoint written by this package
+const CheckpointVersion = 1
+
+// Checkpoint is the full state of an Optimizer between two iterations. Restoring it continues the
+// run exactly as if it had never stopped (for benchmarks that don't draw random numbers themselves,
+// F7 does).
+type Checkpoint struct {
+	Version  int    `msgpack:"version"`
+	Function string `msgpack:"function"` // Benchmark name, for the caller; the function isn't saved
+
+	T  int       `msgpack:"t"`
+	LB []float64 `msgpack:"lb"`
+	UB []float64 `msgpack:"ub"`
+
+	X        [][]float64 `msgpack:"x"`
+	FitnessF []float64   `msgpack:"fitness"`
+
+	BestPos       []float64 `msgpack:"bestPos"`
+	BestFitness   float64   `msgpack:"bestFitness"`
+	GlobalPos     []float64 `msgpack:"globalPos"`
+	GlobalFitness float64   `msgpack:"globalFitness"`
+	GlobalCov     []float64 `msgpack:"globalCov"`
+	Iteration     int       `msgpack:"iteration"`
+	Params        *Params   `msgpack:"params,omitempty"` // DefaultParams when missing (older checkpoints)
+	Stages        []Stage   `msgpack:"stages,omitempty"` // Recorded so far, the caller sets RecordStages again
+
+	Diversity []Diversity `msgpack:"diversity,omitempty"` // As Stages
+	MaxSpread float64     `msgpack:"maxSpread,omitempty"`
+	Collapsed bool        `msgpack:"collapsed,omitempty"`
+
+	Restarts []RestartEvent `msgpack:"restarts,omitempty"` // The caller sets Restart again
+	Stagnant int            `msgpack:"stagnant,omitempty"`
+
+	// State of the variant, the caller sets Variant again
+	Chaos  float64 `msgpack:"chaos,omitempty"`
+	CScale float64 `msgpack:"cScale,omitempty"`
+	LSStep float64 `msgpack:"lsStep,omitempty"`
+
+	// Constraint violations, the caller sets Problem and Constraints again
+	Violation       []float64 `msgpack:"violation,omitempty"`
+	BestViolation   float64   `msgpack:"bestViolation,omitempty"`
+	GlobalViolation float64   `msgpack:"globalViolation,omitempty"`
+	Epsilon         *float64  `msgpack:"epsilon,omitempty"` // ε0 once known
+
+	// State of the Source
+	Stream uint64 `msgpack:"stream"`
+	Drawn  uint64 `msgpack:"drawn"`
+}
+
+// EpochState is what an Optimizer carries from one epoch of a coordinated run to the next, whose
+// optimizer is a new one (on another worker maybe) given the crayfish and the global best: without
+// it the stagnation count, the diversity reference and the variant would start over every epoch
+type EpochState struct {
+	Stagnant  int
+	MaxSpread float64
+	Chaos     float64
+	CScale    float64
+	LSStep    float64
+	Epsilon   *float64 // ε0 once known
+}
+
+// EpochState of the optimizer as its epoch ends
+func (o *Optimizer) EpochState() EpochState {
+	s := EpochState{Stagnant: o.stagnant, MaxSpread: o.MaxSpread, Chaos: o.chaos, CScale: o.cScale, LSStep: o.lsStep}
+	if e := o.epsilon0; e >= 0 {
+		s.Epsilon = &e
+	}
+	return s
+}
+
+// ResumeEpoch continues from the state the optimizer of the previous epoch ended with
+func (o *Optimizer) ResumeEpoch(s EpochState) {
+	o.stagnant, o.MaxSpread = s.Stagnant, s.MaxSpread
+	o.chaos, o.cScale, o.lsStep = s.Chaos, s.CScale, s.LSStep
+	if s.Epsilon != nil {
+		o.epsilon0 = *s.Epsilon
+	}
+}
+
+// ErrNotCheckpointable is returned for optimizers whose random source can't be saved
+var ErrNotCheckpointable = errors.New("coa: optimizer has no checkpointable random source (use NewSeededOptimizer)")
+
+// Checkpoint captures the state, copying it so the optimizer can keep running
+func (o *Optimizer) Checkpoint(function string) (*Checkpoint, error) {
+	if o.src == nil {
+		return nil, ErrNotCheckpointable
+	}
+	stream, drawn := o.src.State()
+
+	X := make([][]float64, len(o.X))
+	for i := range X {
+		X[i] = append([]float64(nil), o.X[i]...)
+	}
+	params := o.Params
+	var epsilon *float64
+	if e := o.epsilon0; e >= 0 {
+		epsilon = &e
+	}
+	return &Checkpoint{
+		Version:         CheckpointVersion,
+		Function:        function,
+		T:               o.T,
+		LB:              append([]float64(nil), o.LB...),
+		UB:              append([]float64(nil), o.UB...),
+		X:               X,
+		FitnessF:        append([]float64(nil), o.FitnessF...),
+		BestPos:         append([]float64(nil), o.BestPos...),
+		BestFitness:     o.BestFitness,
+		GlobalPos:       append([]float64(nil), o.GlobalPos...),
+		GlobalFitness:   o.GlobalFitness,
+		GlobalCov:       append([]float64(nil), o.GlobalCov...),
+		Iteration:       o.Iteration,
+		Params:          &params,
+		Stages:          append([]Stage(nil), o.Stages...),
+		Diversity:       append([]Diversity(nil), o.Diversity...),
+		MaxSpread:       o.MaxSpread,
+		Collapsed:       o.Collapsed,
+		Restarts:        append([]RestartEvent(nil), o.Restarts...),
+		Stagnant:        o.stagnant,
+		Chaos:           o.chaos,
+		CScale:          o.cScale,
+		LSStep:          o.lsStep,
+		Violation:       append([]float64(nil), o.Violation...),
+		BestViolation:   o.BestViolation,
+		GlobalViolation: o.GlobalViolation,
+		Epsilon:         epsilon,
+		Stream:          stream,
+		Drawn:           drawn,
+	}, nil
+}
+
+// RestoreOptimizer continues the run saved in cp with the benchmark F
+func RestoreOptimizer(cp *Checkpoint, F benchmarks.FunctionType) (*Optimizer, error) {
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("coa: checkpoint version %d, expected %d", cp.Version, CheckpointVersion)
+	}
+	if len(cp.X) == 0 || len(cp.X[0]) == 0 {
+		return nil, fmt.Errorf("coa: checkpoint has no crayfish")
+	}
+	N, dim := len(cp.X), len(cp.X[0])
+	for i := range cp.X {
+		if len(cp.X[i]) != dim {
+			return nil, fmt.Errorf("coa: checkpoint crayfish %d has dimension %d, expected %d", i, len(cp.X[i]), dim)
+		}
+	}
+	if len(cp.FitnessF) != N || len(cp.BestPos) != dim || len(cp.GlobalPos) != dim ||
+		len(cp.GlobalCov) != cp.T || cp.Iteration < 0 || cp.Iteration > cp.T {
+		return nil, fmt.Errorf("coa: inconsistent checkpoint (N=%d dim=%d T=%d iteration %d)", N, dim, cp.T, cp.Iteration)
+	}
+
+	src := RestoreSource(cp.Stream, cp.Drawn)
+	o := newOptimizer(cp.T, cp.LB, cp.UB, cp.X, F, rand.New(src))
+	o.src = src
+	copy(o.FitnessF, cp.FitnessF)
+	copy(o.BestPos, cp.BestPos)
+	o.BestFitness = cp.BestFitness
+	copy(o.GlobalPos, cp.GlobalPos)
+	o.GlobalFitness = cp.GlobalFitness
+	copy(o.GlobalCov, cp.GlobalCov)
+	o.Iteration = cp.Iteration
+	if cp.Params != nil {
+		o.Params = *cp.Params
+	}
+	o.Stages = cp.Stages
+	o.Diversity, o.MaxSpread, o.Collapsed = cp.Diversity, cp.MaxSpread, cp.Collapsed
+	o.Restarts, o.stagnant = cp.Restarts, cp.Stagnant
+	o.chaos, o.cScale, o.lsStep = cp.Chaos, cp.CScale, cp.LSStep
+	if len(cp.Violation) == N {
+		copy(o.Violation, cp.Violation)
+	}
+	o.BestViolation, o.GlobalViolation = cp.BestViolation, cp.GlobalViolation
+	if cp.Epsilon != nil {
+		o.epsilon0 = *cp.Epsilon
+	}
+	return o, nil
+}
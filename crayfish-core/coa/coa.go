@@ -0,0 +1,379 @@
+/*
+Package coa is the Crayfish Optimization Algorithm shared by the workers, the same algorithm as
+crayfish.go at the root of the repository but with the benchmark and the random source passed in.
+
+Main reference: https://github.com/rao12138/COA-s-code/tree/main/COA
+Source: https://dl.acm.org/doi/10.1007/s10462-023-10567-4
+*/
+package coa
+
+import (
+	"math"
+	"math/rand"
+
+	"crayfish/benchmarks"
+)
+
+// InitializePopulation generates the N x dim population uniformly within the bounds
+func InitializePopulation(rng *rand.Rand, N, dim int, lb, ub []float64) [][]float64 {
+	X := make([][]float64, N)
+	for i := range X {
+		X[i] = make([]float64, dim)
+		for j := range X[i] {
+			l, u := bound(lb, ub, j)
+			X[i][j] = rng.Float64()*(u-l) + l
+		}
+	}
+	return X
+}
+
+// DividePopulation splits the population into k sub-populations (the first ones get one more
+// crayfish when the division is not even)
+func DividePopulation(X [][]float64, k int) [][][]float64 {
+	totalSize := len(X)
+	baseSubPopSize := totalSize / k
+	remainder := totalSize % k
+
+	Xsub := make([][][]float64, k)
+
+	startIndex := 0
+	for i := 0; i < k; i++ {
+		subPopSize := baseSubPopSize
+		if remainder > 0 {
+			subPopSize++
+			remainder--
+		}
+		Xsub[i] = X[startIndex : startIndex+subPopSize]
+		startIndex += subPopSize
+	}
+	return Xsub
+}
+
+// Bounds of dimension j, a single value applies to all dimensions
+func bound(lb, ub []float64, j int) (float64, float64) {
+	if len(ub) == 1 {
+		return lb[0], ub[0]
+	}
+	return lb[j], ub[j]
+}
+
+// Optimizer holds the state of a COA run so it can be advanced a few iterations at a time (an
+// epoch of a distributed run) and be told about better positions found elsewhere
+type Optimizer struct {
+	F      benchmarks.FunctionType
+	LB, UB []float64
+	T      int // Total iterations, drives the decreasing curve C
+
+	X        [][]float64 // The crayfish
+	FitnessF []float64   // Their fitness
+
+	BestPos       []float64 // Best position found so far (XL)
+	BestFitness   float64
+	GlobalPos     []float64 // Best position of the last iteration (XG)
+	GlobalFitness float64
+
+	GlobalCov []float64 // Best fitness of every iteration (GlobalFitness)
+	Iteration int       // Next iteration to run
+
+	Params  Params // DefaultParams unless changed before running
+	Variant string // VariantCOA when empty, see VariantNames
+
+	// Constraints of F and how they are handled, none unless set with Constrain. The fitness stays
+	// the objective, compared along with the violation of the constraints.
+	Problem         benchmarks.Constrained
+	Constraints     Constraints
+	Violation       []float64 // Of every crayfish, 0 when feasible
+	BestViolation   float64   // Of BestPos
+	GlobalViolation float64   // Of GlobalPos
+	epsilon0        float64   // ε of the first iteration (ConstraintEpsilon), -1 until known
+
+	Counters Counters // Work done by this optimizer (not saved in checkpoints)
+
+	RecordStages bool    // Record every iteration in Stages
+	Stages       []Stage // The iterations run while RecordStages was set
+
+	RecordDiversity bool        // Record the diversity after every iteration in Diversity
+	Diversity       []Diversity // The iterations run while RecordDiversity was set
+	MaxSpread       float64     // Largest Diversity.Spread so far, of the initial population included
+	Collapsed       bool        // Stopped before T because of Params.MinDiversity
+
+	Restart  Restart        // No restarts unless changed before running
+	Restarts []RestartEvent // The restarts so far
+	stagnant int            // Iterations since the best position last improved
+
+	chaos  float64 // VariantChaotic: state of the logistic map
+	cScale float64 // VariantAdaptive: scale of C, 1 when 0
+	lsStep float64 // VariantHybrid: step of the pattern search relative to the bounds, localSearchStep when 0
+
+	rng       *rand.Rand
+	src       *Source // Set when the random state can be checkpointed
+	xf, xfood []float64
+	xnew      [][]float64
+}
+
+// Counters of the work of an optimizer, for the metrics: evaluations of F, iterations run and the
+// moves of every stage
+type Counters struct {
+	Evaluations int64
+	Iterations  int64
+	Summer      int64 // Moves to the cave (Equation 6)
+	Competition int64 // Equation 8
+	Foraging    int64 // Equations 12 and 13
+	Restarts    int64
+}
+
+// NewOptimizer evaluates the population X (updated in place while running) for a run of T iterations
+func NewOptimizer(T int, lb, ub []float64, X [][]float64, F benchmarks.FunctionType, rng *rand.Rand) *Optimizer {
+	o := newOptimizer(T, lb, ub, X, F, rng)
+	N := len(X)
+
+	for i := 0; i < N; i++ {
+		o.FitnessF[i] = F(X[i])
+		o.Counters.Evaluations++
+		if o.FitnessF[i] < o.BestFitness {
+			o.BestFitness = o.FitnessF[i]
+			copy(o.BestPos, X[i])
+		}
+	}
+
+	// Update best position to Global position
+	copy(o.GlobalPos, o.BestPos)
+	o.GlobalFitness = o.BestFitness
+	return o
+}
+
+// NewSeededOptimizer is NewOptimizer with its random numbers drawn from NewSource(seed), so the run
+// can be checkpointed
+func NewSeededOptimizer(T int, lb, ub []float64, X [][]float64, F benchmarks.FunctionType, seed int64) *Optimizer {
+	src := NewSource(seed)
+	o := NewOptimizer(T, lb, ub, X, F, rand.New(src))
+	o.src = src
+	return o
+}
+
+// Allocate the state of a run, without evaluating anything
+func newOptimizer(T int, lb, ub []float64, X [][]float64, F benchmarks.FunctionType, rng *rand.Rand) *Optimizer {
+	N := len(X) // size of the (sub-)population
+	dim := len(X[0])
+
+	o := &Optimizer{
+		F: F, LB: lb, UB: ub, T: T,
+		X:           X,
+		FitnessF:    make([]float64, N),
+		Violation:   make([]float64, N),
+		BestPos:     make([]float64, dim),
+		BestFitness: math.Inf(1),
+		GlobalPos:   make([]float64, dim),
+		GlobalCov:   make([]float64, T),
+		Params:      DefaultParams(),
+		epsilon0:    -1,
+		rng:         rng,
+		xf:          make([]float64, dim), // For Xshade -- array for the cave
+		xfood:       make([]float64, dim),
+		xnew:        make([][]float64, N),
+	}
+	for i := range o.xnew {
+		o.xnew[i] = make([]float64, dim)
+	}
+	return o
+}
+
+// Done reports whether all T iterations ran, or the population collapsed
+func (o *Optimizer) Done() bool {
+	return o.Iteration >= o.T || o.Collapsed
+}
+
+// Share hands the optimizer a position found elsewhere (e.g. the global best of the other islands),
+// it becomes the best position if it is better
+func (o *Optimizer) Share(pos []float64, fitness float64) {
+	if len(pos) != len(o.BestPos) {
+		return
+	}
+	if v := o.violation(pos); o.better(fitness, v, o.BestFitness, o.BestViolation) {
+		o.BestFitness, o.BestViolation = fitness, v
+		copy(o.BestPos, pos)
+	}
+}
+
+// Run advances the optimizer by n iterations (fewer when T is reached)
+func (o *Optimizer) Run(n int) {
+	for ; n > 0 && !o.Done(); n-- {
+		o.Step()
+	}
+}
+
+// Step runs one iteration of COA
+func (o *Optimizer) Step() {
+	var (
+		X, Xnew, fitnessF = o.X, o.xnew, o.FitnessF
+		Xf, Xfood         = o.xf, o.xfood
+		F, rng            = o.F, o.rng
+		N, dim            = len(o.X), len(o.BestPos)
+		t, T              = o.Iteration, o.T
+		p                 = o.Params
+	)
+
+	measure := o.RecordDiversity || p.MinDiversity > 0
+	if measure && o.MaxSpread == 0 { // Exploration is relative to the initial population
+		o.MaxSpread = measureDiversity(X, o.LB, o.UB, false).Spread
+	}
+
+	//Decreasing curve --> Equation 7
+	C := p.C2 - (float64(t) / float64(T))
+	if o.Variant == VariantAdaptive && o.cScale > 0 {
+		C *= o.cScale
+	}
+	//Define the temprature from Equation 3
+	var tmp float64
+	if o.Variant == VariantChaotic {
+		tmp = o.chaoticTemperature()
+	} else {
+		tmp = rng.Float64()*(p.TempMax-p.TempMin) + p.TempMin
+	}
+	stage := Stage{Iteration: t, Temperature: tmp, C: C}
+
+	for i := 0; i < dim; i++ { // Calculating the Cave -> Xshade = XL + XG/2
+		Xf[i] = (o.BestPos[i] + o.GlobalPos[i]) / 2
+	}
+	copy(Xfood, o.BestPos)
+
+	for i := 0; i < N; i++ {
+		if tmp > p.TempHigh { // Summer resort stage
+			if rng.Float64() < p.Summer {
+				o.Counters.Summer++
+				stage.Summer++
+				for j := 0; j < dim; j++ { // Equation 6
+					Xnew[i][j] = X[i][j] + C*rng.Float64()*(Xf[j]-X[i][j])
+				}
+			} else { // Competition Stage
+				o.Counters.Competition++
+				stage.Competition++
+				for j := 0; j < dim; j++ {
+					z := rng.Intn(N)                       // Random crayfish
+					Xnew[i][j] = X[i][j] - X[z][j] + Xf[j] // Equation 8
+				}
+			}
+		} else { // Foraging stage
+			o.Counters.Foraging++
+			o.Counters.Evaluations++ // F(Xfood)
+			P := p.C3 * rng.Float64() * fitnessF[i] / F(Xfood)
+			stage.FoodMean += P
+			stage.FoodMax = math.Max(stage.FoodMax, P)
+			if P > p.FoodSize {
+				stage.Shredding++
+				//Food is broken down becuase it's too big
+				for j := 0; j < dim; j++ {
+					Xfood[j] *= math.Exp(-1 / P)
+					Xnew[i][j] = X[i][j] + math.Cos(2*math.Pi*rng.Float64())*Xfood[j]*p.intake(tmp) - math.Sin(2*math.Pi*rng.Float64())*Xfood[j]*p.intake(tmp)
+				} // ^^ Equation 13: crayfish foraging
+			} else {
+				stage.Eating++
+				for j := 0; j < dim; j++ { // The case where the food is a moderate size
+					Xnew[i][j] = (X[i][j]-Xfood[j])*p.intake(tmp) + p.intake(tmp)*rng.Float64()*X[i][j]
+				}
+			}
+			if o.Variant == VariantLevy { // A Lévy flight relative to the distance to the best crayfish
+				for j := 0; j < dim; j++ {
+					Xnew[i][j] += levyScale * o.levy() * (X[i][j] - o.BestPos[j])
+				}
+			}
+		}
+	}
+
+	// Boundary conditions checks
+	for i := 0; i < N; i++ {
+		for j := 0; j < dim; j++ {
+			l, u := bound(o.LB, o.UB, j)
+			Xnew[i][j] = math.Max(l, math.Min(u, Xnew[i][j]))
+		}
+		if o.RecordStages {
+			step := stepLength(X[i], Xnew[i])
+			stage.StepMean += step
+			stage.StepMax = math.Max(stage.StepMax, step)
+		}
+	}
+
+	//Global update stuff
+	best, bestViolation := o.BestFitness, o.BestViolation
+	copy(o.GlobalPos, Xnew[0])
+	o.GlobalFitness, o.GlobalViolation = F(o.GlobalPos), o.violation(o.GlobalPos)
+
+	for i := 0; i < N; i++ {
+		NewFitness, violation := F(Xnew[i]), o.violation(Xnew[i])
+		if o.better(NewFitness, violation, o.GlobalFitness, o.GlobalViolation) {
+			o.GlobalFitness, o.GlobalViolation = NewFitness, violation
+			copy(o.GlobalPos, Xnew[i])
+		}
+
+		// Update population to a new location
+		if o.better(NewFitness, violation, fitnessF[i], o.Violation[i]) {
+			stage.Accepted++
+			fitnessF[i], o.Violation[i] = NewFitness, violation
+			copy(X[i], Xnew[i])
+			if o.better(fitnessF[i], o.Violation[i], o.BestFitness, o.BestViolation) {
+				o.BestFitness, o.BestViolation = fitnessF[i], o.Violation[i]
+				copy(o.BestPos, X[i])
+			}
+		}
+	}
+
+	switch o.Variant {
+	case VariantAdaptive:
+		o.adapt(stage.Accepted)
+	case VariantOpposition:
+		stage.Variant = o.eliteOpposition()
+	case VariantHybrid:
+		if (t+1)%localSearchEvery == 0 && o.localSearch() {
+			stage.Variant = 1
+		}
+	}
+
+	o.Counters.Evaluations += int64(N) + 1
+	o.Counters.Iterations++
+	o.GlobalCov[t] = o.GlobalFitness
+	o.Iteration++
+
+	if o.RecordStages {
+		if foraging := stage.Shredding + stage.Eating; foraging > 0 {
+			stage.FoodMean /= float64(foraging)
+		}
+		stage.StepMean /= float64(N)
+		o.Stages = append(o.Stages, stage)
+	}
+
+	if o.better(o.BestFitness, o.BestViolation, best, bestViolation) {
+		o.stagnant = 0
+	} else {
+		o.stagnant++
+	}
+
+	if measure {
+		d := measureDiversity(X, o.LB, o.UB, o.RecordDiversity)
+		d.Iteration = t
+		d.Balance(&o.MaxSpread)
+		if o.RecordDiversity {
+			o.Diversity = append(o.Diversity, d)
+		}
+		if p.Collapsed(d) && !o.Done() { // Nothing left to explore: start over, or stop with the best as it is
+			if o.canRestart() {
+				o.restart("diversity")
+				return
+			}
+			o.Collapsed = true
+			for k := o.Iteration; k < T; k++ {
+				o.GlobalCov[k] = o.GlobalFitness
+			}
+		}
+	}
+	if o.Restart.Stagnation > 0 && o.stagnant >= o.Restart.Stagnation && o.canRestart() {
+		o.restart("stagnation")
+	}
+}
+
+// Crayfish runs T iterations of COA on the population X (updated in place) and returns the best
+// position, its fitness and the best fitness of every iteration
+func Crayfish(T int, lb, ub []float64, X [][]float64, F benchmarks.FunctionType, rng *rand.Rand) (bestPos []float64, bestFit float64, globalCov []float64) {
+	o := NewOptimizer(T, lb, ub, X, F, rng)
+	o.Run(T)
+	return o.BestPos, o.BestFitness, o.GlobalCov
+}
@@ -0,0 +1,155 @@
+package coa
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"crayfish/benchmarks"
+)
+
+// Constraint handling: a penalty on the violation added to the objective, static (Penalty times the
+// violation) or growing with the iterations ((Penalty*t)² times the squared violation, as Joines and
+// Houck), Deb's feasibility rules (a feasible crayfish beats an infeasible one, two feasible ones
+// compare their objective, two infeasible ones their violation), or the ε-constraint method of
+// Takahama and Sakai (Deb's rules counting violations up to ε as feasible, ε shrinking from the
+// violation of the best fifth of the initial population to 0 at a fifth of T)
+const (
+	ConstraintStatic  = "static"
+	ConstraintDynamic = "dynamic"
+	ConstraintDeb     = "deb"
+	ConstraintEpsilon = "epsilon"
+)
+
+const (
+	epsilonShare = 0.2 // ε starts at the violation of the crayfish this far in the initial population
+	epsilonEnd   = 0.2 // ... and reaches 0 this far in the run
+	epsilonPower = 5   // Exponent of its decrease
+)
+
+// Constraints is how an optimizer handles the constraints of a benchmarks.Constrained problem,
+// Deb's rules when Handling is empty
+type Constraints struct {
+	Handling  string
+	Penalty   float64 // static: factor of the violation, 1e6 when 0; dynamic: C, 0.5 when 0
+	Tolerance float64 // An equality h(x) = 0 holds within it, 1e-4 when 0
+}
+
+// Validate rejects unknown handlings and settings they can't work with
+func (c Constraints) Validate() error {
+	switch c.Handling {
+	case "", ConstraintStatic, ConstraintDynamic, ConstraintDeb, ConstraintEpsilon:
+	default:
+		return fmt.Errorf("coa: unknown constraint handling %q (known: %s, %s, %s, %s)", c.Handling,
+			ConstraintStatic, ConstraintDynamic, ConstraintDeb, ConstraintEpsilon)
+	}
+	if c.Penalty < 0 || c.Tolerance < 0 {
+		return fmt.Errorf("coa: constraint penalty and tolerance can't be negative, got %g and %g", c.Penalty, c.Tolerance)
+	}
+	return nil
+}
+
+// Violation of the constraints of p at x: the sum of the inequalities above 0 and of the equalities
+// off by more than the tolerance, 0 when x is feasible
+func (c Constraints) Violation(p benchmarks.Constrained, x []float64) float64 {
+	tolerance := c.Tolerance
+	if tolerance == 0 {
+		tolerance = 1e-4
+	}
+	g, h := p.Constraints(x)
+	var v float64
+	for _, gi := range g {
+		v += math.Max(0, gi)
+	}
+	for _, hj := range h {
+		v += math.Max(0, math.Abs(hj)-tolerance)
+	}
+	return v
+}
+
+// Better tells whether a position of fitness f1 and constraint violation v1 beats one of f2 and v2
+// by Deb's rules: the smaller violation wins, the smaller fitness between equal violations (both
+// feasible). It is how results of different islands compare, whatever their handling.
+func Better(f1, v1, f2, v2 float64) bool {
+	if v1 == v2 {
+		return f1 < f2
+	}
+	return v1 < v2
+}
+
+// Constrain has a new optimizer minimize F subject to the constraints of p, handled as c: the
+// best position is picked again by the handling's rules. Without p (nil) there are no constraints.
+// An optimizer restored from a checkpoint only needs Problem and Constraints set again.
+func (o *Optimizer) Constrain(p benchmarks.Constrained, c Constraints) {
+	o.Problem, o.Constraints = p, c
+	for i, x := range o.X {
+		o.Violation[i] = o.violation(x)
+	}
+	o.BestViolation = o.violation(o.BestPos)
+	for i, x := range o.X {
+		if o.better(o.FitnessF[i], o.Violation[i], o.BestFitness, o.BestViolation) {
+			o.BestFitness, o.BestViolation = o.FitnessF[i], o.Violation[i]
+			copy(o.BestPos, x)
+		}
+	}
+	copy(o.GlobalPos, o.BestPos)
+	o.GlobalFitness, o.GlobalViolation = o.BestFitness, o.BestViolation
+
+	if p != nil {
+		v := append([]float64(nil), o.Violation...)
+		sort.Float64s(v)
+		o.epsilon0 = v[int(epsilonShare*float64(len(v)))]
+	}
+}
+
+// Constraint violation of x, 0 without constraints
+func (o *Optimizer) violation(x []float64) float64 {
+	if o.Problem == nil {
+		return 0
+	}
+	return o.Constraints.Violation(o.Problem, x)
+}
+
+// Whether a crayfish of fitness f1 and violation v1 is better than one of f2 and v2, by the
+// constraint handling of the optimizer
+func (o *Optimizer) better(f1, v1, f2, v2 float64) bool {
+	if o.Problem == nil {
+		return f1 < f2
+	}
+	switch o.Constraints.Handling {
+	case ConstraintStatic, ConstraintDynamic:
+		return f1+o.penalty(v1) < f2+o.penalty(v2)
+	case ConstraintEpsilon:
+		if eps := o.epsilon(); v1 <= eps && v2 <= eps {
+			return f1 < f2
+		}
+	}
+	return Better(f1, v1, f2, v2)
+}
+
+// Penalty of the violation v at this iteration
+func (o *Optimizer) penalty(v float64) float64 {
+	if v == 0 {
+		return 0
+	}
+	c := o.Constraints.Penalty
+	if o.Constraints.Handling == ConstraintDynamic {
+		if c == 0 {
+			c = 0.5
+		}
+		return math.Pow(c*float64(o.Iteration+1), 2) * v * v
+	}
+	if c == 0 {
+		c = 1e6
+	}
+	return c * v
+}
+
+// ε of the iteration: ε0 (1 - t/Tc)^cp until Tc, 0 afterwards
+func (o *Optimizer) epsilon() float64 {
+	end := epsilonEnd * float64(o.T)
+	if t := float64(o.Iteration); t < end {
+		return math.Max(o.epsilon0, 0) * math.Pow(1-t/end, epsilonPower)
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package coa
+
+import (
+	"math"
+	"slices"
+)
+
+// Diversity is how spread out the crayfish are after an iteration. Pairwise and Centroid are in the
+// units of the search space, Relative is Centroid over the diagonal of the bounds so that one
+// threshold (Params.MinDiversity) fits every benchmark. Spread is the dimension-wise diversity of
+// Hussain et al. (the mean distance to the median, averaged over the dimensions), and Exploration
+// is it as a percentage of the largest Spread of the run so far, Exploitation the rest.
+type Diversity struct {
+	Iteration int
+
+	Pairwise float64   // Mean Euclidean distance between two crayfish
+	Centroid float64   // Mean Euclidean distance to the centroid
+	Relative float64   // Centroid over the diagonal of the bounds
+	Std      []float64 // Standard deviation of every dimension
+	Spread   float64   // Mean over the dimensions of the mean |median - x|
+
+	Exploration, Exploitation float64 // Percentages, summing to 100
+}
+
+// MeasureDiversity measures the population X within the bounds lb, ub (Exploration and
+// Exploitation are left to the caller, who knows the largest Spread)
+func MeasureDiversity(X [][]float64, lb, ub []float64) Diversity {
+	return measureDiversity(X, lb, ub, true)
+}
+
+// Pairwise is the only quadratic measure, skipped when the diversity is only compared with
+// Params.MinDiversity
+func measureDiversity(X [][]float64, lb, ub []float64, pairwise bool) Diversity {
+	var d Diversity
+	N := len(X)
+	if N == 0 {
+		return d
+	}
+	dim := len(X[0])
+
+	centroid := make([]float64, dim)
+	for _, x := range X {
+		for j, v := range x {
+			centroid[j] += v
+		}
+	}
+	for j := range centroid {
+		centroid[j] /= float64(N)
+	}
+
+	d.Std = make([]float64, dim)
+	for _, x := range X {
+		d.Centroid += stepLength(centroid, x)
+		for j, v := range x {
+			d.Std[j] += (v - centroid[j]) * (v - centroid[j])
+		}
+	}
+	d.Centroid /= float64(N)
+	for j := range d.Std {
+		d.Std[j] = math.Sqrt(d.Std[j] / float64(N))
+	}
+
+	if pairwise && N > 1 {
+		for i := 0; i < N; i++ {
+			for k := i + 1; k < N; k++ {
+				d.Pairwise += stepLength(X[i], X[k])
+			}
+		}
+		d.Pairwise /= float64(N*(N-1)) / 2
+	}
+
+	column := make([]float64, N)
+	for j := 0; j < dim; j++ {
+		for i, x := range X {
+			column[i] = x[j]
+		}
+		slices.Sort(column)
+		median := column[N/2]
+		if N%2 == 0 {
+			median = (column[N/2-1] + column[N/2]) / 2
+		}
+		var sum float64
+		for _, v := range column {
+			sum += math.Abs(median - v)
+		}
+		d.Spread += sum / float64(N)
+	}
+	d.Spread /= float64(dim)
+
+	var diagonal float64
+	for j := 0; j < dim; j++ {
+		l, u := bound(lb, ub, j)
+		diagonal += (u - l) * (u - l)
+	}
+	if diagonal > 0 {
+		d.Relative = d.Centroid / math.Sqrt(diagonal)
+	}
+	return d
+}
+
+// Balance sets Exploration and Exploitation from the largest Spread seen, which it updates
+func (d *Diversity) Balance(maxSpread *float64) {
+	*maxSpread = math.Max(*maxSpread, d.Spread)
+	if *maxSpread > 0 {
+		d.Exploration = 100 * d.Spread / *maxSpread
+		d.Exploitation = 100 - d.Exploration
+	}
+}
+
+// Collapsed reports whether the population's diversity fell below Params.MinDiversity (never when 0)
+func (p Params) Collapsed(d Diversity) bool {
+	return p.MinDiversity > 0 && d.Relative < p.MinDiversity
+}
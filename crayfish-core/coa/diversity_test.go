@@ -0,0 +1,147 @@
+package coa
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"crayfish/benchmarks"
+)
+
+func TestMeasureDiversity(t *testing.T) {
+	square := [][]float64{{0, 0}, {2, 0}, {0, 2}, {2, 2}} // Around (1, 1) in [0, 4]²
+	d := MeasureDiversity(square, []float64{0}, []float64{4})
+	want := Diversity{
+		Pairwise: (4*2 + 2*2*math.Sqrt2) / 6, // 4 sides and 2 diagonals
+		Centroid: math.Sqrt2,
+		Relative: 0.25, // √2 over the diagonal 4√2
+		Std:      []float64{1, 1},
+		Spread:   1, // Median 1 in both dimensions
+	}
+	if !sameDiversity(d, want) {
+		t.Errorf("square: %+v, want %+v", d, want)
+	}
+
+	if d := measureDiversity(square, []float64{0}, []float64{4}, false); d.Pairwise != 0 || d.Centroid != math.Sqrt2 {
+		t.Errorf("without the pairwise distances: %+v", d)
+	}
+
+	same := [][]float64{{3, -1}, {3, -1}, {3, -1}}
+	if d := MeasureDiversity(same, []float64{-5}, []float64{5}); !sameDiversity(d, Diversity{Std: []float64{0, 0}}) {
+		t.Errorf("collapsed population: %+v, want nothing but zeros", d)
+	}
+	if d := MeasureDiversity(nil, []float64{0}, []float64{1}); !reflect.DeepEqual(d, Diversity{}) {
+		t.Errorf("no population: %+v", d)
+	}
+
+	// Relative is scale-free: the same population stretched with its bounds
+	stretched := [][]float64{{0, 0}, {20, 0}, {0, 20}, {20, 20}}
+	if d := MeasureDiversity(stretched, []float64{0}, []float64{40}); math.Abs(d.Relative-0.25) > 1e-12 || d.Centroid != 10*math.Sqrt2 {
+		t.Errorf("stretched square: relative %g, centroid %g", d.Relative, d.Centroid)
+	}
+}
+
+func sameDiversity(a, b Diversity) bool {
+	near := func(x, y float64) bool { return math.Abs(x-y) < 1e-12 }
+	if len(a.Std) != len(b.Std) {
+		return false
+	}
+	for j := range a.Std {
+		if !near(a.Std[j], b.Std[j]) {
+			return false
+		}
+	}
+	return a.Iteration == b.Iteration && near(a.Pairwise, b.Pairwise) && near(a.Centroid, b.Centroid) &&
+		near(a.Relative, b.Relative) && near(a.Spread, b.Spread) &&
+		near(a.Exploration, b.Exploration) && near(a.Exploitation, b.Exploitation)
+}
+
+// Exploration is the spread relative to the largest one so far
+func TestDiversityBalance(t *testing.T) {
+	maxSpread := 0.0
+	steps := []struct {
+		spread                    float64
+		exploration, exploitation float64
+		maxSpread                 float64
+	}{
+		{0, 0, 0, 0}, // Nothing to compare with yet
+		{4, 100, 0, 4},
+		{1, 25, 75, 4},
+		{8, 100, 0, 8},
+		{2, 25, 75, 8},
+	}
+	for _, s := range steps {
+		d := Diversity{Spread: s.spread}
+		d.Balance(&maxSpread)
+		if d.Exploration != s.exploration || d.Exploitation != s.exploitation || maxSpread != s.maxSpread {
+			t.Errorf("spread %g: exploration %g, exploitation %g, largest spread %g; want %g, %g, %g",
+				s.spread, d.Exploration, d.Exploitation, maxSpread, s.exploration, s.exploitation, s.maxSpread)
+		}
+	}
+}
+
+func TestCollapsed(t *testing.T) {
+	tests := []struct {
+		minDiversity, relative float64
+		collapsed              bool
+	}{
+		{0, 0, false}, // Never without a threshold
+		{0.01, 0.02, false},
+		{0.01, 0.01, false},
+		{0.01, 0.005, true},
+	}
+	for _, tt := range tests {
+		p := DefaultParams()
+		p.MinDiversity = tt.minDiversity
+		if got := p.Collapsed(Diversity{Relative: tt.relative}); got != tt.collapsed {
+			t.Errorf("min diversity %g, relative %g: collapsed %v, want %v", tt.minDiversity, tt.relative, got, tt.collapsed)
+		}
+	}
+}
+
+// A run whose crayfish gather below min_diversity stops there, the rest of its convergence the last
+// best fitness
+func TestMinDiversityEndsRun(t *testing.T) {
+	specs, err := benchmarks.Get("F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(minDiversity float64) *Optimizer {
+		X := SeededPopulation(9, 10, 3, specs.LB, specs.UB)
+		o := NewSeededOptimizer(500, specs.LB, specs.UB, X, specs.Function, 9)
+		o.Params.MinDiversity = minDiversity
+		o.RecordDiversity = true
+		o.Run(o.T)
+		return o
+	}
+
+	o := run(0.01)
+	if !o.Collapsed || !o.Done() || o.Iteration >= o.T {
+		t.Fatalf("collapsed %v after %d iterations of %d, want an early stop", o.Collapsed, o.Iteration, o.T)
+	}
+	last := o.Diversity[len(o.Diversity)-1]
+	if len(o.Diversity) != o.Iteration || last.Relative >= 0.01 {
+		t.Errorf("%d diversities for %d iterations, the last relative %g", len(o.Diversity), o.Iteration, last.Relative)
+	}
+	for _, d := range o.Diversity[:len(o.Diversity)-1] {
+		if d.Relative < 0.01 {
+			t.Errorf("iteration %d already below the threshold (%g)", d.Iteration, d.Relative)
+		}
+		if d.Exploration < 0 || d.Exploration > 100 || math.Abs(d.Exploration+d.Exploitation-100) > 1e-9 {
+			t.Errorf("iteration %d: exploration %g, exploitation %g", d.Iteration, d.Exploration, d.Exploitation)
+		}
+	}
+	for k := o.Iteration; k < o.T; k++ {
+		if o.GlobalCov[k] != o.GlobalFitness {
+			t.Fatalf("convergence %g at iteration %d after the collapse, want %g", o.GlobalCov[k], k, o.GlobalFitness)
+		}
+	}
+	o.Run(10)
+	if o.Iteration != len(o.Diversity) {
+		t.Error("a collapsed run kept running")
+	}
+
+	if o := run(0); o.Collapsed || o.Iteration != o.T {
+		t.Errorf("without min_diversity: collapsed %v after %d iterations", o.Collapsed, o.Iteration)
+	}
+}
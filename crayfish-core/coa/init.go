@@ -0,0 +1,249 @@
+package coa
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"crayfish/benchmarks"
+)
+
+// Initializer draws the N x dim initial population within the bounds (one value of lb and ub
+// applies to every dimension). All of them are deterministic given their seed, so a worker can
+// regenerate its slice of the population the coordinator drew.
+type Initializer interface {
+	Initialize(N, dim int, lb, ub []float64) ([][]float64, error)
+}
+
+// Names of the initializers, see NewInitializer
+const (
+	InitUniform    = "uniform"    // SeededPopulation, the default
+	InitLHS        = "lhs"        // Latin hypercube
+	InitHalton     = "halton"     // Halton sequence, randomly shifted
+	InitSobol      = "sobol"      // Sobol sequence, digitally shifted
+	InitOpposition = "opposition" // Best half of uniform crayfish and their opposites
+	InitLogistic   = "logistic"   // Logistic map
+	InitTent       = "tent"       // Tent map
+)
+
+// InitializerNames lists the names NewInitializer understands
+func InitializerNames() []string {
+	return []string{InitUniform, InitLHS, InitHalton, InitSobol, InitOpposition, InitLogistic, InitTent}
+}
+
+// NewInitializer returns the initializer of the given name (uniform when empty) drawing from seed.
+// F is the benchmark, which opposition-based initialization evaluates. With warm-start points, they
+// are the first crayfish and the initializer draws the others.
+func NewInitializer(name string, seed int64, F benchmarks.FunctionType, warm [][]float64) (Initializer, error) {
+	var i Initializer
+	switch name {
+	case "", InitUniform:
+		i = Uniform{Seed: seed}
+	case InitLHS:
+		i = LatinHypercube{Seed: seed}
+	case InitHalton:
+		i = Halton{Seed: seed}
+	case InitSobol:
+		i = Sobol{Seed: seed}
+	case InitOpposition:
+		i = Opposition{Base: Uniform{Seed: seed}, F: F}
+	case InitLogistic, InitTent:
+		i = Chaotic{Map: name, Seed: seed}
+	default:
+		return nil, fmt.Errorf("coa: unknown initializer %q (known: %v)", name, InitializerNames())
+	}
+	if len(warm) > 0 {
+		i = WarmStart{Points: warm, Base: i}
+	}
+	return i, nil
+}
+
+// Uniform draws every value uniformly, it is SeededPopulation
+type Uniform struct{ Seed int64 }
+
+func (u Uniform) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	return SeededPopulation(u.Seed, N, dim, lb, ub), nil
+}
+
+// LatinHypercube splits every dimension into N strata and puts exactly one crayfish in each
+type LatinHypercube struct{ Seed int64 }
+
+func (h LatinHypercube) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	rng := rand.New(NewSource(h.Seed))
+	X := population(N, dim)
+	for j := 0; j < dim; j++ {
+		l, u := bound(lb, ub, j)
+		for i, stratum := range rng.Perm(N) {
+			X[i][j] = l + (float64(stratum)+rng.Float64())/float64(N)*(u-l)
+		}
+	}
+	return X, nil
+}
+
+// Halton takes the points 1 to N of the Halton sequence (the radical inverses in the first dim
+// primes), each dimension shifted by a random amount modulo 1 (Cranley-Patterson rotation)
+type Halton struct{ Seed int64 }
+
+func (h Halton) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	rng := rand.New(NewSource(h.Seed))
+	X := population(N, dim)
+	for j, base := range primes(dim) {
+		shift := rng.Float64()
+		l, u := bound(lb, ub, j)
+		for i := range X {
+			_, v := math.Modf(radicalInverse(uint64(i+1), base) + shift)
+			X[i][j] = l + v*(u-l)
+		}
+	}
+	return X, nil
+}
+
+// The digits of n in base b mirrored around the point
+func radicalInverse(n, b uint64) float64 {
+	var v float64
+	f := 1 / float64(b)
+	for scale := f; n > 0; n /= b {
+		v += float64(n%b) * scale
+		scale *= f
+	}
+	return v
+}
+
+// The first n primes
+func primes(n int) []uint64 {
+	p := make([]uint64, 0, n)
+	for c := uint64(2); len(p) < n; c++ {
+		prime := true
+		for _, q := range p {
+			if q*q > c {
+				break
+			}
+			if c%q == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			p = append(p, c)
+		}
+	}
+	return p
+}
+
+// Chaotic iterates the logistic map x = 4x(1-x) or the tent map (x/0.7 below 0.7, (1-x)/0.3
+// above) from a random start, after 100 iterations to leave the transient, filling the population
+// row by row
+type Chaotic struct {
+	Map  string // InitLogistic or InitTent
+	Seed int64
+}
+
+func (c Chaotic) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	step := func(x float64) float64 { return 4 * x * (1 - x) }
+	switch c.Map {
+	case InitLogistic:
+	case InitTent:
+		step = func(x float64) float64 {
+			if x < 0.7 {
+				return x / 0.7
+			}
+			return (1 - x) / 0.3
+		}
+	default:
+		return nil, fmt.Errorf("coa: unknown chaotic map %q", c.Map)
+	}
+
+	rng := rand.New(NewSource(c.Seed))
+	x := 0.01 + 0.98*rng.Float64() // Away from the fixed points 0 and 1
+	for k := 0; k < 100; k++ {
+		x = step(x)
+	}
+	X := population(N, dim)
+	for i := range X {
+		for j := range X[i] {
+			x = step(x)
+			if x <= 0 || x >= 1 { // Fell on a fixed point in floating point, start elsewhere
+				x = 0.01 + 0.98*rng.Float64()
+			}
+			l, u := bound(lb, ub, j)
+			X[i][j] = l + x*(u-l)
+		}
+	}
+	return X, nil
+}
+
+// Opposition draws N crayfish with Base and keeps the best N of them and their opposites
+// lb + ub - x (opposition-based learning)
+type Opposition struct {
+	Base Initializer
+	F    benchmarks.FunctionType
+}
+
+func (o Opposition) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	if o.F == nil {
+		return nil, fmt.Errorf("coa: opposition-based initialization needs the benchmark")
+	}
+	X, err := o.Base.Initialize(N, dim, lb, ub)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([][]float64, 0, 2*N)
+	for _, x := range X {
+		opposite := make([]float64, dim)
+		for j, v := range x {
+			l, u := bound(lb, ub, j)
+			opposite[j] = l + u - v
+		}
+		candidates = append(candidates, x, opposite)
+	}
+	fitness := make([]float64, len(candidates))
+	for i, x := range candidates {
+		fitness[i] = o.F(x)
+	}
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fitness[order[a]] < fitness[order[b]] })
+	for i := range X {
+		X[i] = candidates[order[i]]
+	}
+	return X, nil
+}
+
+// WarmStart starts from the given points (clamped into the bounds), N at most, and has Base draw the
+// rest of the population
+type WarmStart struct {
+	Points [][]float64
+	Base   Initializer
+}
+
+func (w WarmStart) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	if len(w.Points) > N {
+		return nil, fmt.Errorf("coa: %d warm-start points for a population of %d", len(w.Points), N)
+	}
+	X, err := w.Base.Initialize(N, dim, lb, ub)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range w.Points {
+		if len(p) != dim {
+			return nil, fmt.Errorf("coa: warm-start point %d has dimension %d, expected %d", i, len(p), dim)
+		}
+		for j, v := range p {
+			l, u := bound(lb, ub, j)
+			X[i][j] = math.Max(l, math.Min(u, v))
+		}
+	}
+	return X, nil
+}
+
+// An N x dim matrix
+func population(N, dim int) [][]float64 {
+	X := make([][]float64, N)
+	for i := range X {
+		X[i] = make([]float64, dim)
+	}
+	return X
+}
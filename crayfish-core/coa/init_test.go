@@ -0,0 +1,230 @@
+package coa
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+
+	"crayfish/benchmarks"
+)
+
+func sphere(x []float64) float64 {
+	var s float64
+	for _, v := range x {
+		s += v * v
+	}
+	return s
+}
+
+func TestInitializers(t *testing.T) {
+	bounds := []struct {
+		name   string
+		lb, ub []float64
+	}{
+		{"shared", []float64{-100}, []float64{100}},
+		{"per dimension", []float64{-5, 0, 10, -1}, []float64{5, 1, 20, 1}},
+	}
+	for _, name := range InitializerNames() {
+		for _, b := range bounds {
+			init, err := NewInitializer(name, 21, sphere, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			X, err := init.Initialize(20, 4, b.lb, b.ub)
+			if err != nil {
+				t.Fatalf("%s, %s bounds: %v", name, b.name, err)
+			}
+			if len(X) != 20 {
+				t.Fatalf("%s, %s bounds: %d crayfish, want 20", name, b.name, len(X))
+			}
+			for i, x := range X {
+				if len(x) != 4 {
+					t.Fatalf("%s, %s bounds: crayfish %d has dimension %d", name, b.name, i, len(x))
+				}
+				for j, v := range x {
+					if l, u := bound(b.lb, b.ub, j); v < l || v > u || math.IsNaN(v) {
+						t.Errorf("%s, %s bounds: crayfish %d has %g in dimension %d, outside [%g, %g]", name, b.name, i, v, j, l, u)
+					}
+				}
+			}
+
+			same, _ := NewInitializer(name, 21, sphere, nil)
+			other, _ := NewInitializer(name, 22, sphere, nil)
+			again, _ := same.Initialize(20, 4, b.lb, b.ub)
+			elsewhere, _ := other.Initialize(20, 4, b.lb, b.ub)
+			if !reflect.DeepEqual(X, again) {
+				t.Errorf("%s, %s bounds: seed 21 drew two populations", name, b.name)
+			}
+			if reflect.DeepEqual(X, elsewhere) {
+				t.Errorf("%s, %s bounds: seeds 21 and 22 drew the same population", name, b.name)
+			}
+		}
+	}
+
+	if _, err := NewInitializer("grid", 1, sphere, nil); err == nil {
+		t.Error("NewInitializer accepted an unknown initializer")
+	}
+	if _, err := (Chaotic{Map: "henon"}).Initialize(2, 2, []float64{0}, []float64{1}); err == nil {
+		t.Error("Chaotic accepted an unknown map")
+	}
+	if _, err := (Opposition{Base: Uniform{Seed: 1}}).Initialize(2, 2, []float64{0}, []float64{1}); err == nil {
+		t.Error("Opposition ran without a benchmark")
+	}
+}
+
+// The first points of the Sobol sequence in dimensions 1 to 3 (Joe and Kuo), once the shift is undone
+func TestSobol(t *testing.T) {
+	want := [][]float64{
+		{0.5, 0.5, 0.5},
+		{0.75, 0.25, 0.25},
+		{0.25, 0.75, 0.75},
+		{0.375, 0.375, 0.625},
+		{0.875, 0.875, 0.125},
+		{0.625, 0.125, 0.875},
+		{0.125, 0.625, 0.375},
+	}
+	const seed = 5
+	X, err := Sobol{Seed: seed}.Initialize(len(want), 3, []float64{0}, []float64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(NewSource(seed))
+	for j := 0; j < 3; j++ {
+		shift := rng.Uint32()
+		for i := range X {
+			if got := float64(uint32(X[i][j]*(1<<32))^shift) / (1 << 32); got != want[i][j] {
+				t.Errorf("point %d, dimension %d: %g, want %g", i+1, j+1, got, want[i][j])
+			}
+		}
+	}
+
+	// Past the tabulated dimensions the directions are drawn, still within the bounds and the same
+	// every time
+	wide, err := Sobol{Seed: seed}.Initialize(8, 40, []float64{-1}, []float64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Sobol{Seed: seed}.Initialize(8, 40, []float64{-1}, []float64{1})
+	if !reflect.DeepEqual(wide, again) {
+		t.Error("40 dimensions: the same seed drew two populations")
+	}
+}
+
+func TestHaltonDigits(t *testing.T) {
+	tests := []struct {
+		n, b uint64
+		want float64
+	}{
+		{1, 2, 0.5},
+		{2, 2, 0.25},
+		{3, 2, 0.75},
+		{6, 2, 0.375},
+		{1, 3, 1.0 / 3},
+		{5, 3, 7.0 / 9}, // 12 in base 3
+	}
+	for _, tt := range tests {
+		if got := radicalInverse(tt.n, tt.b); math.Abs(got-tt.want) > 1e-15 {
+			t.Errorf("radicalInverse(%d, %d) = %g, want %g", tt.n, tt.b, got, tt.want)
+		}
+	}
+	if got, want := primes(6), []uint64{2, 3, 5, 7, 11, 13}; !reflect.DeepEqual(got, want) {
+		t.Errorf("primes(6) = %v, want %v", got, want)
+	}
+}
+
+// Exactly one crayfish in every one of the N strata of every dimension
+func TestLatinHypercubeStrata(t *testing.T) {
+	const N = 25
+	lb, ub := []float64{-5, 0, 100}, []float64{5, 1, 300}
+	X, err := LatinHypercube{Seed: 3}.Initialize(N, 3, lb, ub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := range lb {
+		strata := make([]int, N)
+		for i := range X {
+			strata[i] = int((X[i][j] - lb[j]) / (ub[j] - lb[j]) * N)
+		}
+		slices.Sort(strata)
+		for k, s := range strata {
+			if s != k {
+				t.Errorf("dimension %d: strata %v, want each of 0 to %d once", j, strata, N-1)
+				break
+			}
+		}
+	}
+}
+
+// The best half of the uniform crayfish and their opposites
+func TestOppositionInitializer(t *testing.T) {
+	lb, ub := []float64{-10, 0}, []float64{10, 4}
+	base, _ := Uniform{Seed: 8}.Initialize(10, 2, lb, ub)
+	X, err := Opposition{Base: Uniform{Seed: 8}, F: sphere}.Initialize(10, 2, lb, ub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var candidates [][]float64
+	for _, x := range base {
+		candidates = append(candidates, x, []float64{lb[0] + ub[0] - x[0], lb[1] + ub[1] - x[1]})
+	}
+	slices.SortStableFunc(candidates, func(a, b []float64) int {
+		switch fa, fb := sphere(a), sphere(b); {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	})
+	if !reflect.DeepEqual(X, candidates[:10]) {
+		t.Errorf("population %v, want the best half of %v", X, candidates)
+	}
+}
+
+func TestWarmStart(t *testing.T) {
+	lb, ub := []float64{-1}, []float64{1}
+	points := [][]float64{{0.5, -0.5}, {3, -7}} // The second is clamped
+	init, err := NewInitializer(InitLHS, 4, sphere, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	X, err := init.Initialize(6, 2, lb, ub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drawn, _ := LatinHypercube{Seed: 4}.Initialize(6, 2, lb, ub)
+	if want := append([][]float64{{0.5, -0.5}, {1, -1}}, drawn[2:]...); !reflect.DeepEqual(X, want) {
+		t.Errorf("population %v, want %v", X, want)
+	}
+	if points[1][0] != 3 {
+		t.Error("the warm start clamped the caller's points")
+	}
+
+	if _, err := init.Initialize(1, 2, lb, ub); err == nil {
+		t.Error("2 warm-start points for a population of 1 were accepted")
+	}
+	if _, err := (WarmStart{Points: [][]float64{{0}}, Base: Uniform{}}).Initialize(3, 2, lb, ub); err == nil {
+		t.Error("a warm-start point of the wrong dimension was accepted")
+	}
+}
+
+// Every initializer of a benchmark of the registry, as the coordinator calls them
+func TestInitializersOnBenchmark(t *testing.T) {
+	specs, err := benchmarks.Get("F9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range InitializerNames() {
+		init, err := NewInitializer(name, 1, specs.Function, [][]float64{make([]float64, 10)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		X, err := init.Initialize(30, 10, specs.LB, specs.UB)
+		if err != nil || len(X) != 30 || specs.Function(X[0]) != 0 {
+			t.Errorf("%s: %d crayfish, F9 of the first %g (%v); want 30 starting at the optimum", name, len(X), specs.Function(X[0]), err)
+		}
+	}
+}
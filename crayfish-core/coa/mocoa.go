@@ -0,0 +1,194 @@
+package coa
+
+import (
+	"math"
+	"math/rand"
+
+	"crayfish/benchmarks"
+)
+
+// Mutation rate of MOPSO: a move is mutated with probability (1 - t/T)^(1/mutationRate)
+const mutationRate = 0.5
+
+// MultiOptimizer is COA for several objectives (MOCOA): the external Pareto archive takes the place
+// of the best position, every crayfish follows leaders picked from it (the food is one, the cave
+// halfway between two), and a move is kept when it dominates the crayfish, or half the time when
+// neither dominates the other. Every position evaluated is offered to the archive. The mutation of
+// MOPSO (Coello et al.) redraws a dimension of the moves, less and less often and less and less far:
+// without it, a front with an end at the origin draws COA's moves there until every crayfish sits on it.
+type MultiOptimizer struct {
+	F      benchmarks.MultiFunctionType
+	LB, UB []float64
+	T      int // Total iterations, drives the decreasing curve C
+
+	X          [][]float64 // The crayfish
+	Objectives [][]float64 // Their objectives
+
+	Archive   *Archive  // The non-dominated positions found so far
+	GlobalCov []float64 // Smallest sum of the objectives in the archive after every iteration
+	Iteration int       // Next iteration to run
+
+	Params   Params   // DefaultParams unless changed before running
+	Counters Counters // Work done by this optimizer
+
+	RecordStages bool    // Record every iteration in Stages
+	Stages       []Stage // The iterations run while RecordStages was set
+
+	rng       *rand.Rand
+	xf, xfood []float64
+	xnew      []float64
+}
+
+// NewMultiOptimizer evaluates the population X (updated in place while running) for a run of T
+// iterations, the archive starting with its non-dominated crayfish
+func NewMultiOptimizer(T int, lb, ub []float64, X [][]float64, F benchmarks.MultiFunctionType, archive Pareto, rng *rand.Rand) *MultiOptimizer {
+	dim := len(X[0])
+	o := &MultiOptimizer{
+		F: F, LB: lb, UB: ub, T: T,
+		X:          X,
+		Objectives: make([][]float64, len(X)),
+		Archive:    NewArchive(archive),
+		GlobalCov:  make([]float64, T),
+		Params:     DefaultParams(),
+		rng:        rng,
+		xf:         make([]float64, dim),
+		xfood:      make([]float64, dim),
+		xnew:       make([]float64, dim),
+	}
+	for i, x := range X {
+		o.Objectives[i] = F(x)
+		o.Counters.Evaluations++
+		o.Archive.Add(x, o.Objectives[i])
+	}
+	return o
+}
+
+// Done reports whether all T iterations ran
+func (o *MultiOptimizer) Done() bool {
+	return o.Iteration >= o.T
+}
+
+// Share hands the optimizer a front found elsewhere (e.g. the merged archive of the other islands)
+func (o *MultiOptimizer) Share(f *Front) {
+	o.Archive.Merge(f)
+}
+
+// Run advances the optimizer by n iterations (fewer when T is reached)
+func (o *MultiOptimizer) Run(n int) {
+	for ; n > 0 && !o.Done(); n-- {
+		o.Step()
+	}
+}
+
+// BestPosition is the compromise of the archive (the member of the smallest sum of objectives) and
+// that sum
+func (o *MultiOptimizer) BestPosition() ([]float64, float64) {
+	i := o.Archive.Compromise()
+	return o.Archive.Positions[i], sum(o.Archive.Objectives[i])
+}
+
+// Step runs one iteration of MOCOA
+func (o *MultiOptimizer) Step() {
+	var (
+		X, Xnew    = o.X, o.xnew
+		Xf, Xfood  = o.xf, o.xfood
+		rng        = o.rng
+		N, dim     = len(o.X), len(o.xnew)
+		t, T       = o.Iteration, o.T
+		p          = o.Params
+		archive    = o.Archive
+		lo, hi     = archive.Range()
+		normalized = func(f []float64) float64 { // Food size needs one number: 1 + the objectives scaled to the archive
+			s := 1.0
+			for m, v := range f {
+				if hi[m] > lo[m] {
+					s += math.Max(0, (v-lo[m])/(hi[m]-lo[m]))
+				}
+			}
+			return s
+		}
+	)
+
+	C := p.C2 - (float64(t) / float64(T)) // Equation 7
+	tmp := rng.Float64()*(p.TempMax-p.TempMin) + p.TempMin
+	stage := Stage{Iteration: t, Temperature: tmp, C: C}
+
+	for i := 0; i < N; i++ {
+		food, other := archive.Leader(rng), archive.Leader(rng)
+		for j := 0; j < dim; j++ { // The cave between two leaders, the food at one of them
+			Xf[j] = (archive.Positions[food][j] + archive.Positions[other][j]) / 2
+		}
+		copy(Xfood, archive.Positions[food])
+
+		if tmp > p.TempHigh { // Summer resort stage
+			if rng.Float64() < p.Summer {
+				o.Counters.Summer++
+				stage.Summer++
+				for j := 0; j < dim; j++ { // Equation 6
+					Xnew[j] = X[i][j] + C*rng.Float64()*(Xf[j]-X[i][j])
+				}
+			} else { // Competition Stage
+				o.Counters.Competition++
+				stage.Competition++
+				for j := 0; j < dim; j++ {
+					z := rng.Intn(N)
+					Xnew[j] = X[i][j] - X[z][j] + Xf[j] // Equation 8
+				}
+			}
+		} else { // Foraging stage
+			o.Counters.Foraging++
+			P := p.C3 * rng.Float64() * normalized(o.Objectives[i]) / normalized(archive.Objectives[food])
+			stage.FoodMean += P
+			stage.FoodMax = math.Max(stage.FoodMax, P)
+			if P > p.FoodSize {
+				stage.Shredding++
+				for j := 0; j < dim; j++ { // Equation 13
+					Xfood[j] *= math.Exp(-1 / P)
+					Xnew[j] = X[i][j] + math.Cos(2*math.Pi*rng.Float64())*Xfood[j]*p.intake(tmp) - math.Sin(2*math.Pi*rng.Float64())*Xfood[j]*p.intake(tmp)
+				}
+			} else {
+				stage.Eating++
+				for j := 0; j < dim; j++ {
+					Xnew[j] = (X[i][j]-Xfood[j])*p.intake(tmp) + p.intake(tmp)*rng.Float64()*X[i][j]
+				}
+			}
+		}
+
+		for j := 0; j < dim; j++ { // Boundary conditions checks
+			l, u := bound(o.LB, o.UB, j)
+			Xnew[j] = math.Max(l, math.Min(u, Xnew[j]))
+		}
+		if pm := math.Pow(1-float64(t)/float64(T), 1/mutationRate); rng.Float64() < pm {
+			j := rng.Intn(dim) // Anywhere within pm of the bounds' range around it
+			l, u := bound(o.LB, o.UB, j)
+			from, to := math.Max(l, Xnew[j]-pm*(u-l)), math.Min(u, Xnew[j]+pm*(u-l))
+			Xnew[j] = from + rng.Float64()*(to-from)
+		}
+		if o.RecordStages {
+			step := stepLength(X[i], Xnew)
+			stage.StepMean += step
+			stage.StepMax = math.Max(stage.StepMax, step)
+		}
+
+		f := o.F(Xnew)
+		o.Counters.Evaluations++
+		archive.Add(Xnew, f)
+		if Dominates(f, o.Objectives[i]) || (!Dominates(o.Objectives[i], f) && rng.Float64() < 0.5) {
+			stage.Accepted++
+			o.Objectives[i] = f
+			copy(X[i], Xnew)
+		}
+	}
+
+	o.Counters.Iterations++
+	_, o.GlobalCov[t] = o.BestPosition()
+	o.Iteration++
+
+	if o.RecordStages {
+		if foraging := stage.Shredding + stage.Eating; foraging > 0 {
+			stage.FoodMean /= float64(foraging)
+		}
+		stage.StepMean /= float64(N)
+		o.Stages = append(o.Stages, stage)
+	}
+}
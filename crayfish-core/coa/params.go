@@ -0,0 +1,120 @@
+package coa
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Params are the constants of the paper's equations, tunable per run. DefaultParams are the paper's
+// values; a run with them draws exactly the numbers the original code did.
+type Params struct {
+	TempMin  float64 `json:"temp_min" msgpack:"temp_min"` // Temperature drawn in [TempMin, TempMax] (Equation 3)
+	TempMax  float64 `json:"temp_max" msgpack:"temp_max"`
+	TempHigh float64 `json:"temp_high" msgpack:"temp_high"` // Above it the crayfish summer or compete, below it they forage
+	Mu       float64 `json:"mu" msgpack:"mu"`               // Temperature most suitable for the intake (Equation 4)
+	Sigma    float64 `json:"sigma" msgpack:"sigma"`         // Spread of the intake around Mu
+	C1       float64 `json:"c1" msgpack:"c1"`               // Scale of the intake
+	C2       float64 `json:"c2" msgpack:"c2"`               // Start of the decreasing curve C (Equation 7)
+	C3       float64 `json:"c3" msgpack:"c3"`               // Scale of the food size P (Equation 11)
+	FoodSize float64 `json:"food_size" msgpack:"food_size"` // P above it: the food is too big and torn first (Equation 13)
+	Summer   float64 `json:"summer" msgpack:"summer"`       // Chance of going to the cave rather than competing
+
+	// Not in the paper: stop before T once Diversity.Relative falls below it (0: never)
+	MinDiversity float64 `json:"min_diversity" msgpack:"min_diversity"`
+}
+
+// DefaultParams are the values of the paper
+func DefaultParams() Params {
+	return Params{
+		TempMin:  20,
+		TempMax:  35,
+		TempHigh: 30,
+		Mu:       25,
+		Sigma:    3,
+		C1:       0.2,
+		C2:       2,
+		C3:       3,
+		FoodSize: 2,
+		Summer:   0.5,
+	}
+}
+
+func (p *Params) fields() map[string]*float64 {
+	return map[string]*float64{
+		"temp_min":  &p.TempMin,
+		"temp_max":  &p.TempMax,
+		"temp_high": &p.TempHigh,
+		"mu":        &p.Mu,
+		"sigma":     &p.Sigma,
+		"c1":        &p.C1,
+		"c2":        &p.C2,
+		"c3":        &p.C3,
+		"food_size": &p.FoodSize,
+		"summer":    &p.Summer,
+
+		"min_diversity": &p.MinDiversity,
+	}
+}
+
+// ParamNames lists the names Set understands
+func ParamNames() []string {
+	var p Params
+	names := make([]string, 0, 11)
+	for name := range p.fields() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set changes the parameter of the given name (see ParamNames)
+func (p *Params) Set(name string, value float64) error {
+	f, ok := p.fields()[name]
+	if !ok {
+		return fmt.Errorf("coa: unknown parameter %q (known: %v)", name, ParamNames())
+	}
+	*f = value
+	return nil
+}
+
+// Get returns the parameter of the given name, 0 when there is none
+func (p Params) Get(name string) float64 {
+	if f, ok := p.fields()[name]; ok {
+		return *f
+	}
+	return 0
+}
+
+// Validate rejects parameters the equations can't work with
+func (p Params) Validate() error {
+	switch {
+	case p.TempMax < p.TempMin:
+		return fmt.Errorf("coa: temp_max %g is below temp_min %g", p.TempMax, p.TempMin)
+	case p.Sigma <= 0:
+		return fmt.Errorf("coa: sigma must be positive, got %g", p.Sigma)
+	case p.FoodSize <= 0:
+		return fmt.Errorf("coa: food_size must be positive, got %g", p.FoodSize)
+	case p.Summer < 0 || p.Summer > 1:
+		return fmt.Errorf("coa: summer is a probability, got %g", p.Summer)
+	case p.MinDiversity < 0 || p.MinDiversity >= 1:
+		return fmt.Errorf("coa: min_diversity is a share of the diagonal of the bounds, in [0, 1), got %g", p.MinDiversity)
+	}
+	return nil
+}
+
+// ParseParams applies the named values over DefaultParams
+func ParseParams(values map[string]float64) (Params, error) {
+	p := DefaultParams()
+	for name, value := range values {
+		if err := p.Set(name, value); err != nil {
+			return p, err
+		}
+	}
+	return p, p.Validate()
+}
+
+// Equation 4: Mathimatical model of crayfish intake
+func (p Params) intake(temp float64) float64 {
+	return p.C1 * (1 / (math.Sqrt(2*math.Pi) * p.Sigma)) * math.Exp(-math.Pow(temp-p.Mu, 2)/(2*math.Pow(p.Sigma, 2)))
+}
@@ -0,0 +1,381 @@
+package coa
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"crayfish/benchmarks"
+)
+
+// Pruning of a full Pareto archive: drop the member of the smallest crowding distance (as NSGA-II),
+// or a member of the most crowded cell of a grid over the objectives (as PAES and MOPSO)
+const (
+	PruneCrowding = "crowding"
+	PruneGrid     = "grid"
+)
+
+// Pareto settings of the external archive of a multi-objective run
+type Pareto struct {
+	Size    int    // Most members, 100 when 0
+	Pruning string // PruneCrowding when empty, or PruneGrid
+	Grid    int    // PruneGrid: divisions of every objective, 10 when 0
+}
+
+// Validate rejects unknown prunings and sizes that can't work
+func (p Pareto) Validate() error {
+	switch p.Pruning {
+	case "", PruneCrowding, PruneGrid:
+	default:
+		return fmt.Errorf("coa: unknown archive pruning %q (known: %s, %s)", p.Pruning, PruneCrowding, PruneGrid)
+	}
+	if p.Size < 0 || p.Grid < 0 {
+		return fmt.Errorf("coa: archive size and grid can't be negative, got %d and %d", p.Size, p.Grid)
+	}
+	return nil
+}
+
+func (p Pareto) size() int {
+	if p.Size <= 0 {
+		return 100
+	}
+	return p.Size
+}
+
+func (p Pareto) divisions() int {
+	if p.Grid <= 0 {
+		return 10
+	}
+	return p.Grid
+}
+
+// Dominates tells whether the objectives a Pareto-dominate b: no worse in any, better in one
+func Dominates(a, b []float64) bool {
+	better := false
+	for m := range a {
+		if a[m] > b[m] {
+			return false
+		}
+		if a[m] < b[m] {
+			better = true
+		}
+	}
+	return better
+}
+
+// Archive is the external Pareto archive of a multi-objective run: the non-dominated positions
+// found so far and their objectives, at most Size of them
+type Archive struct {
+	Pareto
+	Positions  [][]float64
+	Objectives [][]float64
+
+	scores []float64 // What leaders are picked by, nil when the archive changed since
+}
+
+// NewArchive returns an empty archive
+func NewArchive(p Pareto) *Archive {
+	return &Archive{Pareto: p}
+}
+
+// Add offers position x of objectives f to the archive (both copied). It is kept unless a member
+// dominates or equals it, and removes the members it dominates. Returns whether it was kept.
+func (a *Archive) Add(x, f []float64) bool {
+	kept := 0
+	for i, g := range a.Objectives {
+		if Dominates(g, f) || equal(g, f) {
+			return false
+		}
+		if !Dominates(f, g) {
+			a.Positions[kept], a.Objectives[kept] = a.Positions[i], g
+			kept++
+		}
+	}
+	a.Positions = append(a.Positions[:kept], append([]float64(nil), x...))
+	a.Objectives = append(a.Objectives[:kept], append([]float64(nil), f...))
+	a.scores = nil
+
+	for len(a.Objectives) > a.size() {
+		a.remove(a.prune())
+	}
+	return true
+}
+
+func equal(a, b []float64) bool {
+	for m := range a {
+		if a[m] != b[m] {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Archive) remove(i int) {
+	a.Positions = append(a.Positions[:i], a.Positions[i+1:]...)
+	a.Objectives = append(a.Objectives[:i], a.Objectives[i+1:]...)
+	a.scores = nil
+}
+
+// The member to drop from a full archive: the most crowded one, within the densest cell for the grid
+func (a *Archive) prune() int {
+	crowding := Crowding(a.Objectives)
+	var candidates []int
+	if a.Pruning == PruneGrid {
+		cells := a.cells()
+		count := map[int]int{}
+		densest := cells[0]
+		for _, c := range cells {
+			count[c]++
+			if count[c] > count[densest] {
+				densest = c
+			}
+		}
+		for i, c := range cells {
+			if c == densest {
+				candidates = append(candidates, i)
+			}
+		}
+	} else {
+		for i := range a.Objectives {
+			candidates = append(candidates, i)
+		}
+	}
+	worst := candidates[0]
+	for _, i := range candidates {
+		if crowding[i] < crowding[worst] {
+			worst = i
+		}
+	}
+	return worst
+}
+
+// Crowding distance of every point among the others: the sum over the objectives of the gap
+// between its two neighbours, normalized by the range, infinite for the extremes
+func Crowding(objectives [][]float64) []float64 {
+	n := len(objectives)
+	d := make([]float64, n)
+	if n == 0 {
+		return d
+	}
+	order := make([]int, n)
+	for m := range objectives[0] {
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool { return objectives[order[i]][m] < objectives[order[j]][m] })
+		lo, hi := objectives[order[0]][m], objectives[order[n-1]][m]
+		d[order[0]], d[order[n-1]] = math.Inf(1), math.Inf(1)
+		if hi == lo {
+			continue
+		}
+		for k := 1; k < n-1; k++ {
+			d[order[k]] += (objectives[order[k+1]][m] - objectives[order[k-1]][m]) / (hi - lo)
+		}
+	}
+	return d
+}
+
+// The cell of the grid over the archive's objective ranges every member falls in
+func (a *Archive) cells() []int {
+	div := a.divisions()
+	lo, hi := a.Range()
+	cells := make([]int, len(a.Objectives))
+	for i, f := range a.Objectives {
+		for m := range f {
+			k := 0
+			if hi[m] > lo[m] {
+				k = min(int(float64(div)*(f[m]-lo[m])/(hi[m]-lo[m])), div-1)
+			}
+			cells[i] = cells[i]*div + k
+		}
+	}
+	return cells
+}
+
+// Range of every objective over the archive
+func (a *Archive) Range() (lo, hi []float64) {
+	if len(a.Objectives) == 0 {
+		return nil, nil
+	}
+	lo = append([]float64(nil), a.Objectives[0]...)
+	hi = append([]float64(nil), a.Objectives[0]...)
+	for _, f := range a.Objectives[1:] {
+		for m, v := range f {
+			lo[m], hi[m] = math.Min(lo[m], v), math.Max(hi[m], v)
+		}
+	}
+	return lo, hi
+}
+
+// Leader picks a member to lead a crayfish, favouring the sparse parts of the front: the less
+// crowded of two random members, or for the grid a roulette weighted by 1 / the members of the cell
+func (a *Archive) Leader(rng *rand.Rand) int {
+	n := len(a.Objectives)
+	if a.scores == nil {
+		if a.Pruning == PruneGrid {
+			cells := a.cells()
+			count := map[int]int{}
+			for _, c := range cells {
+				count[c]++
+			}
+			a.scores = make([]float64, n)
+			for i, c := range cells {
+				a.scores[i] = 1 / float64(count[c])
+			}
+		} else {
+			a.scores = Crowding(a.Objectives)
+		}
+	}
+
+	if a.Pruning != PruneGrid {
+		i, j := rng.Intn(n), rng.Intn(n)
+		if a.scores[j] > a.scores[i] {
+			return j
+		}
+		return i
+	}
+	var total float64
+	for _, s := range a.scores {
+		total += s
+	}
+	r := rng.Float64() * total
+	for i, s := range a.scores {
+		if r -= s; r < 0 {
+			return i
+		}
+	}
+	return n - 1
+}
+
+// Compromise is the member of the smallest sum of objectives, what a multi-objective run reports as
+// its best position; -1 when the archive is empty
+func (a *Archive) Compromise() int {
+	best, bestSum := -1, math.Inf(1)
+	for i, f := range a.Objectives {
+		if s := sum(f); s < bestSum {
+			best, bestSum = i, s
+		}
+	}
+	return best
+}
+
+func sum(f []float64) float64 {
+	var s float64
+	for _, v := range f {
+		s += v
+	}
+	return s
+}
+
+// Hypervolume of the region dominated by the points and bounded by the reference point, by slicing
+// along the last objective; points not dominating the reference don't count
+func Hypervolume(points [][]float64, reference []float64) float64 {
+	var in [][]float64
+	for _, p := range points {
+		if below(p, reference) {
+			in = append(in, p)
+		}
+	}
+	return hypervolume(in, reference)
+}
+
+// Whether a point is better than the reference in every objective
+func below(p, reference []float64) bool {
+	for m := range p {
+		if p[m] >= reference[m] {
+			return false
+		}
+	}
+	return true
+}
+
+func hypervolume(points [][]float64, reference []float64) float64 {
+	m := len(reference)
+	if len(points) == 0 {
+		return 0
+	}
+	if m == 1 {
+		lo := points[0][0]
+		for _, p := range points[1:] {
+			lo = math.Min(lo, p[0])
+		}
+		return reference[0] - lo
+	}
+
+	sorted := append([][]float64(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][m-1] < sorted[j][m-1] })
+	var volume float64
+	for i, p := range sorted {
+		next := reference[m-1]
+		if i+1 < len(sorted) {
+			next = sorted[i+1][m-1]
+		}
+		if next == p[m-1] {
+			continue
+		}
+		slice := make([][]float64, i+1) // The points below the slab, projected onto the other objectives
+		for k := range slice {
+			slice[k] = sorted[k][:m-1]
+		}
+		volume += hypervolume(slice, reference[:m-1]) * (next - p[m-1])
+	}
+	return volume
+}
+
+// IGD is the inverted generational distance of the points to a sample of the true front: the mean
+// distance of every point of the sample to the closest of the points
+func IGD(points, front [][]float64) float64 {
+	if len(front) == 0 || len(points) == 0 {
+		return math.Inf(1)
+	}
+	var total float64
+	for _, r := range front {
+		closest := math.Inf(1)
+		for _, p := range points {
+			var d float64
+			for m := range r {
+				d += (r[m] - p[m]) * (r[m] - p[m])
+			}
+			closest = math.Min(closest, d)
+		}
+		total += math.Sqrt(closest)
+	}
+	return total / float64(len(front))
+}
+
+// Front is the Pareto front a multi-objective run found, with its quality against the benchmark's
+// reference point and true front
+type Front struct {
+	Positions   [][]float64
+	Objectives  [][]float64
+	Hypervolume float64 // Larger is better
+	IGD         float64 // Smaller is better, 0 on the true front
+}
+
+// Front copies the archive out, measured against the benchmark
+func (a *Archive) Front(problem benchmarks.MultiObjective) *Front {
+	f := &Front{
+		Positions:  make([][]float64, len(a.Positions)),
+		Objectives: make([][]float64, len(a.Objectives)),
+	}
+	for i := range a.Positions {
+		f.Positions[i] = append([]float64(nil), a.Positions[i]...)
+		f.Objectives[i] = append([]float64(nil), a.Objectives[i]...)
+	}
+	f.Hypervolume = Hypervolume(f.Objectives, problem.Reference)
+	if problem.Front != nil {
+		f.IGD = IGD(f.Objectives, problem.Front())
+	}
+	return f
+}
+
+// Merge offers every member of a front to the archive
+func (a *Archive) Merge(f *Front) {
+	if f == nil {
+		return
+	}
+	for i := range f.Positions {
+		a.Add(f.Positions[i], f.Objectives[i])
+	}
+}
@@ -0,0 +1,171 @@
+package coa
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+
+	"crayfish/benchmarks"
+)
+
+func TestDominates(t *testing.T) {
+	tests := []struct {
+		a, b []float64
+		want bool
+	}{
+		{[]float64{1, 1}, []float64{2, 2}, true},
+		{[]float64{1, 2}, []float64{2, 2}, true}, // Better in one, no worse in the other
+		{[]float64{1, 3}, []float64{2, 2}, false},
+		{[]float64{2, 2}, []float64{2, 2}, false}, // Equal
+		{[]float64{3, 3}, []float64{2, 2}, false},
+		{[]float64{0, 0, 1}, []float64{0, 0, 2}, true},
+		{[]float64{0, 1, 0}, []float64{1, 0, 0}, false},
+	}
+	for _, tt := range tests {
+		if got := Dominates(tt.a, tt.b); got != tt.want {
+			t.Errorf("Dominates(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// The objectives of the archive's members, sorted by the first objective
+func members(a *Archive) [][]float64 {
+	objectives := append([][]float64(nil), a.Objectives...)
+	sort.Slice(objectives, func(i, j int) bool { return objectives[i][0] < objectives[j][0] })
+	return objectives
+}
+
+func TestArchiveAdd(t *testing.T) {
+	a := NewArchive(Pareto{})
+	steps := []struct {
+		f    []float64
+		kept bool
+	}{
+		{[]float64{2, 2}, true},
+		{[]float64{3, 3}, false}, // Dominated
+		{[]float64{2, 2}, false}, // Already there
+		{[]float64{1, 3}, true},  // Neither dominates
+		{[]float64{3, 1}, true},
+		{[]float64{1.5, 1.5}, true}, // Dominates (2, 2), which goes
+	}
+	for _, s := range steps {
+		if got := a.Add(s.f, s.f); got != s.kept {
+			t.Errorf("Add(%v) = %v, want %v", s.f, got, s.kept)
+		}
+	}
+	if want := [][]float64{{1, 3}, {1.5, 1.5}, {3, 1}}; !reflect.DeepEqual(members(a), want) {
+		t.Errorf("archive %v, want %v", members(a), want)
+	}
+	for i := range a.Positions { // Positions follow their objectives
+		if !reflect.DeepEqual(a.Positions[i], a.Objectives[i]) {
+			t.Errorf("member %d: position %v, objectives %v", i, a.Positions[i], a.Objectives[i])
+		}
+	}
+
+	// Copies are kept, the caller's slices may change
+	x := []float64{0, 4}
+	a.Add(x, x)
+	x[0] = 9
+	if members(a)[0][0] != 0 {
+		t.Error("the archive kept the caller's slice")
+	}
+}
+
+func TestArchivePruning(t *testing.T) {
+	front := [][]float64{{0, 10}, {1, 9}, {1.2, 8.8}, {5, 5}, {10, 0}} // (1, 9) and (1.2, 8.8) crowd each other
+
+	crowding := NewArchive(Pareto{Size: 4})
+	for _, f := range front {
+		crowding.Add(f, f)
+	}
+	got := members(crowding)
+	if len(got) != 4 || !reflect.DeepEqual(got[0], front[0]) || !reflect.DeepEqual(got[3], front[4]) {
+		t.Fatalf("crowding kept %v, want 4 members with both extremes", got)
+	}
+	if reflect.DeepEqual(got[1], front[1]) && reflect.DeepEqual(got[2], front[2]) {
+		t.Errorf("crowding kept %v, want one of the crowded pair dropped", got)
+	}
+
+	// A grid of 2 cells per objective: (0, 10), (1, 9) and (1.2, 8.8) share a cell, one of them goes
+	grid := NewArchive(Pareto{Size: 4, Pruning: PruneGrid, Grid: 2})
+	for _, f := range front {
+		grid.Add(f, f)
+	}
+	got = members(grid)
+	if len(got) != 4 || !reflect.DeepEqual(got[2], front[3]) || !reflect.DeepEqual(got[3], front[4]) {
+		t.Errorf("grid kept %v, want (5, 5) and (10, 0), alone in their cells", got)
+	}
+}
+
+func TestHypervolume(t *testing.T) {
+	tests := []struct {
+		name      string
+		points    [][]float64
+		reference []float64
+		want      float64
+	}{
+		{"one point", [][]float64{{1, 2}}, []float64{4, 4}, 3 * 2},
+		{"staircase", [][]float64{{1, 3}, {2, 2}, {3, 1}}, []float64{4, 4}, 3 + 2 + 1},
+		{"dominated point", [][]float64{{1, 1}, {2, 2}}, []float64{3, 3}, 4},
+		{"beyond the reference", [][]float64{{1, 1}, {5, 0}, {3, 0}}, []float64{3, 3}, 4}, // Not strictly below it
+		{"empty", nil, []float64{1, 1}, 0},
+		{"one box", [][]float64{{1, 1, 1}}, []float64{2, 3, 4}, 1 * 2 * 3},
+		{"two boxes", [][]float64{{1, 2, 2}, {2, 1, 1}}, []float64{3, 3, 3}, 2 + 4 - 1}, // Less their intersection
+		{"corner", [][]float64{{0, 0, 0}}, []float64{1, 1, 1}, 1},
+	}
+	for _, tt := range tests {
+		if got := Hypervolume(tt.points, tt.reference); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%s: hypervolume %g, want %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+// The samples of the true fronts come close to their hypervolume in closed form: the box up to the
+// reference less what lies under the front. The staircase of a sample misses a little of it, more so
+// for the 3-D grid of DTLZ2.
+func TestHypervolumeOfTrueFronts(t *testing.T) {
+	tests := []struct {
+		name string
+		want float64
+		gap  float64
+	}{
+		{"ZDT1", 1.21 - 1.0/3, 0.01},       // f2 = 1 - √f1
+		{"ZDT2", 1.21 - 2.0/3, 0.01},       // f2 = 1 - f1²
+		{"DTLZ2", 1.331 - math.Pi/6, 0.03}, // An eighth of the unit sphere
+	}
+	for _, tt := range tests {
+		problem, ok := benchmarks.GetMulti(tt.name)
+		if !ok {
+			t.Fatalf("%s is not a multi-objective benchmark", tt.name)
+		}
+		if got := Hypervolume(problem.Front(), problem.Reference); got > tt.want || got < tt.want-tt.gap {
+			t.Errorf("%s: hypervolume of the true front %g, want just under %g", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIGD(t *testing.T) {
+	front := [][]float64{{0, 1}, {1, 0}}
+	tests := []struct {
+		name   string
+		points [][]float64
+		want   float64
+	}{
+		{"on the front", [][]float64{{1, 0}, {0, 1}}, 0},
+		{"half of it", [][]float64{{0, 1}}, math.Sqrt2 / 2},
+		{"shifted", [][]float64{{0, 1.5}, {1.5, 0}}, 0.5},
+		{"none", nil, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		if got := IGD(tt.points, front); math.Abs(got-tt.want) > 1e-12 && got != tt.want {
+			t.Errorf("%s: IGD %g, want %g", tt.name, got, tt.want)
+		}
+	}
+
+	// The true front is at 0 of itself
+	problem, _ := benchmarks.GetMulti("ZDT1")
+	if got := IGD(problem.Front(), problem.Front()); got != 0 {
+		t.Errorf("IGD of the true front to itself %g, want 0", got)
+	}
+}
@@ -0,0 +1,146 @@
+package coa
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Restart strategies: reinitialize every crayfish but the best one (elite), the same with a
+// population Growth times larger every time (ipop, as in IPOP-CMA-ES), or only the worst Share of
+// them, each from the better of a random point and its opposite in the bounds (opposition)
+const (
+	RestartElite      = "elite"
+	RestartIPOP       = "ipop"
+	RestartOpposition = "opposition"
+)
+
+// Restart is when and how an optimizer starts over once the crayfish stop finding anything: after
+// Stagnation iterations without a better best position, or when the population collapses (see
+// Params.MinDiversity, which then restarts the run instead of stopping it). No restarts when
+// Strategy is empty.
+type Restart struct {
+	Strategy   string
+	Stagnation int     // Iterations without improvement, 0: on collapse only
+	Growth     float64 // ipop: population multiplier, 2 when 0
+	Share      float64 // opposition: share of the crayfish reinitialized, 0.5 when 0
+	Max        int     // Restarts per run, 0: no limit
+	MaxSize    int     // ipop: the population grows up to it, 1000 when 0
+}
+
+// RestartEvent is one restart of a run
+type RestartEvent struct {
+	Iteration   int    // Last iteration before the restart
+	Reason      string // "stagnation" or "diversity"
+	Size        int    // Crayfish after the restart
+	BestFitness float64
+}
+
+// Validate rejects unknown strategies and settings they can't work with
+func (r Restart) Validate() error {
+	switch r.Strategy {
+	case "", RestartElite, RestartIPOP, RestartOpposition:
+	default:
+		return fmt.Errorf("coa: unknown restart strategy %q (known: %s, %s, %s)", r.Strategy, RestartElite, RestartIPOP, RestartOpposition)
+	}
+	switch {
+	case r.Stagnation < 0 || r.Max < 0 || r.MaxSize < 0:
+		return fmt.Errorf("coa: restart stagnation, max and max size can't be negative, got %d, %d and %d", r.Stagnation, r.Max, r.MaxSize)
+	case r.Growth != 0 && r.Growth < 1:
+		return fmt.Errorf("coa: restart growth must be at least 1, got %g", r.Growth)
+	case r.Share < 0 || r.Share > 1:
+		return fmt.Errorf("coa: restart share is a fraction of the population, got %g", r.Share)
+	}
+	return nil
+}
+
+// Whether the optimizer may restart once more
+func (o *Optimizer) canRestart() bool {
+	r := o.Restart
+	return r.Strategy != "" && (r.Max == 0 || len(o.Restarts) < r.Max) && !o.Done()
+}
+
+// Start over from the best crayfish, the new ones are drawn from the optimizer's random source
+func (o *Optimizer) restart(reason string) {
+	r := o.Restart
+	N, dim := len(o.X), len(o.BestPos)
+
+	// Worst first, the best crayfish (the elite) last
+	order := make([]int, N)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return o.better(o.FitnessF[order[b]], o.Violation[order[b]], o.FitnessF[order[a]], o.Violation[order[a]])
+	})
+	renew := order[:N-1]
+
+	switch r.Strategy {
+	case RestartIPOP:
+		growth := r.Growth
+		if growth == 0 {
+			growth = 2
+		}
+		limit := r.MaxSize
+		if limit == 0 {
+			limit = 1000
+		}
+		size := min(int(math.Ceil(float64(N)*growth)), max(limit, N))
+		for i := N; i < size; i++ {
+			o.X = append(o.X, make([]float64, dim))
+			o.FitnessF = append(o.FitnessF, 0)
+			o.Violation = append(o.Violation, 0)
+			o.xnew = append(o.xnew, make([]float64, dim))
+			renew = append(renew, i)
+		}
+	case RestartOpposition:
+		share := r.Share
+		if share == 0 {
+			share = 0.5
+		}
+		renew = renew[:int(math.Ceil(share*float64(N-1)))]
+	}
+
+	opposite := make([]float64, dim)
+	for _, i := range renew {
+		x := o.X[i]
+		for j := range x {
+			l, u := bound(o.LB, o.UB, j)
+			x[j] = o.rng.Float64()*(u-l) + l
+		}
+		o.FitnessF[i], o.Violation[i] = o.F(x), o.violation(x)
+		o.Counters.Evaluations++
+		if r.Strategy == RestartOpposition { // Opposition-based learning: keep the better of x and lb + ub - x
+			for j := range x {
+				l, u := bound(o.LB, o.UB, j)
+				opposite[j] = l + u - x[j]
+			}
+			o.Counters.Evaluations++
+			if f, v := o.F(opposite), o.violation(opposite); o.better(f, v, o.FitnessF[i], o.Violation[i]) {
+				o.FitnessF[i], o.Violation[i] = f, v
+				copy(x, opposite)
+			}
+		}
+	}
+
+	// The cave and the food start again from the new population's best
+	o.GlobalFitness, o.GlobalViolation = math.Inf(1), math.Inf(1)
+	for i, f := range o.FitnessF {
+		if o.better(f, o.Violation[i], o.GlobalFitness, o.GlobalViolation) {
+			o.GlobalFitness, o.GlobalViolation = f, o.Violation[i]
+			copy(o.GlobalPos, o.X[i])
+		}
+	}
+	o.Share(o.GlobalPos, o.GlobalFitness)
+
+	o.stagnant = 0
+	o.lsStep = 0    // The pattern search starts wide again
+	o.MaxSpread = 0 // Exploration is relative to the new population
+	o.Counters.Restarts++
+	o.Restarts = append(o.Restarts, RestartEvent{
+		Iteration:   o.Iteration - 1,
+		Reason:      reason,
+		Size:        len(o.X),
+		BestFitness: o.BestFitness,
+	})
+}
@@ -0,0 +1,207 @@
+package coa
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+
+	"crayfish/benchmarks"
+)
+
+// An optimizer of 10 crayfish on F1 in 3 dimensions with the restart policy r
+func restartOptimizer(t *testing.T, r Restart, seed int64) *Optimizer {
+	t.Helper()
+	specs, err := benchmarks.Get("F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	X := SeededPopulation(seed, 10, 3, specs.LB, specs.UB)
+	o := NewSeededOptimizer(30, specs.LB, specs.UB, X, specs.Function, seed)
+	o.Restart = r
+	return o
+}
+
+// Nothing beats F1's optimum: every iteration from now on is a stagnant one
+func stagnate(o *Optimizer) {
+	o.Share(make([]float64, len(o.BestPos)), 0)
+}
+
+func inBounds(o *Optimizer) bool {
+	for _, x := range o.X {
+		for j, v := range x {
+			if l, u := bound(o.LB, o.UB, j); v < l || v > u {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestRestartValidate(t *testing.T) {
+	tests := []struct {
+		r  Restart
+		ok bool
+	}{
+		{Restart{}, true},
+		{Restart{Strategy: RestartElite, Stagnation: 20, Max: 3}, true},
+		{Restart{Strategy: RestartIPOP, Growth: 1.5, MaxSize: 200}, true},
+		{Restart{Strategy: RestartOpposition, Share: 1}, true},
+		{Restart{Strategy: "random"}, false},
+		{Restart{Strategy: RestartElite, Stagnation: -1}, false},
+		{Restart{Strategy: RestartElite, Max: -1}, false},
+		{Restart{Strategy: RestartIPOP, Growth: 0.5}, false},
+		{Restart{Strategy: RestartIPOP, MaxSize: -10}, false},
+		{Restart{Strategy: RestartOpposition, Share: 1.5}, false},
+		{Restart{Strategy: RestartOpposition, Share: -0.1}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.r.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v): %v, want ok=%v", tt.r, err, tt.ok)
+		}
+	}
+}
+
+func TestRestartStrategies(t *testing.T) {
+	tests := []struct {
+		r       Restart
+		size    int // Crayfish after the restart
+		renewed int // ... drawn again
+	}{
+		{Restart{Strategy: RestartElite}, 10, 9},
+		{Restart{Strategy: RestartIPOP}, 20, 19},
+		{Restart{Strategy: RestartIPOP, Growth: 1.5}, 15, 14},
+		{Restart{Strategy: RestartIPOP, MaxSize: 12}, 12, 11},
+		{Restart{Strategy: RestartOpposition}, 10, 5}, // Half of the 9 worst, rounded up
+		{Restart{Strategy: RestartOpposition, Share: 1}, 10, 9},
+	}
+	for _, tt := range tests {
+		o := restartOptimizer(t, tt.r, 3)
+		o.Run(5)
+		before := make([][]float64, len(o.X))
+		for i, x := range o.X {
+			before[i] = append([]float64(nil), x...)
+		}
+		elite := slices.Index(o.FitnessF, slices.Min(o.FitnessF))
+		bestFitness := o.BestFitness
+
+		o.restart("stagnation")
+
+		if len(o.X) != tt.size || len(o.FitnessF) != tt.size || len(o.Violation) != tt.size {
+			t.Errorf("%+v: %d crayfish (%d fitness), want %d", tt.r, len(o.X), len(o.FitnessF), tt.size)
+			continue
+		}
+		renewed := tt.size - len(before)
+		for i := range before {
+			if !reflect.DeepEqual(o.X[i], before[i]) {
+				renewed++
+			}
+		}
+		// The opposition keeps the better of a point and its opposite, both differ from the old one
+		if renewed != tt.renewed {
+			t.Errorf("%+v: %d crayfish drawn again, want %d", tt.r, renewed, tt.renewed)
+		}
+		if !reflect.DeepEqual(o.X[elite], before[elite]) {
+			t.Errorf("%+v: the best crayfish was drawn again", tt.r)
+		}
+		if !inBounds(o) {
+			t.Errorf("%+v: crayfish out of bounds after the restart", tt.r)
+		}
+		for i, x := range o.X {
+			if f := o.F(x); f != o.FitnessF[i] {
+				t.Errorf("%+v: crayfish %d has fitness %g, F1 says %g", tt.r, i, o.FitnessF[i], f)
+				break
+			}
+		}
+		if o.BestFitness != bestFitness {
+			t.Errorf("%+v: best fitness %g after the restart, %g before", tt.r, o.BestFitness, bestFitness)
+		}
+		want := []RestartEvent{{Iteration: 4, Reason: "stagnation", Size: tt.size, BestFitness: bestFitness}}
+		if !reflect.DeepEqual(o.Restarts, want) || o.Counters.Restarts != 1 {
+			t.Errorf("%+v: events %+v (counted %d), want %+v", tt.r, o.Restarts, o.Counters.Restarts, want)
+		}
+	}
+}
+
+func TestRestartOnStagnation(t *testing.T) {
+	tests := []struct {
+		name       string
+		r          Restart
+		iterations []int // Of the restart events
+	}{
+		{"no strategy", Restart{Stagnation: 5}, nil},
+		{"collapse only", Restart{Strategy: RestartElite}, nil},
+		{"every 5 iterations", Restart{Strategy: RestartElite, Stagnation: 5}, []int{4, 9, 14, 19, 24}},
+		{"at most twice", Restart{Strategy: RestartElite, Stagnation: 5, Max: 2}, []int{4, 9}},
+		{"every 12 iterations", Restart{Strategy: RestartOpposition, Stagnation: 12}, []int{11, 23}},
+	}
+	for _, tt := range tests {
+		o := restartOptimizer(t, tt.r, 4)
+		stagnate(o)
+		o.Run(o.T)
+
+		var iterations []int
+		for _, e := range o.Restarts {
+			iterations = append(iterations, e.Iteration)
+			if e.Reason != "stagnation" || e.BestFitness != 0 {
+				t.Errorf("%s: event %+v, want a stagnation at the best fitness 0", tt.name, e)
+			}
+		}
+		if !reflect.DeepEqual(iterations, tt.iterations) || o.Counters.Restarts != int64(len(tt.iterations)) {
+			t.Errorf("%s: restarts after iterations %v (counted %d), want %v", tt.name, iterations, o.Counters.Restarts, tt.iterations)
+		}
+		if !inBounds(o) {
+			t.Errorf("%s: crayfish out of bounds", tt.name)
+		}
+	}
+
+	// A better best position starts the count again
+	o := restartOptimizer(t, Restart{Strategy: RestartElite, Stagnation: 5}, 4)
+	o.Run(3)
+	if o.stagnant >= 3 {
+		t.Errorf("stagnant for %d of the first 3 iterations of a random population", o.stagnant)
+	}
+}
+
+// IPOP doubles the population at every restart, up to MaxSize
+func TestRestartIPOPGrowth(t *testing.T) {
+	o := restartOptimizer(t, Restart{Strategy: RestartIPOP, Stagnation: 5, MaxSize: 50}, 5)
+	stagnate(o)
+	o.Run(o.T)
+
+	var sizes []int
+	for _, e := range o.Restarts {
+		sizes = append(sizes, e.Size)
+	}
+	if want := []int{20, 40, 50, 50, 50}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("sizes after the restarts %v, want %v", sizes, want)
+	}
+	if len(o.X) != 50 || len(o.xnew) != 50 || !inBounds(o) {
+		t.Errorf("%d crayfish (%d moves) at the end, want 50 in bounds", len(o.X), len(o.xnew))
+	}
+	if math.IsInf(o.GlobalCov[o.T-1], 0) || o.BestFitness != 0 {
+		t.Errorf("last convergence %g, best fitness %g; want the grown population measured and the best kept", o.GlobalCov[o.T-1], o.BestFitness)
+	}
+}
+
+// Restarts draw from the optimizer's source: the same seed restarts the same way
+func TestRestartSeeded(t *testing.T) {
+	for _, strategy := range []string{RestartElite, RestartIPOP, RestartOpposition} {
+		r := Restart{Strategy: strategy, Stagnation: 5}
+		a, b := restartOptimizer(t, r, 6), restartOptimizer(t, r, 6)
+		stagnate(a)
+		stagnate(b)
+		a.Run(a.T)
+		b.Run(b.T)
+		if len(a.Restarts) == 0 || !reflect.DeepEqual(a.Restarts, b.Restarts) || !reflect.DeepEqual(a.X, b.X) {
+			t.Errorf("%s: the same seed gave the restarts %+v and %+v", strategy, a.Restarts, b.Restarts)
+		}
+
+		c := restartOptimizer(t, r, 7)
+		stagnate(c)
+		c.Run(c.T)
+		if reflect.DeepEqual(a.X, c.X) {
+			t.Errorf("%s: seeds 6 and 7 ended with the same crayfish", strategy)
+		}
+	}
+}
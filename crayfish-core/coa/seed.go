@@ -0,0 +1,54 @@
+package coa
+
+// Seeded populations: instead of shipping a sub-population, a task carries the job's seed and the
+// worker regenerates its rows of the N x dim population. The values come from a SplitMix64 stream
+// (documented in wire/SCHEMA.md, so workers in other languages can reproduce them) drawn row-major
+// over the whole population; the stream can jump straight to a row, so a worker only generates its own.
+
+const splitMixGamma = 0x9E3779B97F4A7C15
+
+// n-th output (1-based) of the SplitMix64 stream started at seed
+func splitMix64(seed, n uint64) uint64 {
+	z := seed + n*splitMixGamma
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// SubPopulationRange returns where the index-th of k sub-populations starts in a population of N
+// and how many crayfish it has (same split as DividePopulation)
+func SubPopulationRange(N, k, index int) (start, size int) {
+	base, remainder := N/k, N%k
+	start = index*base + min(index, remainder)
+	size = base
+	if index < remainder {
+		size++
+	}
+	return start, size
+}
+
+// SeededPopulation generates the whole N x dim population of a seeded job
+func SeededPopulation(seed int64, N, dim int, lb, ub []float64) [][]float64 {
+	return seededRows(seed, 0, N, dim, lb, ub)
+}
+
+// SeededSubPopulation regenerates the index-th of k sub-populations of SeededPopulation(seed, N, ...)
+func SeededSubPopulation(seed int64, N, k, index, dim int, lb, ub []float64) [][]float64 {
+	start, size := SubPopulationRange(N, k, index)
+	return seededRows(seed, start, size, dim, lb, ub)
+}
+
+// Rows [start, start+size) of the seeded population
+func seededRows(seed int64, start, size, dim int, lb, ub []float64) [][]float64 {
+	X := make([][]float64, size)
+	for i := range X {
+		X[i] = make([]float64, dim)
+		for j := range X[i] {
+			n := uint64((start+i)*dim+j) + 1
+			u := float64(splitMix64(uint64(seed), n)>>11) / (1 << 53) // Uniform in [0, 1)
+			l, h := bound(lb, ub, j)
+			X[i][j] = u*(h-l) + l
+		}
+	}
+	return X
+}
@@ -0,0 +1,156 @@
+package coa
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Sobol takes the points 1 to N of the Sobol sequence, every dimension XORed with a random
+// 32-bit shift. The first 21 dimensions use the direction numbers of Joe and Kuo (new-joe-kuo-6.21201);
+// the next ones their primitive polynomials in the same order, with initial direction numbers drawn
+// from a fixed stream, which spreads the points less evenly than tuned ones would.
+type Sobol struct{ Seed int64 }
+
+func (s Sobol) Initialize(N, dim int, lb, ub []float64) ([][]float64, error) {
+	rng := rand.New(NewSource(s.Seed))
+	X := population(N, dim)
+	for j, v := range sobolDirections(dim) {
+		shift := rng.Uint32()
+		l, u := bound(lb, ub, j)
+		for i := range X {
+			var x uint32
+			for g, k := uint32(i+1)^uint32(i+1)>>1, 0; g > 0; g, k = g>>1, k+1 { // Gray code of the index
+				if g&1 == 1 {
+					x ^= v[k]
+				}
+			}
+			X[i][j] = l + float64(x^shift)/(1<<32)*(u-l)
+		}
+	}
+	return X, nil
+}
+
+// Degree, coefficients and initial direction numbers of dimensions 2 to 21 (Joe and Kuo)
+var joeKuo = []struct {
+	s, a uint32
+	m    []uint32
+}{
+	{1, 0, []uint32{1}}, {2, 1, []uint32{1, 3}}, {3, 1, []uint32{1, 3, 1}}, {3, 2, []uint32{1, 1, 1}},
+	{4, 1, []uint32{1, 1, 3, 3}}, {4, 4, []uint32{1, 3, 5, 13}}, {5, 2, []uint32{1, 1, 5, 5, 17}},
+	{5, 4, []uint32{1, 1, 5, 5, 5}}, {5, 7, []uint32{1, 1, 7, 11, 19}}, {5, 11, []uint32{1, 1, 5, 1, 1}},
+	{5, 13, []uint32{1, 1, 1, 3, 11}}, {5, 14, []uint32{1, 3, 5, 5, 31}}, {6, 1, []uint32{1, 3, 3, 9, 7, 49}},
+	{6, 13, []uint32{1, 1, 1, 15, 21, 21}}, {6, 16, []uint32{1, 3, 1, 13, 27, 49}}, {6, 19, []uint32{1, 1, 1, 15, 7, 5}},
+	{6, 22, []uint32{1, 3, 1, 15, 13, 25}}, {6, 25, []uint32{1, 1, 5, 5, 19, 61}}, {7, 1, []uint32{1, 3, 7, 11, 23, 15, 103}},
+	{7, 4, []uint32{1, 3, 7, 13, 13, 15, 69}},
+}
+
+var (
+	sobolMu    sync.Mutex
+	sobolCache [][32]uint32 // Direction numbers of the dimensions computed so far
+)
+
+// Direction numbers of the first dim dimensions, scaled to 32 bits
+func sobolDirections(dim int) [][32]uint32 {
+	sobolMu.Lock()
+	defer sobolMu.Unlock()
+	if len(sobolCache) >= dim {
+		return sobolCache[:dim]
+	}
+
+	var v [32]uint32 // The first dimension is the van der Corput sequence
+	for k := range v {
+		v[k] = 1 << (31 - k)
+	}
+	directions := [][32]uint32{v}
+	stream := NewSource(0x5eed)
+	for s, a := uint32(1), uint32(0); len(directions) < dim; {
+		if primitive(s, a) {
+			var m []uint32
+			if n := len(directions) - 1; n < len(joeKuo) {
+				m = joeKuo[n].m
+			} else {
+				for k := uint32(1); k <= s; k++ { // Odd and below 2^k
+					m = append(m, uint32(stream.Uint64()%(1<<(k-1)))<<1|1)
+				}
+			}
+			directions = append(directions, sobolVector(s, a, m))
+		}
+		if a++; a == 1<<(s-1) {
+			s, a = s+1, 0
+		}
+	}
+	sobolCache = directions
+	return directions
+}
+
+// Direction numbers of the polynomial of degree s with inner coefficients a from the initial ones m
+func sobolVector(s, a uint32, m []uint32) [32]uint32 {
+	mk := make([]uint32, 32)
+	copy(mk, m)
+	for k := s; k < 32; k++ {
+		next := mk[k-s] ^ mk[k-s]<<s
+		for i := uint32(1); i < s; i++ {
+			next ^= (a >> (s - 1 - i) & 1) * (mk[k-i] << i)
+		}
+		mk[k] = next
+	}
+	var v [32]uint32
+	for k := range v {
+		v[k] = mk[k] << (31 - k)
+	}
+	return v
+}
+
+// Whether x^s + a_1 x^(s-1) + ... + a_(s-1) x + 1 (a_1 the highest bit of a) is primitive over
+// GF(2): x has order 2^s - 1 modulo it
+func primitive(s, a uint32) bool {
+	if s == 1 {
+		return true // x + 1
+	}
+	p := uint64(1)<<s | uint64(a)<<1 | 1
+	order := uint64(1)<<s - 1
+	if polyPow(2, order, p, s) != 1 {
+		return false
+	}
+	n := order
+	for q := uint64(2); n > 1; q++ { // x^(order/q) must not be 1 for the prime factors q of the order
+		if q*q > n {
+			q = n // What is left is prime
+		}
+		if n%q != 0 {
+			continue
+		}
+		if polyPow(2, order/q, p, s) == 1 {
+			return false
+		}
+		for n%q == 0 {
+			n /= q
+		}
+	}
+	return true
+}
+
+// x^e modulo p, polynomials over GF(2) as bits
+func polyPow(x, e, p uint64, s uint32) uint64 {
+	r := uint64(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = polyMul(r, x, p, s)
+		}
+		x = polyMul(x, x, p, s)
+	}
+	return r
+}
+
+func polyMul(x, y, p uint64, s uint32) uint64 {
+	var r uint64
+	for ; y > 0; y >>= 1 {
+		if y&1 == 1 {
+			r ^= x
+		}
+		if x <<= 1; x>>s&1 == 1 {
+			x ^= p
+		}
+	}
+	return r
+}
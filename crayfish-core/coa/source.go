@@ -0,0 +1,43 @@
+package coa
+
+import "math/rand"
+
+// Source is a rand.Source64 whose whole state is two integers, so a run can be checkpointed and
+// resumed with the exact same random numbers (math/rand's own source can't be saved). It is the
+// SplitMix64 stream of seed.go, started from the mixed seed so it doesn't repeat the values of a
+// seeded population drawn from the same seed.
+type Source struct {
+	seed, n uint64
+}
+
+var _ rand.Source64 = (*Source)(nil)
+
+// NewSource returns a source seeded with seed
+func NewSource(seed int64) *Source {
+	s := &Source{}
+	s.Seed(seed)
+	return s
+}
+
+func (s *Source) Seed(seed int64) {
+	s.seed, s.n = splitMix64(uint64(seed), 0), 0
+}
+
+func (s *Source) Uint64() uint64 {
+	s.n++
+	return splitMix64(s.seed, s.n)
+}
+
+func (s *Source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// State returns the stream and how many values were drawn from it
+func (s *Source) State() (stream, drawn uint64) {
+	return s.seed, s.n
+}
+
+// RestoreSource continues a source from its State
+func RestoreSource(stream, drawn uint64) *Source {
+	return &Source{seed: stream, n: drawn}
+}
@@ -0,0 +1,91 @@
+package coa
+
+import "math"
+
+// Stage is what one iteration did: the temperature that picked the stage, how many crayfish took
+// each branch, how big the food was, how many moves were kept and how far the crayfish moved. A run
+// records them when Optimizer.RecordStages is set, to see why it stalls (e.g. a temperature that
+// never leaves foraging, or food that is always shredded).
+type Stage struct {
+	Iteration   int
+	Temperature float64 // Equation 3, summer resort or competition above Params.TempHigh
+	C           float64 // Decreasing curve, Equation 7
+
+	// Crayfish per branch: summer resort (Equation 6), competition (Equation 8), foraging with the
+	// food shredded because P > Params.FoodSize (Equation 13) or eaten directly (Equation 14)
+	Summer, Competition, Shredding, Eating int
+
+	FoodMean, FoodMax float64 // Food size P over the foraging crayfish, 0 when none foraged
+	Accepted          int     // Moves that improved the crayfish and were kept
+	StepMean, StepMax float64 // Euclidean length of the moves, within the bounds
+	Variant           int     // Crayfish improved by the variant after the moves (elite opposition, local search)
+}
+
+// Moves is the number of crayfish that moved
+func (s Stage) Moves() int {
+	return s.Summer + s.Competition + s.Shredding + s.Eating
+}
+
+// AcceptanceRate is the share of the moves that were kept
+func (s Stage) AcceptanceRate() float64 {
+	if s.Moves() == 0 {
+		return 0
+	}
+	return float64(s.Accepted) / float64(s.Moves())
+}
+
+// StageSummary is the behavior of a run over its recorded iterations
+type StageSummary struct {
+	Iterations int
+
+	// Share of the moves per branch
+	Summer, Competition, Shredding, Eating float64
+
+	Acceptance float64 // Share of the moves kept
+	StepMean   float64 // Mean length of a move
+	Stalled    int     // Iterations where no move was kept
+}
+
+// SummarizeStages sums up the recorded iterations of one or more runs
+func SummarizeStages(stages ...[]Stage) StageSummary {
+	var (
+		s               StageSummary
+		moves, accepted int
+		branches        [4]int
+		steps           float64
+	)
+	for _, run := range stages {
+		for _, st := range run {
+			s.Iterations++
+			moves += st.Moves()
+			accepted += st.Accepted
+			branches[0] += st.Summer
+			branches[1] += st.Competition
+			branches[2] += st.Shredding
+			branches[3] += st.Eating
+			steps += st.StepMean * float64(st.Moves())
+			if st.Accepted == 0 {
+				s.Stalled++
+			}
+		}
+	}
+	if moves == 0 {
+		return s
+	}
+	total := float64(moves)
+	s.Summer, s.Competition = float64(branches[0])/total, float64(branches[1])/total
+	s.Shredding, s.Eating = float64(branches[2])/total, float64(branches[3])/total
+	s.Acceptance = float64(accepted) / total
+	s.StepMean = steps / total
+	return s
+}
+
+// Length of the move of a crayfish from x to xnew
+func stepLength(x, xnew []float64) float64 {
+	var sum float64
+	for j := range x {
+		d := xnew[j] - x[j]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,115 @@
+package coa
+
+import (
+	"math"
+	"testing"
+
+	"crayfish/benchmarks"
+)
+
+func TestStageRates(t *testing.T) {
+	s := Stage{Summer: 2, Competition: 1, Shredding: 3, Eating: 4, Accepted: 5}
+	if s.Moves() != 10 || s.AcceptanceRate() != 0.5 {
+		t.Errorf("moves %d, acceptance %g; want 10 and 0.5", s.Moves(), s.AcceptanceRate())
+	}
+	if r := (Stage{}).AcceptanceRate(); r != 0 {
+		t.Errorf("acceptance of an iteration without moves %g", r)
+	}
+}
+
+// The shares printed by the publishers' summary (crayfish publish -stages)
+func TestSummarizeStages(t *testing.T) {
+	first := []Stage{
+		{Iteration: 0, Summer: 6, Competition: 4, Accepted: 5, StepMean: 2},
+		{Iteration: 1, Shredding: 10, Accepted: 0, StepMean: 1},
+	}
+	second := []Stage{
+		{Iteration: 0, Eating: 10, Accepted: 10, StepMean: 0.5},
+		{Iteration: 1, Summer: 4, Competition: 6, Accepted: 3, StepMean: 4.5},
+	}
+	tests := []struct {
+		name   string
+		stages [][]Stage
+		want   StageSummary
+	}{
+		{"nothing recorded", nil, StageSummary{}},
+		{"no moves", [][]Stage{{{Iteration: 0}, {Iteration: 1}}}, StageSummary{Iterations: 2, Stalled: 2}},
+		{"one run", [][]Stage{first}, StageSummary{
+			Iterations: 2,
+			Summer:     0.3, Competition: 0.2, Shredding: 0.5,
+			Acceptance: 0.25, StepMean: 1.5, Stalled: 1,
+		}},
+		{"two runs", [][]Stage{first, second}, StageSummary{
+			Iterations: 4,
+			Summer:     0.25, Competition: 0.25, Shredding: 0.25, Eating: 0.25,
+			Acceptance: 0.45, StepMean: 2, Stalled: 1,
+		}},
+	}
+	for _, tt := range tests {
+		got := SummarizeStages(tt.stages...)
+		near := func(a, b float64) bool { return math.Abs(a-b) < 1e-12 }
+		if got.Iterations != tt.want.Iterations || got.Stalled != tt.want.Stalled ||
+			!near(got.Summer, tt.want.Summer) || !near(got.Competition, tt.want.Competition) ||
+			!near(got.Shredding, tt.want.Shredding) || !near(got.Eating, tt.want.Eating) ||
+			!near(got.Acceptance, tt.want.Acceptance) || !near(got.StepMean, tt.want.StepMean) {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Every iteration of a run is recorded, consistent with the temperature that picked its stage and
+// with the optimizer's counters
+func TestRecordStages(t *testing.T) {
+	specs, err := benchmarks.Get("F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const N, T = 12, 40
+	X := SeededPopulation(2, N, 4, specs.LB, specs.UB)
+	o := NewSeededOptimizer(T, specs.LB, specs.UB, X, specs.Function, 2)
+	o.RecordStages = true
+	o.Run(T)
+
+	if len(o.Stages) != T {
+		t.Fatalf("%d stages recorded for %d iterations", len(o.Stages), T)
+	}
+	p := o.Params
+	var summer, competition, foraging int64
+	for k, st := range o.Stages {
+		if st.Iteration != k || st.Moves() != N || st.Accepted > N {
+			t.Errorf("iteration %d: %+v, want %d moves", k, st, N)
+		}
+		if st.Temperature < p.TempMin || st.Temperature > p.TempMax {
+			t.Errorf("iteration %d: temperature %g outside [%g, %g]", k, st.Temperature, p.TempMin, p.TempMax)
+		}
+		if want := p.C2 - float64(k)/T; math.Abs(st.C-want) > 1e-12 {
+			t.Errorf("iteration %d: C %g, want %g", k, st.C, want)
+		}
+		if hot := st.Temperature > p.TempHigh; hot && st.Shredding+st.Eating > 0 || !hot && st.Summer+st.Competition > 0 {
+			t.Errorf("iteration %d at %g degrees: %+v", k, st.Temperature, st)
+		}
+		if st.Shredding+st.Eating == 0 && (st.FoodMean != 0 || st.FoodMax != 0) {
+			t.Errorf("iteration %d: food %g without foraging", k, st.FoodMean)
+		}
+		if st.FoodMean > st.FoodMax || st.StepMean > st.StepMax || st.StepMean < 0 {
+			t.Errorf("iteration %d: food mean %g max %g, step mean %g max %g", k, st.FoodMean, st.FoodMax, st.StepMean, st.StepMax)
+		}
+		summer += int64(st.Summer)
+		competition += int64(st.Competition)
+		foraging += int64(st.Shredding + st.Eating)
+	}
+	if c := o.Counters; c.Summer != summer || c.Competition != competition || c.Foraging != foraging {
+		t.Errorf("counters %+v, stages %d summer, %d competition, %d foraging", c, summer, competition, foraging)
+	}
+
+	s := SummarizeStages(o.Stages)
+	if sum := s.Summer + s.Competition + s.Shredding + s.Eating; s.Iterations != T || math.Abs(sum-1) > 1e-12 {
+		t.Errorf("summary %+v: shares summing to %g", s, sum)
+	}
+
+	quiet := NewSeededOptimizer(T, specs.LB, specs.UB, SeededPopulation(2, N, 4, specs.LB, specs.UB), specs.Function, 2)
+	quiet.Run(T)
+	if quiet.Stages != nil || quiet.BestFitness != o.BestFitness {
+		t.Errorf("without recording: %d stages, best fitness %g (recorded %g)", len(quiet.Stages), quiet.BestFitness, o.BestFitness)
+	}
+}
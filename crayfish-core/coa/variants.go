@@ -0,0 +1,178 @@
+package coa
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Variants of COA from the literature, each a change to one stage of Step so they compare fairly
+// with the original (VariantCOA): a Lévy flight added to the foraging moves, the temperature drawn
+// from a logistic map, elite opposition-based learning after every iteration, the curve C scaled by
+// the success of the last iteration (the 1/5 rule), and a pattern search around the best crayfish
+// every few iterations (hybrid)
+const (
+	VariantCOA        = "coa"
+	VariantLevy       = "levy"
+	VariantChaotic    = "chaotic"
+	VariantOpposition = "elite-opposition"
+	VariantAdaptive   = "adaptive"
+	VariantHybrid     = "hybrid"
+)
+
+const (
+	levyBeta         = 1.5  // Exponent of the Lévy distribution
+	levyScale        = 0.01 // Step size of the flight, relative to the distance to the best crayfish
+	eliteShare       = 0.1  // Share of the crayfish in the elite of the elite opposition, at least 2
+	adaptiveSuccess  = 0.2  // Acceptance rate the adaptive C aims at
+	adaptiveFactor   = 1.1  // Change of the scale of C per iteration
+	localSearchEvery = 10   // Iterations between two pattern searches of the hybrid
+	localSearchStep  = 0.05 // First step of the pattern search, relative to the bounds
+)
+
+// VariantNames lists the variants Optimizer.Variant understands
+func VariantNames() []string {
+	return []string{VariantCOA, VariantLevy, VariantChaotic, VariantOpposition, VariantAdaptive, VariantHybrid}
+}
+
+// ValidateVariant rejects unknown variants, empty is VariantCOA
+func ValidateVariant(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, v := range VariantNames() {
+		if name == v {
+			return nil
+		}
+	}
+	return fmt.Errorf("coa: unknown variant %q (known: %v)", name, VariantNames())
+}
+
+// Spread of the numerator of Mantegna's algorithm for levyBeta
+var levySigma = math.Pow(math.Gamma(1+levyBeta)*math.Sin(math.Pi*levyBeta/2)/
+	(math.Gamma((1+levyBeta)/2)*levyBeta*math.Pow(2, (levyBeta-1)/2)), 1/levyBeta)
+
+// Mantegna's algorithm for a step of a Lévy flight
+func (o *Optimizer) levy() float64 {
+	u, v := o.rng.NormFloat64()*levySigma, o.rng.NormFloat64()
+	return u / math.Pow(math.Abs(v), 1/levyBeta)
+}
+
+// Temperature of the iteration from the logistic map z = 4z(1-z), started at a random point away
+// from its fixed points
+func (o *Optimizer) chaoticTemperature() float64 {
+	z := 4 * o.chaos * (1 - o.chaos)
+	if z <= 0 || z >= 1 || z == 0.75 {
+		z = 0.01 + 0.98*o.rng.Float64()
+	}
+	o.chaos = z
+	return z*(o.Params.TempMax-o.Params.TempMin) + o.Params.TempMin
+}
+
+// Scale of the curve C after an iteration that kept accepted of its moves: larger when more than a
+// fifth of them improved, smaller otherwise
+func (o *Optimizer) adapt(accepted int) {
+	if o.cScale == 0 {
+		o.cScale = 1
+	}
+	if float64(accepted) > adaptiveSuccess*float64(len(o.X)) {
+		o.cScale = math.Min(o.cScale*adaptiveFactor, 2)
+	} else {
+		o.cScale = math.Max(o.cScale/adaptiveFactor, 0.25)
+	}
+}
+
+// Elite opposition-based learning: each of the best crayfish is compared with its opposite
+// k(da + db) - x within the box [da, db] they span, and replaced by it when that is better. Returns
+// the crayfish replaced.
+func (o *Optimizer) eliteOpposition() int {
+	N, dim := len(o.X), len(o.BestPos)
+	order := make([]int, N)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return o.better(o.FitnessF[order[a]], o.Violation[order[a]], o.FitnessF[order[b]], o.Violation[order[b]])
+	})
+	elite := order[:min(max(int(math.Ceil(eliteShare*float64(N))), 2), N)]
+
+	da, db := make([]float64, dim), make([]float64, dim)
+	for j := 0; j < dim; j++ {
+		da[j], db[j] = math.Inf(1), math.Inf(-1)
+		for _, e := range elite {
+			da[j], db[j] = math.Min(da[j], o.X[e][j]), math.Max(db[j], o.X[e][j])
+		}
+	}
+
+	k := o.rng.Float64()
+	replaced := 0
+	opposite := make([]float64, dim)
+	for _, i := range elite {
+		for j := 0; j < dim; j++ {
+			opposite[j] = k*(da[j]+db[j]) - o.X[i][j]
+			if opposite[j] < da[j] || opposite[j] > db[j] { // Outside the box: anywhere in it
+				opposite[j] = da[j] + o.rng.Float64()*(db[j]-da[j])
+			}
+		}
+		o.Counters.Evaluations++
+		if f, v := o.F(opposite), o.violation(opposite); o.better(f, v, o.FitnessF[i], o.Violation[i]) {
+			replaced++
+			o.FitnessF[i], o.Violation[i] = f, v
+			copy(o.X[i], opposite)
+			o.keep(o.X[i], f, v)
+		}
+	}
+	return replaced
+}
+
+// Pattern search around the best crayfish: a step up or down along every dimension, kept when it
+// improves, the step halving whenever none did. What it finds replaces the worst crayfish. Returns
+// whether it found anything.
+func (o *Optimizer) localSearch() bool {
+	if o.lsStep == 0 {
+		o.lsStep = localSearchStep
+	}
+	x := append([]float64(nil), o.BestPos...)
+	fx, vx := o.BestFitness, o.BestViolation
+	improved := false
+	for j := range x {
+		l, u := bound(o.LB, o.UB, j)
+		for _, d := range [2]float64{1, -1} {
+			old := x[j]
+			x[j] = math.Max(l, math.Min(u, old+d*o.lsStep*(u-l)))
+			o.Counters.Evaluations++
+			if f, v := o.F(x), o.violation(x); o.better(f, v, fx, vx) {
+				fx, vx, improved = f, v, true
+				break
+			}
+			x[j] = old
+		}
+	}
+	if !improved {
+		o.lsStep /= 2
+		return false
+	}
+
+	worst := 0
+	for i, f := range o.FitnessF {
+		if o.better(o.FitnessF[worst], o.Violation[worst], f, o.Violation[i]) {
+			worst = i
+		}
+	}
+	o.FitnessF[worst], o.Violation[worst] = fx, vx
+	copy(o.X[worst], x)
+	o.keep(x, fx, vx)
+	return true
+}
+
+// A crayfish moved outside the stages of the iteration: update the best positions with it
+func (o *Optimizer) keep(x []float64, f, v float64) {
+	if o.better(f, v, o.GlobalFitness, o.GlobalViolation) {
+		o.GlobalFitness, o.GlobalViolation = f, v
+		copy(o.GlobalPos, x)
+	}
+	if o.better(f, v, o.BestFitness, o.BestViolation) {
+		o.BestFitness, o.BestViolation = f, v
+		copy(o.BestPos, x)
+	}
+}
@@ -0,0 +1,145 @@
+package coa
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"crayfish/benchmarks"
+)
+
+func TestValidateVariant(t *testing.T) {
+	for _, name := range append(VariantNames(), "") {
+		if err := ValidateVariant(name); err != nil {
+			t.Errorf("ValidateVariant(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"pso", "Levy", "elite_opposition", " coa"} {
+		if err := ValidateVariant(name); err == nil {
+			t.Errorf("ValidateVariant(%q) accepted an unknown variant", name)
+		}
+	}
+}
+
+// A run of the variant on the benchmark, recording its stages
+func variantRun(t *testing.T, variant, function string, seed int64) *Optimizer {
+	t.Helper()
+	specs, err := benchmarks.Get(function)
+	if err != nil {
+		t.Fatal(err)
+	}
+	X := SeededPopulation(seed, 20, 5, specs.LB, specs.UB)
+	o := NewSeededOptimizer(60, specs.LB, specs.UB, X, specs.Function, seed)
+	o.Variant = variant
+	o.RecordStages = true
+	o.Run(o.T)
+	return o
+}
+
+func TestVariants(t *testing.T) {
+	for _, function := range []string{"F1", "F9"} { // Sphere, Rastrigin
+		plain := variantRun(t, VariantCOA, function, 11)
+		for _, variant := range VariantNames() {
+			o := variantRun(t, variant, function, 11)
+			if !inBounds(o) {
+				t.Errorf("%s on %s: crayfish out of bounds", variant, function)
+			}
+			if f := o.F(o.BestPos); f != o.BestFitness || math.IsNaN(f) {
+				t.Errorf("%s on %s: best fitness %g, %s of the best position %g", variant, function, o.BestFitness, function, f)
+			}
+			for i := 1; i < len(o.GlobalCov); i++ {
+				if o.GlobalCov[i] < o.BestFitness {
+					t.Errorf("%s on %s: iteration %d found %g, better than the best %g", variant, function, i, o.GlobalCov[i], o.BestFitness)
+					break
+				}
+			}
+
+			again := variantRun(t, variant, function, 11)
+			if !reflect.DeepEqual(o.X, again.X) || o.BestFitness != again.BestFitness {
+				t.Errorf("%s on %s: the same seed gave %g and %g", variant, function, o.BestFitness, again.BestFitness)
+			}
+			// The pattern search may find nothing, its evaluations tell it ran
+			if variant != VariantCOA && reflect.DeepEqual(o.X, plain.X) && o.Counters.Evaluations == plain.Counters.Evaluations {
+				t.Errorf("%s on %s: ran exactly as COA", variant, function)
+			}
+		}
+	}
+}
+
+// What each variant keeps between iterations stays in its range
+func TestVariantState(t *testing.T) {
+	p := DefaultParams()
+
+	chaotic := variantRun(t, VariantChaotic, "F1", 12)
+	if chaotic.chaos <= 0 || chaotic.chaos >= 1 {
+		t.Errorf("chaotic: logistic map at %g, want in (0, 1)", chaotic.chaos)
+	}
+	for _, st := range chaotic.Stages {
+		if st.Temperature < p.TempMin || st.Temperature > p.TempMax {
+			t.Errorf("chaotic: temperature %g of iteration %d outside [%g, %g]", st.Temperature, st.Iteration, p.TempMin, p.TempMax)
+			break
+		}
+	}
+
+	adaptive := variantRun(t, VariantAdaptive, "F1", 12)
+	if adaptive.cScale < 0.25 || adaptive.cScale > 2 {
+		t.Errorf("adaptive: scale of C %g, want in [0.25, 2]", adaptive.cScale)
+	}
+	for _, st := range adaptive.Stages[1:] { // The first iteration runs unscaled
+		plainC := p.C2 - float64(st.Iteration)/float64(adaptive.T)
+		if scale := st.C / plainC; scale < 0.25-1e-9 || scale > 2+1e-9 {
+			t.Errorf("adaptive: C scaled by %g at iteration %d", scale, st.Iteration)
+			break
+		}
+	}
+
+	hybrid := variantRun(t, VariantHybrid, "F1", 12)
+	if hybrid.lsStep <= 0 || hybrid.lsStep > localSearchStep {
+		t.Errorf("hybrid: pattern search step %g, want in (0, %g]", hybrid.lsStep, localSearchStep)
+	}
+	searches := 0
+	for _, st := range hybrid.Stages {
+		searches += st.Variant
+	}
+	if searches > hybrid.T/localSearchEvery {
+		t.Errorf("hybrid: %d pattern searches improved in %d iterations", searches, hybrid.T)
+	}
+
+	opposition := variantRun(t, VariantOpposition, "F1", 12)
+	elite := max(int(math.Ceil(eliteShare*20)), 2)
+	replaced := 0
+	for _, st := range opposition.Stages {
+		if st.Variant > elite {
+			t.Errorf("elite-opposition: %d crayfish replaced at iteration %d, the elite is %d", st.Variant, st.Iteration, elite)
+		}
+		replaced += st.Variant
+	}
+	if replaced == 0 {
+		t.Error("elite-opposition: no opposite was ever better")
+	}
+}
+
+// Mantegna's steps are symmetric and heavy-tailed: mostly small, a few far longer
+func TestLevy(t *testing.T) {
+	o := variantRun(t, VariantLevy, "F1", 13)
+	var positive, long int
+	const n = 10000
+	for i := 0; i < n; i++ {
+		step := o.levy()
+		if math.IsNaN(step) || math.IsInf(step, 0) {
+			t.Fatalf("step %g", step)
+		}
+		if step > 0 {
+			positive++
+		}
+		if math.Abs(step) > 10 {
+			long++
+		}
+	}
+	if positive < n*45/100 || positive > n*55/100 {
+		t.Errorf("%d positive steps of %d", positive, n)
+	}
+	if long == 0 || long > n/10 {
+		t.Errorf("%d steps longer than 10 of %d, want a few", long, n)
+	}
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Job is what to optimize and how: the algorithm, the benchmark, the population and its split, the
+// iterations, the search space and the algorithm's parameters
+type Job struct {
+	Algorithm string             `yaml:"algorithm"` // Only "coa" for now
+	Variant   string             `yaml:"variant"`   // Of the algorithm, see coa.VariantNames; the original when empty
+	Benchmark string             `yaml:"benchmark"` // Name in the benchmarks registry, e.g. F6
+	N         int                `yaml:"n"`         // Population size
+	K         int                `yaml:"k"`         // Sub-populations (islands, workers)
+	T         int                `yaml:"t"`         // Iterations
+	Dim       int                `yaml:"dim"`       // The benchmark's when 0
+	LB        []float64          `yaml:"lb"`        // The benchmark's when empty, one value applies to every dimension
+	UB        []float64          `yaml:"ub"`
+	Seed      int64              `yaml:"seed"`       // Of the initial population, a random one when 0
+	Init      string             `yaml:"init"`       // Initializer of the population, uniform when empty
+	WarmStart string             `yaml:"warm_start"` // File of points to start from, one per line, see WarmStartPoints
+	Params    map[string]float64 `yaml:"params"`     // Over the algorithm's defaults, e.g. {c3: 3, summer: 0.5}
+	Restart   Restart            `yaml:"restart"`
+
+	Constraints Constraints `yaml:"constraints"`
+	Pareto      Pareto      `yaml:"pareto"`
+}
+
+// Pareto is the archive of a run on a multi-objective benchmark (coa.Pareto)
+type Pareto struct {
+	Size    int    `yaml:"size"`    // Most members, 100 when 0
+	Pruning string `yaml:"pruning"` // crowding or grid; crowding when empty
+	Grid    int    `yaml:"grid"`    // grid: divisions of every objective, 10 when 0
+}
+
+// Constraints is how a run handles the constraints of a constrained benchmark (coa.Constraints)
+type Constraints struct {
+	Handling  string  `yaml:"handling"`  // static, dynamic, deb or epsilon; deb when empty
+	Penalty   float64 `yaml:"penalty"`   // static: factor of the violation, 1e6 when 0; dynamic: C, 0.5 when 0
+	Tolerance float64 `yaml:"tolerance"` // Equalities hold within it, 1e-4 when 0
+}
+
+// Restart is when and how a run starts over once its crayfish stagnate or collapse (coa.Restart)
+type Restart struct {
+	Strategy   string  `yaml:"strategy"`   // elite, ipop or opposition, none when empty
+	Stagnation int     `yaml:"stagnation"` // Iterations without improvement, 0: when the population collapses only
+	Growth     float64 `yaml:"growth"`     // ipop: population multiplier, 2 when 0
+	Share      float64 `yaml:"share"`      // opposition: share of the crayfish reinitialized, 0.5 when 0
+	Max        int     `yaml:"max"`        // Restarts per run, 0: no limit
+	MaxSize    int     `yaml:"max_size"`   // ipop: the largest population, 1000 when 0
+}
+
+// DefaultJob is a small run of F6
+func DefaultJob() Job {
+	return Job{
+		Algorithm: "coa",
+		Benchmark: "F6",
+		N:         30,
+		K:         4,
+		T:         500,
+		Dim:       10,
+	}
+}
+
+// RegisterFlags binds the job to -algorithm, -variant, -f (or -benchmark), -n, -k, -t, -dim, -lb, -ub, -seed
+// -init, -warm-start, -param name=value, the -restart-* flags, -constraints, -penalty, -tolerance and the
+// -archive-* flags
+func (j *Job) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&j.Algorithm, "algorithm", j.Algorithm, "optimization algorithm")
+	fs.StringVar(&j.Variant, "variant", j.Variant, "variant of the algorithm: coa (the original), levy, chaotic, elite-opposition, adaptive or hybrid")
+	fs.StringVar(&j.Benchmark, "f", j.Benchmark, "benchmark function (see the list below)")
+	fs.StringVar(&j.Benchmark, "benchmark", j.Benchmark, "same as -f")
+	fs.IntVar(&j.N, "n", j.N, "population size")
+	fs.IntVar(&j.K, "k", j.K, "number of sub-populations")
+	fs.IntVar(&j.T, "t", j.T, "iterations")
+	fs.IntVar(&j.Dim, "dim", j.Dim, "dimension (the benchmark's when 0)")
+	fs.Var((*floatList)(&j.LB), "lb", "lower bound(s), comma-separated (the benchmark's when empty)")
+	fs.Var((*floatList)(&j.UB), "ub", "upper bound(s), comma-separated (the benchmark's when empty)")
+	fs.Int64Var(&j.Seed, "seed", j.Seed, "seed of the initial population (0: random)")
+	fs.StringVar(&j.Init, "init", j.Init, "initializer of the population: uniform, lhs, halton, sobol, opposition, logistic or tent")
+	fs.StringVar(&j.WarmStart, "warm-start", j.WarmStart, "file of points the population starts from, one per line, the initializer draws the rest")
+	fs.Var((*paramFlag)(&j.Params), "param", "algorithm parameter name=value (repeatable), e.g. -param c3=2.5")
+	fs.StringVar(&j.Restart.Strategy, "restart", j.Restart.Strategy, "restart stagnating or collapsed populations: elite, ipop or opposition (empty: never)")
+	fs.IntVar(&j.Restart.Stagnation, "restart-stagnation", j.Restart.Stagnation, "restart after this many iterations without improvement (0: on collapse only, see -param min_diversity)")
+	fs.Float64Var(&j.Restart.Growth, "restart-growth", j.Restart.Growth, "ipop: population multiplier per restart (0: 2)")
+	fs.Float64Var(&j.Restart.Share, "restart-share", j.Restart.Share, "opposition: share of the crayfish reinitialized (0: 0.5)")
+	fs.IntVar(&j.Restart.Max, "restart-max", j.Restart.Max, "restarts per run (0: no limit)")
+	fs.IntVar(&j.Restart.MaxSize, "restart-max-size", j.Restart.MaxSize, "ipop: the largest population (0: 1000)")
+	fs.StringVar(&j.Constraints.Handling, "constraints", j.Constraints.Handling, "constraint handling of the constrained benchmarks: static, dynamic, deb or epsilon (empty: deb)")
+	fs.Float64Var(&j.Constraints.Penalty, "penalty", j.Constraints.Penalty, "static: penalty per unit of violation (0: 1e6); dynamic: C of (C*t)^2 (0: 0.5)")
+	fs.Float64Var(&j.Constraints.Tolerance, "tolerance", j.Constraints.Tolerance, "equality constraints hold within it (0: 1e-4)")
+	fs.IntVar(&j.Pareto.Size, "archive-size", j.Pareto.Size, "multi-objective benchmarks: most members of the Pareto archive (0: 100)")
+	fs.StringVar(&j.Pareto.Pruning, "archive-pruning", j.Pareto.Pruning, "pruning of a full Pareto archive: crowding or grid (empty: crowding)")
+	fs.IntVar(&j.Pareto.Grid, "archive-grid", j.Pareto.Grid, "grid pruning: divisions of every objective (0: 10)")
+}
+
+// Check rejects what no run can use, before anything is sent
+func (j Job) Check() error {
+	switch {
+	case j.N <= 0 || j.K <= 0 || j.N < j.K:
+		return fmt.Errorf("config: need 0 < k <= n, got n=%d k=%d", j.N, j.K)
+	case j.T <= 0:
+		return fmt.Errorf("config: need t > 0, got %d", j.T)
+	case len(j.LB) != len(j.UB):
+		return fmt.Errorf("config: %d lower bounds but %d upper bounds", len(j.LB), len(j.UB))
+	case len(j.LB) > 1 && j.Dim > 0 && len(j.LB) != j.Dim:
+		return fmt.Errorf("config: %d bounds for %d dimensions", len(j.LB), j.Dim)
+	}
+	for i := range j.LB {
+		if j.LB[i] > j.UB[i] {
+			return fmt.Errorf("config: lower bound %g is above upper bound %g", j.LB[i], j.UB[i])
+		}
+	}
+	return nil
+}
+
+// WarmStartPoints reads the points of the WarmStart file, none when it is empty. A point is a line of
+// numbers separated by commas or spaces; empty lines and lines starting with # are skipped.
+func (j Job) WarmStartPoints() ([][]float64, error) {
+	if j.WarmStart == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(j.WarmStart)
+	if err != nil {
+		return nil, fmt.Errorf("config: reading the warm-start points: %w", err)
+	}
+	var points [][]float64
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		point, err := parseFloats(strings.Join(strings.Fields(line), ","))
+		if err != nil {
+			return nil, fmt.Errorf("config: %s line %d: %w", j.WarmStart, n+1, err)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+type floatList []float64
+
+func (l *floatList) String() string {
+	if l == nil {
+		return ""
+	}
+	s := make([]string, len(*l))
+	for i, x := range *l {
+		s[i] = strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	return strings.Join(s, ",")
+}
+
+func (l *floatList) Set(v string) error {
+	list, err := parseFloats(v)
+	if err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+func parseFloats(v string) ([]float64, error) {
+	var list []float64
+	for _, s := range splitList(v) {
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+	}
+	return list, nil
+}
+
+type paramFlag map[string]float64
+
+func (p *paramFlag) String() string {
+	if p == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*p))
+	for name := range *p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + strconv.FormatFloat((*p)[name], 'g', -1, 64)
+	}
+	return strings.Join(names, ",")
+}
+
+// Set takes name=value, or several of them comma-separated
+func (p *paramFlag) Set(v string) error {
+	for _, s := range splitList(v) {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("expected name=value, got %q", s)
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return err
+		}
+		if *p == nil {
+			*p = make(map[string]float64)
+		}
+		(*p)[strings.TrimSpace(name)] = x
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables, e.g. CRAYFISH_REDIS_ADDRS for redis.addrs
+const EnvPrefix = "CRAYFISH"
+
+// Flagger is a configuration that binds itself to flags
+type Flagger interface {
+	RegisterFlags(fs *flag.FlagSet)
+}
+
+// Load fills v in layers: what it holds already (the defaults), the file named by -config or
+// CRAYFISH_CONFIG, the environment, and finally the command line. The flags are registered with
+// the values of the first three layers as defaults, so -help shows what a run would use (secrets
+// redacted).
+func Load(fs *flag.FlagSet, args []string, v Flagger) error {
+	path := os.Getenv(EnvPrefix + "_CONFIG")
+	if p, ok := scanFlag(args, "config"); ok {
+		path = p
+	}
+	if path != "" {
+		if err := LoadFile(path, v); err != nil {
+			return err
+		}
+	}
+	if err := ApplyEnv(EnvPrefix, v); err != nil {
+		return err
+	}
+
+	fs.String("config", path, "YAML or JSON configuration file (also CRAYFISH_CONFIG)")
+	v.RegisterFlags(fs)
+	return fs.Parse(args)
+}
+
+// LoadFile reads a YAML (or JSON, which is YAML too) file into v. Unknown keys are errors, so a
+// typo doesn't go unnoticed.
+func LoadFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) { // An empty file changes nothing
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv sets the fields of v (a pointer to a struct) from PREFIX_<YAML KEY PATH> variables, e.g.
+// CRAYFISH_REDIS_TLS_CA_FILE for redis.tls.ca_file. Lists are comma-separated, durations like "5s",
+// maps name=value pairs like "c3=2.5,summer=0.4". Inlined structs share their parent's prefix.
+func ApplyEnv(prefix string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: ApplyEnv needs a pointer to a struct, got %T", v)
+	}
+	return applyEnv(prefix, rv.Elem())
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func applyEnv(prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		f := v.Field(i)
+		if opts == "inline" && f.Kind() == reflect.Struct {
+			if err := applyEnv(prefix, f); err != nil {
+				return err
+			}
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		name := prefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+
+		if f.Kind() == reflect.Struct {
+			if err := applyEnv(name, f); err != nil {
+				return err
+			}
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(f, value); err != nil {
+			return fmt.Errorf("config: %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(f reflect.Value, value string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(x)
+	case reflect.Slice:
+		switch f.Type().Elem().Kind() {
+		case reflect.String:
+			f.Set(reflect.ValueOf(splitList(value)))
+		case reflect.Float64:
+			list, err := parseFloats(value)
+			if err != nil {
+				return err
+			}
+			f.Set(reflect.ValueOf(list))
+		default:
+			return fmt.Errorf("unsupported list type %s", f.Type())
+		}
+	case reflect.Map:
+		if f.Type() != reflect.TypeOf(map[string]float64(nil)) {
+			return fmt.Errorf("unsupported map type %s", f.Type())
+		}
+		var m paramFlag
+		if err := m.Set(value); err != nil {
+			return err
+		}
+		f.Set(reflect.ValueOf(map[string]float64(m)))
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+	return nil
+}
+
+// Value of -name (or --name) in args, if given
+func scanFlag(args []string, name string) (string, bool) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		a = strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		if a == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if value, ok := strings.CutPrefix(a, name+"="); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
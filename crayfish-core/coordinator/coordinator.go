@@ -0,0 +1,659 @@
+// Package coordinator runs a distributed COA in synchronization epochs: every epoch it dispatches
+// the K sub-populations to the workers through a broker, gathers their results, recomputes the
+// global best and hands it to every sub-population for the next epoch. The islands thereby share
+// their best position the way the goroutines of concurrent-crayfish.go do, instead of K isolated runs.
+//
+// In asynchronous mode there are no epochs: the K workers run all iterations at their own pace and
+// share the global best through a globalbest.Store, so a slow worker never holds the others up.
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"crayfish/benchmarks"
+	"crayfish/broker"
+	"crayfish/coa"
+	"crayfish/globalbest"
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/tracing"
+	"crayfish/wire"
+
+	"github.com/rs/xid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config of a coordinated run
+type Config struct {
+	JobID       string // Generated when empty
+	Function    string
+	N, K, T     int         // Population, sub-populations, iterations
+	Dim         int         // The benchmark's when 0
+	LB, UB      []float64   // The benchmark's when empty
+	EpochLength int         // Iterations between synchronizations, the whole run in one epoch when 0
+	Seed        int64       // Seed of the initial population
+	Init        string      // Initializer of the initial population, see coa.NewInitializer
+	WarmStart   [][]float64 // First crayfish of the initial population, shipped to the workers
+
+	Algorithm string      // wire.AlgorithmCOA when empty
+	Variant   string      // coa.VariantCOA when empty
+	Params    wire.Params // Over coa.DefaultParams, by name
+
+	RecordStages bool // Have the workers record what every iteration did, see Summary.Stages
+
+	// Have the workers record the diversity of their islands after every iteration, and measure the
+	// whole population after every epoch, see Summary.Diversity. The whole population is measured
+	// anyway when the param min_diversity is set: the run ends once it collapses.
+	RecordDiversity bool
+
+	Restart coa.Restart // What the islands do once they stagnate or collapse, nothing when empty
+
+	Constraints coa.Constraints // How the islands handle the constraints of a constrained benchmark
+
+	// Pareto archive of a multi-objective benchmark, of every island and of the coordinator that
+	// merges theirs after every epoch and hands the result to the next one, see Summary.Front
+	Pareto coa.Pareto
+
+	// Asynchronous mode: Chunk iterations between pulls of the global best from Best, and pulls
+	// within a chunk once a worker's copy is older than MaxAge (no bound when 0)
+	Async  bool
+	Chunk  int
+	MaxAge time.Duration
+	Best   globalbest.Store
+
+	// Stragglers: a task without a result after TaskTimeout is sent again, up to Retries times, and
+	// given up after that. A task running longer than the SpeculatePercentile (e.g. 0.9) of the
+	// latencies seen so far gets one speculative duplicate. The first result of a sub-population
+	// wins. Up to MaxMissing (fewer than K) given-up sub-populations end the epoch degraded instead of
+	// failing it.
+	TaskTimeout         time.Duration
+	Retries             int
+	SpeculatePercentile float64
+	MaxMissing          int
+}
+
+// Summary of a finished run
+type Summary struct {
+	JobID          string
+	BestPosition   []float64
+	BestFitness    float64
+	BestViolation  float64       // Constraint violation of BestPosition (constrained benchmarks), 0 when feasible
+	Front          *coa.Front    // Multi-objective benchmark: the merged archive, BestPosition is its compromise
+	GlobalConverge []float64     // Best fitness of every iteration over all islands
+	Stages         [][]coa.Stage // Per island, every iteration it ran, when Config.RecordStages
+
+	// The whole population after every epoch when it is measured (not in asynchronous mode), and
+	// every island after every iteration it ran when Config.RecordDiversity
+	Diversity       []coa.Diversity
+	IslandDiversity [][]coa.Diversity
+	Collapsed       bool                 // The run ended before T because the population collapsed (min_diversity)
+	Restarts        [][]coa.RestartEvent // Per island, when Config.Restart
+	Epochs          int                  // 0 in asynchronous mode
+	Elapsed         time.Duration
+
+	// Asynchronous mode: how stale the workers' copies of the global best were, over all workers
+	Staleness *globalbest.Staleness
+
+	Degraded   []Degraded // Epochs that ended without some sub-populations
+	Resent     int        // Tasks sent again after TaskTimeout
+	Speculated int        // Speculative duplicates sent
+}
+
+// Degraded epoch: the sub-populations that were given up
+type Degraded struct {
+	Epoch   int
+	Missing []int
+}
+
+// Coordinator keeps the state of the run between epochs
+type Coordinator struct {
+	cfg     Config
+	b       broker.Broker
+	results <-chan broker.ResultDelivery
+	replyTo string       // Where the job's results go, when the broker gives them their own destination
+	log     *slog.Logger // With the job's ID
+
+	subs        [][][]float64      // Current sub-populations
+	states      []*wire.EpochState // ... and the state their optimizers ended the last epoch with
+	bestPos     []float64
+	bestFitness float64
+	bestViol    float64                    // Constraint violation of bestPos
+	problem     benchmarks.Constrained     // Constraints of the benchmark, nil when it has none
+	multi       *benchmarks.MultiObjective // Objectives of a multi-objective benchmark, nil when it has one
+	archive     *coa.Archive               // ... and the merged archive of the islands
+	globalCov   []float64
+	stages      [][]coa.Stage
+
+	params          coa.Params // Parsed cfg.Params, for the diversity threshold
+	maxSpread       float64    // Largest Diversity.Spread of the whole population
+	diversity       []coa.Diversity
+	islandDiversity [][]coa.Diversity
+	collapsed       bool
+	restarts        [][]coa.RestartEvent
+	staleness       globalbest.Staleness
+
+	inflight   map[int]*inflight // Tasks of the current epoch without a result
+	latencies  []time.Duration   // From the first send to the first result, over the whole run
+	degraded   []Degraded
+	resent     int
+	speculated int
+
+	initStart, initEnd time.Time // Drawing the initial population, traced once the run starts
+}
+
+// New prepares a run, the initial population is drawn from cfg.Seed so the first epoch can send
+// seed tasks instead of the crayfish
+func New(b broker.Broker, cfg Config) (*Coordinator, error) {
+	initStart := time.Now()
+	specs, err := benchmarks.Get(cfg.Function)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.JobID == "" {
+		cfg.JobID = xid.New().String()
+	}
+	cfg.Dim = specs.Dimension(cfg.Dim)
+	if len(cfg.LB) == 0 {
+		cfg.LB, cfg.UB = specs.LB, specs.UB
+	}
+	if cfg.EpochLength <= 0 || cfg.EpochLength > cfg.T {
+		cfg.EpochLength = cfg.T
+	}
+	if cfg.K <= 0 || cfg.N < cfg.K || cfg.T <= 0 {
+		return nil, fmt.Errorf("coordinator: need 0 < K <= N and T > 0 (got N=%d K=%d T=%d)", cfg.N, cfg.K, cfg.T)
+	}
+	if cfg.MaxMissing < 0 || cfg.MaxMissing >= cfg.K {
+		return nil, fmt.Errorf("coordinator: an epoch needs a result, MaxMissing must be in [0, K) (got %d for K=%d)", cfg.MaxMissing, cfg.K)
+	}
+	if cfg.SpeculatePercentile < 0 || cfg.SpeculatePercentile >= 1 {
+		return nil, fmt.Errorf("coordinator: speculation percentile %g is not in [0, 1)", cfg.SpeculatePercentile)
+	}
+	if cfg.Async && cfg.Best == nil {
+		return nil, fmt.Errorf("coordinator: asynchronous mode needs a global best store")
+	}
+	if cfg.Algorithm != "" && cfg.Algorithm != wire.AlgorithmCOA {
+		return nil, fmt.Errorf("coordinator: unknown algorithm %q", cfg.Algorithm)
+	}
+	if err := coa.ValidateVariant(cfg.Variant); err != nil {
+		return nil, err
+	}
+	params, err := coa.ParseParams(cfg.Params) // Better here than in every worker
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Restart.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Constraints.Validate(); err != nil {
+		return nil, err
+	}
+	multi, isMulti := benchmarks.GetMulti(cfg.Function)
+	if isMulti {
+		if err := cfg.Pareto.Validate(); err != nil {
+			return nil, err
+		}
+		if cfg.Async || cfg.Restart.Strategy != "" || (cfg.Variant != "" && cfg.Variant != coa.VariantCOA) {
+			return nil, fmt.Errorf("coordinator: multi-objective benchmark %s runs in epochs, without variants or restarts", cfg.Function)
+		}
+	}
+
+	initializer, err := coa.NewInitializer(cfg.Init, cfg.Seed, specs.Function, cfg.WarmStart)
+	if err != nil {
+		return nil, err
+	}
+	X, err := initializer.Initialize(cfg.N, cfg.Dim, cfg.LB, cfg.UB)
+	if err != nil {
+		return nil, err
+	}
+	c := &Coordinator{
+		cfg:         cfg,
+		b:           b,
+		log:         logging.ForJob(cfg.JobID),
+		subs:        coa.DividePopulation(X, cfg.K),
+		states:      make([]*wire.EpochState, cfg.K),
+		bestPos:     make([]float64, cfg.Dim),
+		bestFitness: math.Inf(1),
+		globalCov:   make([]float64, cfg.T),
+		params:      params,
+		initStart:   initStart,
+	}
+	if cfg.RecordDiversity {
+		c.islandDiversity = make([][]coa.Diversity, cfg.K)
+	}
+	if cfg.Restart.Strategy != "" {
+		c.restarts = make([][]coa.RestartEvent, cfg.K)
+	}
+	if c.measuring() { // Exploration is relative to the initial population
+		c.maxSpread = coa.MeasureDiversity(X, cfg.LB, cfg.UB).Spread
+	}
+	if cfg.RecordStages {
+		c.stages = make([][]coa.Stage, cfg.K)
+	}
+	for t := range c.globalCov {
+		c.globalCov[t] = math.Inf(1)
+	}
+	c.problem, _ = benchmarks.GetConstrained(cfg.Function)
+	if isMulti { // The archive starts with the non-dominated crayfish of the initial population
+		c.multi, c.archive = &multi, coa.NewArchive(cfg.Pareto)
+		for _, x := range X {
+			c.archive.Add(x, multi.Objectives(x))
+		}
+	}
+	c.bestViol = math.Inf(1)
+	for _, x := range X { // Global best of the initial population
+		if f, v := specs.Function(x), c.violation(x); coa.Better(f, v, c.bestFitness, c.bestViol) {
+			c.bestFitness, c.bestViol = f, v
+			copy(c.bestPos, x)
+		}
+	}
+	c.initEnd = time.Now()
+	return c, nil
+}
+
+// Run executes all epochs and returns the merged result
+func Run(ctx context.Context, b broker.Broker, cfg Config) (Summary, error) {
+	c, err := New(b, cfg)
+	if err != nil {
+		return Summary{}, err
+	}
+	return c.Run(ctx)
+}
+
+// Run executes all epochs and returns the merged result
+func (c *Coordinator) Run(ctx context.Context) (Summary, error) {
+	kickStart := time.Now()
+
+	// The job's trace starts with the initial population, drawn in New
+	ctx, span := tracing.Start(ctx, "job", trace.WithTimestamp(c.initStart), trace.WithAttributes(
+		attribute.String("job.id", c.cfg.JobID),
+		attribute.String("benchmark", c.cfg.Function),
+		attribute.Int("population.size", c.cfg.N),
+		attribute.Int("subpopulations", c.cfg.K),
+		attribute.Int("iterations", c.cfg.T),
+		attribute.Bool("async", c.cfg.Async),
+	))
+	defer span.End()
+	_, initSpan := tracing.Start(ctx, "population.init", trace.WithTimestamp(c.initStart),
+		trace.WithAttributes(attribute.Int("dim", c.cfg.Dim), attribute.Int64("seed", c.cfg.Seed)))
+	initSpan.End(trace.WithTimestamp(c.initEnd))
+
+	// Stop consuming when the run is over, so a later run on the same broker gets its results
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// The job's best is in its summary, the gauge would only grow with every job of the process
+	defer metrics.BestFitness.DeleteLabelValues(c.cfg.JobID)
+
+	results, err := c.consumeResults(ctx)
+	if err != nil {
+		return Summary{}, err
+	}
+	c.results = results
+
+	if c.cfg.Async {
+		summary, err := c.runAsync(ctx, kickStart)
+		return summary, tracing.Fail(span, err)
+	}
+
+	epoch := 0
+	for start := 0; start < c.cfg.T; start += c.cfg.EpochLength {
+		iterations := min(c.cfg.EpochLength, c.cfg.T-start)
+		if err := c.epoch(ctx, epoch, start, iterations); err != nil {
+			return Summary{}, tracing.Fail(span, err)
+		}
+
+		c.log.Info("Epoch done", logging.KeyEpoch, epoch, "first_iteration", start, "last_iteration", start+iterations-1,
+			"best_fitness", c.bestFitness, "front", c.frontSize())
+		epoch++
+		if c.measuring() && c.measure(start+iterations-1) {
+			c.log.Info("Population collapsed, ending the run", logging.KeyEpoch, epoch-1, "diversity", c.diversity[len(c.diversity)-1].Relative)
+			for t := start + iterations; t < c.cfg.T; t++ {
+				c.globalCov[t] = c.globalCov[start+iterations-1]
+			}
+			break
+		}
+	}
+
+	return Summary{
+		JobID:           c.cfg.JobID,
+		BestPosition:    c.bestPos,
+		BestFitness:     c.bestFitness,
+		BestViolation:   c.bestViol,
+		Front:           c.front(),
+		GlobalConverge:  c.globalCov,
+		Stages:          c.stages,
+		Diversity:       c.diversity,
+		IslandDiversity: c.islandDiversity,
+		Collapsed:       c.collapsed,
+		Restarts:        c.restarts,
+		Epochs:          epoch,
+		Elapsed:         time.Since(kickStart),
+		Degraded:        c.degraded,
+		Resent:          c.resent,
+		Speculated:      c.speculated,
+	}, nil
+}
+
+// Publish the K asynchronous tasks at once and wait for the workers to finish
+func (c *Coordinator) runAsync(ctx context.Context, kickStart time.Time) (Summary, error) {
+	// The initial best is there for the first pulls, unless it violates the constraints (the store
+	// only compares fitness)
+	if c.bestViol == 0 {
+		if _, _, err := c.cfg.Best.Offer(ctx, c.cfg.JobID, c.bestPos, c.bestFitness); err != nil {
+			return Summary{}, fmt.Errorf("coordinator: storing the initial global best: %w", err)
+		}
+	}
+	if err := c.epoch(ctx, 0, 0, 0); err != nil {
+		return Summary{}, err
+	}
+
+	c.log.Info("Asynchronous run done", "best_fitness", c.bestFitness, "pulls", c.staleness.Refreshes,
+		"missed_per_pull", c.staleness.MeanMissed(), "max_missed", c.staleness.MaxMissed, "max_age", c.staleness.MaxAge)
+
+	staleness := c.staleness
+	return Summary{
+		JobID:           c.cfg.JobID,
+		BestPosition:    c.bestPos,
+		BestFitness:     c.bestFitness,
+		BestViolation:   c.bestViol,
+		GlobalConverge:  c.globalCov,
+		Stages:          c.stages,
+		Diversity:       c.diversity,
+		IslandDiversity: c.islandDiversity,
+		Collapsed:       c.collapsed,
+		Restarts:        c.restarts,
+		Elapsed:         time.Since(kickStart),
+		Staleness:       &staleness,
+		Degraded:        c.degraded,
+		Resent:          c.resent,
+		Speculated:      c.speculated,
+	}, nil
+}
+
+// The merged archive measured against the benchmark, nil for a single objective
+func (c *Coordinator) front() *coa.Front {
+	if c.archive == nil {
+		return nil
+	}
+	return c.archive.Front(*c.multi)
+}
+
+// Members of the merged archive, 0 for a single objective
+func (c *Coordinator) frontSize() int {
+	if c.archive == nil {
+		return 0
+	}
+	return len(c.archive.Objectives)
+}
+
+// Constraint violation of x, 0 for benchmarks without constraints
+func (c *Coordinator) violation(x []float64) float64 {
+	if c.problem == nil {
+		return 0
+	}
+	return c.cfg.Constraints.Violation(c.problem, x)
+}
+
+// Run one epoch (the whole run in asynchronous mode): send the tasks, then gather and merge the results
+func (c *Coordinator) epoch(ctx context.Context, epoch, start, iterations int) error {
+	ctx, span := tracing.Start(ctx, "epoch", trace.WithAttributes(
+		attribute.Int("epoch", epoch), attribute.Int("start", start), attribute.Int("iterations", iterations)))
+	defer span.End()
+
+	if err := c.dispatch(ctx, epoch, start, iterations); err != nil {
+		return tracing.Fail(span, err)
+	}
+
+	ctx, aggregation := tracing.Start(ctx, "aggregation")
+	defer aggregation.End()
+	gathered, err := c.gather(ctx, epoch, start, iterations)
+	if err != nil {
+		return tracing.Fail(aggregation, err)
+	}
+	c.merge(gathered, start)
+	aggregation.SetAttributes(attribute.Int("results", len(gathered)), attribute.Float64("best_fitness", c.bestFitness))
+	return nil
+}
+
+// Consume the results of the job: from a destination of its own where the broker has them (see
+// broker.JobResults), from the shared results otherwise
+func (c *Coordinator) consumeResults(ctx context.Context) (<-chan broker.ResultDelivery, error) {
+	jr, ok := c.b.(broker.JobResults)
+	if !ok {
+		return c.b.ConsumeResults(ctx)
+	}
+	results, replyTo, err := jr.ConsumeJobResults(ctx, c.cfg.JobID)
+	c.replyTo = replyTo
+	return results, err
+}
+
+// Task of the index-th sub-population for an epoch
+func (c *Coordinator) task(index, epoch, start, iterations int) wire.Task {
+	t := wire.Task{
+		JobID:          c.cfg.JobID,
+		Index:          index,
+		Workers:        c.cfg.K,
+		Function:       c.cfg.Function,
+		T:              c.cfg.T,
+		Seed:           c.cfg.Seed,
+		LB:             c.cfg.LB,
+		UB:             c.cfg.UB,
+		Epoch:          epoch,
+		Start:          start,
+		Iterations:     iterations,
+		GlobalPosition: c.bestPos,
+		GlobalFitness:  c.bestFitness,
+		State:          c.states[index],
+		Algorithm:      c.cfg.Algorithm,
+		Variant:        c.cfg.Variant,
+		Params:         c.cfg.Params,
+		Stages:         c.cfg.RecordStages,
+		Diversity:      c.cfg.RecordDiversity,
+		ReplyTo:        c.replyTo,
+	}
+	if c.cfg.Restart.Strategy != "" {
+		restart := wire.Restart(c.cfg.Restart)
+		left := restart.Max - len(c.restarts[index]) // Max is per run, the island's epochs share it
+		if restart.Max == 0 || left > 0 {
+			restart.Max = max(left, 0)
+			t.Restart = &restart
+		}
+	}
+	if c.problem != nil {
+		constraints := wire.Constraints(c.cfg.Constraints)
+		t.Constraints = &constraints
+	}
+	if c.archive != nil {
+		pareto := wire.Pareto(c.cfg.Pareto)
+		t.Pareto = &pareto
+		if epoch > 0 { // The islands start from the front of all of them
+			t.Front = &wire.Front{ // Merging changes the archive, not its rows
+				Positions:  slices.Clone(c.archive.Positions),
+				Objectives: slices.Clone(c.archive.Objectives),
+			}
+		}
+	}
+	if c.cfg.Async { // The workers pull the global best themselves
+		t.Mode = wire.ModeAsync
+		t.Chunk = c.cfg.Chunk
+		t.MaxAgeMs = c.cfg.MaxAge.Milliseconds()
+		t.Iterations, t.GlobalPosition, t.GlobalFitness = 0, nil, 0
+	}
+	if epoch == 0 && len(c.cfg.WarmStart) == 0 { // The workers regenerate the initial crayfish from the seed
+		t.Init = c.cfg.Init
+		t.PopulationSize = c.cfg.N
+		t.Size = len(c.subs[index])
+		t.Dim = c.cfg.Dim
+		t.Hash = wire.PopulationHash(c.subs[index])
+	} else {
+		t.SubPopulation = c.subs[index]
+	}
+	return t
+}
+
+// Send the K tasks of an epoch
+func (c *Coordinator) dispatch(ctx context.Context, epoch, start, iterations int) error {
+	c.inflight = make(map[int]*inflight, len(c.subs))
+	now := time.Now()
+	for i := range c.subs {
+		if err := c.send(ctx, i, epoch, start, iterations, "dispatch"); err != nil {
+			return fmt.Errorf("coordinator: publishing sub-population %d of epoch %d: %w", i, epoch, err)
+		}
+		c.inflight[i] = &inflight{first: now, sent: now, attempts: 1}
+	}
+	return nil
+}
+
+// Publish a task in its own span, the worker's span of the sub-population run is its child
+func (c *Coordinator) send(ctx context.Context, index, epoch, start, iterations int, reason string) error {
+	ctx, span := tracing.Start(ctx, "task.publish", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.Int("subpopulation", index), attribute.Int("epoch", epoch), attribute.String("reason", reason)))
+	defer span.End()
+	return tracing.Fail(span, c.b.PublishTask(ctx, c.task(index, epoch, start, iterations)))
+}
+
+// Wait for the K results of the epoch (fewer when sub-populations were given up). Results of other
+// jobs (on the shared results, from their workers' point of view already delivered) and stale or
+// duplicate ones of this job are dropped.
+func (c *Coordinator) gather(ctx context.Context, epoch, start, iterations int) (map[int]wire.Result, error) {
+	gathered := make(map[int]wire.Result, c.cfg.K)
+	givenUp := make(map[int]bool)
+
+	var check <-chan time.Time // Only watch for stragglers when asked to
+	if c.cfg.TaskTimeout > 0 || c.cfg.SpeculatePercentile > 0 {
+		ticker := time.NewTicker(stragglerCheck)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+
+	for len(gathered)+len(givenUp) < c.cfg.K {
+		var d broker.ResultDelivery
+		select {
+		case delivery, ok := <-c.results:
+			if !ok {
+				return nil, fmt.Errorf("coordinator: result stream closed during epoch %d: %w", epoch, ctx.Err())
+			}
+			d = delivery
+		case now := <-check:
+			if err := c.stragglers(ctx, now, epoch, start, iterations, givenUp); err != nil {
+				return nil, err
+			}
+			continue
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		r := d.Result
+		if r.JobID != c.cfg.JobID { // Given back, it would come round again to whichever coordinator is fastest
+			d.Ack()
+			continue
+		}
+		if _, dup := gathered[r.Index]; dup || r.Epoch != epoch || r.Index < 0 || r.Index >= c.cfg.K {
+			d.Ack() // A duplicate lost the race, the first result wins
+			continue
+		}
+		if !c.cfg.Async && len(r.Population) != len(c.subs[r.Index]) &&
+			!(c.cfg.Restart.Strategy == coa.RestartIPOP && len(r.Population) > len(c.subs[r.Index])) { // IPOP grows the islands
+			c.log.Warn("Dropping a result of the wrong size", logging.KeySubPopulation, r.Index, logging.KeyEpoch, epoch,
+				"crayfish", len(r.Population), "expected", len(c.subs[r.Index]))
+			d.Ack()
+			continue
+		}
+		gathered[r.Index] = r
+		delete(givenUp, r.Index) // Late, but still in time
+		if p := c.inflight[r.Index]; p != nil {
+			c.latencies = append(c.latencies, time.Since(p.first))
+			delete(c.inflight, r.Index)
+		}
+		d.Ack()
+	}
+
+	if len(givenUp) > 0 {
+		missing := make([]int, 0, len(givenUp))
+		for i := range givenUp {
+			missing = append(missing, i)
+		}
+		slices.Sort(missing)
+		c.degraded = append(c.degraded, Degraded{Epoch: epoch, Missing: missing})
+		c.log.Warn("Epoch degraded", logging.KeyEpoch, epoch, "missing", missing)
+	}
+	return gathered, nil
+}
+
+// Take over the evolved sub-populations, the new global best and the epoch's convergence
+func (c *Coordinator) merge(gathered map[int]wire.Result, start int) {
+	for i, r := range gathered {
+		if r.Population != nil {
+			c.subs[i] = r.Population
+		}
+		if r.State != nil {
+			c.states[i] = r.State
+		}
+		if r.Staleness != nil {
+			c.staleness.Add(globalbest.Staleness{
+				Refreshes:       r.Staleness.Refreshes,
+				ForcedRefreshes: r.Staleness.Forced,
+				Pushes:          r.Staleness.Pushes,
+				Missed:          r.Staleness.Missed,
+				MaxMissed:       r.Staleness.MaxMissed,
+				TotalAge:        time.Duration(r.Staleness.TotalAgeMs * float64(time.Millisecond)),
+				MaxAge:          time.Duration(r.Staleness.MaxAgeMs * float64(time.Millisecond)),
+			})
+		}
+		if c.archive != nil && r.Front != nil {
+			c.archive.Merge((*coa.Front)(r.Front))
+		}
+		if coa.Better(r.BestFitness, r.Violation, c.bestFitness, c.bestViol) {
+			c.bestFitness, c.bestViol = r.BestFitness, r.Violation
+			copy(c.bestPos, r.BestPosition)
+		}
+		for j, f := range r.GlobalConverge {
+			if t := start + j; t < len(c.globalCov) && f < c.globalCov[t] {
+				c.globalCov[t] = f
+			}
+		}
+		if c.stages != nil {
+			for _, st := range r.Stages {
+				c.stages[i] = append(c.stages[i], coa.Stage(st))
+			}
+		}
+		for _, e := range r.Restarts {
+			if c.restarts == nil {
+				break
+			}
+			c.log.Info("Island restarted", logging.KeySubPopulation, i, "iteration", e.Iteration, "reason", e.Reason,
+				"crayfish", e.Size, "best_fitness", e.BestFitness)
+			c.restarts[i] = append(c.restarts[i], coa.RestartEvent(e))
+		}
+		if c.islandDiversity != nil {
+			for _, d := range r.Diversity {
+				c.islandDiversity[i] = append(c.islandDiversity[i], coa.Diversity(d))
+			}
+		}
+	}
+	metrics.BestFitness.WithLabelValues(c.cfg.JobID).Set(c.bestFitness)
+}
+
+// Whether the whole population is measured after every epoch
+func (c *Coordinator) measuring() bool {
+	return !c.cfg.Async && (c.cfg.RecordDiversity || c.params.MinDiversity > 0)
+}
+
+// Measure the whole population after the given iteration, reports whether it collapsed
+func (c *Coordinator) measure(iteration int) bool {
+	var X [][]float64
+	for _, sub := range c.subs {
+		X = append(X, sub...)
+	}
+	d := coa.MeasureDiversity(X, c.cfg.LB, c.cfg.UB)
+	d.Iteration = iteration
+	d.Balance(&c.maxSpread)
+	c.diversity = append(c.diversity, d)
+	c.collapsed = c.params.Collapsed(d)
+	return c.collapsed
+}
@@ -0,0 +1,206 @@
+package coordinator
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crayfish/benchmarks"
+	"crayfish/broker"
+	"crayfish/handler"
+	"crayfish/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// A coordinated run end to end: the coordinator and K workers on the in-memory broker
+func run(t *testing.T, cfg Config, workers int) Summary {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	b := broker.NewMemory()
+	defer b.Close()
+	for i := 0; i < workers; i++ {
+		go handler.Work(ctx, b, handler.Env{T: cfg.T})
+	}
+	summary, err := Run(ctx, b, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+func TestRunEndToEnd(t *testing.T) {
+	cfg := Config{JobID: "e2e", Function: "F1", N: 20, K: 4, T: 60, Dim: 5, EpochLength: 20, Seed: 1}
+	summary := run(t, cfg, cfg.K)
+
+	if summary.Epochs != 3 || len(summary.GlobalConverge) != cfg.T || len(summary.Degraded) != 0 {
+		t.Errorf("epochs %d, convergence of %d iterations, degraded %v; want 3, %d, none",
+			summary.Epochs, len(summary.GlobalConverge), summary.Degraded, cfg.T)
+	}
+	specs, _ := benchmarks.Get("F1")
+	if f := specs.Function(summary.BestPosition); f != summary.BestFitness || math.IsInf(f, 0) {
+		t.Errorf("best fitness %g, F1 of the best position %g", summary.BestFitness, f)
+	}
+	if summary.BestFitness > summary.GlobalConverge[0] {
+		t.Errorf("best fitness %g worse than after the first iteration (%g)", summary.BestFitness, summary.GlobalConverge[0])
+	}
+
+	if metrics.BestFitness.DeleteLabelValues(cfg.JobID) {
+		t.Error("the job's best fitness gauge outlived the run")
+	}
+
+	// The islands draw from the job's seed: fewer workers take longer, not elsewhere
+	again := run(t, cfg, 1)
+	if again.BestFitness != summary.BestFitness || !reflect.DeepEqual(again.GlobalConverge, summary.GlobalConverge) {
+		t.Errorf("same seed, best fitness %g then %g", summary.BestFitness, again.BestFitness)
+	}
+}
+
+// Two jobs at once on one broker: each coordinator only sees its own results, none is handed the
+// other's over and over
+func TestConcurrentJobs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	b := broker.NewMemory()
+	defer b.Close()
+	for i := 0; i < 4; i++ {
+		go handler.Work(ctx, b, handler.Env{})
+	}
+	jobs := []Config{
+		{JobID: "a", Function: "F1", N: 12, K: 3, T: 40, Dim: 4, EpochLength: 10, Seed: 1},
+		{JobID: "b", Function: "F5", N: 12, K: 3, T: 40, Dim: 4, EpochLength: 10, Seed: 2},
+	}
+	summaries := make([]Summary, len(jobs))
+	errs := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	for i, cfg := range jobs {
+		i, cfg := i, cfg
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			summaries[i], errs[i] = Run(ctx, b, cfg)
+		}()
+	}
+	wg.Wait()
+
+	for i, cfg := range jobs {
+		if errs[i] != nil {
+			t.Fatalf("job %s: %v", cfg.JobID, errs[i])
+		}
+		alone := run(t, cfg, 1)
+		if summaries[i].BestFitness != alone.BestFitness || summaries[i].Epochs != 4 {
+			t.Errorf("job %s: best fitness %g in %d epochs, %g alone", cfg.JobID, summaries[i].BestFitness, summaries[i].Epochs, alone.BestFitness)
+		}
+	}
+}
+
+func TestNewMaxMissing(t *testing.T) {
+	tests := []struct {
+		maxMissing int
+		ok         bool
+	}{
+		{0, true},
+		{3, true},
+		{4, false}, // K: an epoch could end without any result
+		{5, false},
+		{-1, false},
+	}
+	for _, tt := range tests {
+		_, err := New(broker.NewMemory(), Config{Function: "F1", N: 8, K: 4, T: 10, MaxMissing: tt.maxMissing})
+		if (err == nil) != tt.ok {
+			t.Errorf("MaxMissing %d: error %v, want ok=%v", tt.maxMissing, err, tt.ok)
+		}
+	}
+}
+
+// Without workers every sub-population is given up: the epoch fails once more are missing than
+// MaxMissing allows, which New keeps below K so an epoch never ends with nothing gathered
+func TestEpochWithoutResults(t *testing.T) {
+	c, err := New(broker.NewMemory(), Config{Function: "F1", N: 4, K: 2, T: 10, Dim: 2, TaskTimeout: 10 * time.Millisecond, MaxMissing: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = c.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "of epoch 0 timed out after 1 attempts") {
+		t.Fatalf("Run: %v, want the epoch to fail", err)
+	}
+}
+
+// One trace per job across the hops: the coordinator's spans, the task crossing the broker to the
+// worker's run and the result coming back
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+	tracerProvider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(tracerProvider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	b := broker.NewMemory()
+	defer b.Close()
+	var workers sync.WaitGroup
+	workerCtx, stop := context.WithCancel(ctx)
+	for i := 0; i < 2; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			handler.Work(workerCtx, b, handler.Env{})
+		}()
+	}
+	if _, err := Run(ctx, b, Config{Function: "F1", N: 10, K: 2, T: 20, Dim: 3, EpochLength: 10, Seed: 1}); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+	workers.Wait() // Their spans end once they are back
+
+	spans := exporter.GetSpans()
+	byID := make(map[string]tracetest.SpanStub)
+	count := make(map[string]int)
+	for _, s := range spans {
+		byID[s.SpanContext.SpanID().String()] = s
+		count[s.Name]++
+	}
+	want := map[string]int{"job": 1, "population.init": 1, "epoch": 2, "aggregation": 2, "task.publish": 4, "subpopulation.run": 4, "result.publish": 4}
+	if !reflect.DeepEqual(count, want) {
+		t.Errorf("spans %v, want %v", count, want)
+	}
+
+	parents := map[string]string{
+		"population.init":   "job",
+		"epoch":             "job",
+		"aggregation":       "epoch",
+		"task.publish":      "epoch",
+		"subpopulation.run": "task.publish", // Across the broker, from the task's trace context
+		"result.publish":    "subpopulation.run",
+	}
+	traceID := spans[0].SpanContext.TraceID()
+	for _, s := range spans {
+		if s.SpanContext.TraceID() != traceID {
+			t.Errorf("%s is in trace %s, the job in %s", s.Name, s.SpanContext.TraceID(), traceID)
+		}
+		if want, ok := parents[s.Name]; ok {
+			if parent, found := byID[s.Parent.SpanID().String()]; !found || parent.Name != want {
+				t.Errorf("%s is a child of %q, want %q", s.Name, parent.Name, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"crayfish/logging"
+)
+
+// How often the coordinator looks for stragglers while gathering
+const stragglerCheck = 100 * time.Millisecond
+
+// A task of the current epoch still waiting for its result
+type inflight struct {
+	first, sent time.Time // First and latest send
+	attempts    int
+	speculated  bool
+}
+
+// Resend the tasks past TaskTimeout, give up the ones out of retries and send a speculative duplicate
+// of the ones slower than the percentile. Fails when more sub-populations are given up than allowed.
+func (c *Coordinator) stragglers(ctx context.Context, now time.Time, epoch, start, iterations int, givenUp map[int]bool) error {
+	slow := c.speculationThreshold()
+
+	for i, p := range c.inflight {
+		if givenUp[i] {
+			continue
+		}
+
+		if c.cfg.TaskTimeout > 0 && now.Sub(p.sent) > c.cfg.TaskTimeout {
+			if p.attempts > c.cfg.Retries {
+				givenUp[i] = true
+				if len(givenUp) > c.cfg.MaxMissing {
+					return fmt.Errorf("coordinator: sub-population %d of epoch %d timed out after %d attempts", i, epoch, p.attempts)
+				}
+				c.log.Warn("Giving up a sub-population", logging.KeySubPopulation, i, logging.KeyEpoch, epoch, "attempts", p.attempts)
+				continue
+			}
+			if err := c.send(ctx, i, epoch, start, iterations, "timeout"); err != nil {
+				return fmt.Errorf("coordinator: resending sub-population %d of epoch %d: %w", i, epoch, err)
+			}
+			c.log.Warn("Sub-population timed out, sent again", logging.KeySubPopulation, i, logging.KeyEpoch, epoch, "attempts", p.attempts+1)
+			p.sent = now
+			p.attempts++
+			c.resent++
+			continue
+		}
+
+		if slow > 0 && !p.speculated && now.Sub(p.first) > slow {
+			if err := c.send(ctx, i, epoch, start, iterations, "speculative"); err != nil {
+				return fmt.Errorf("coordinator: duplicating sub-population %d of epoch %d: %w", i, epoch, err)
+			}
+			c.log.Info("Sub-population is slow, sent a speculative duplicate", logging.KeySubPopulation, i, logging.KeyEpoch, epoch,
+				"slower_than", slow.Round(time.Millisecond))
+			p.speculated = true
+			c.speculated++
+		}
+	}
+	return nil
+}
+
+// Latency above which a task gets a speculative duplicate, 0 until half an epoch's worth of
+// latencies has been seen (or when speculation is off)
+func (c *Coordinator) speculationThreshold() time.Duration {
+	if c.cfg.SpeculatePercentile <= 0 || len(c.latencies) < max(c.cfg.K/2, 1) {
+		return 0
+	}
+	sorted := slices.Clone(c.latencies)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(c.cfg.SpeculatePercentile*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
@@ -0,0 +1,171 @@
+package coordinator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"crayfish/broker"
+	"crayfish/handler"
+)
+
+// A worker that gives every delivery to handle, with solve to solve it as a worker would: handle
+// drops it by acking it, or holds it
+type faultyWorker struct {
+	mu         sync.Mutex
+	deliveries map[int]int // Per sub-population
+}
+
+func (w *faultyWorker) run(t *testing.T, ctx context.Context, b broker.Broker, handle func(d broker.TaskDelivery, delivery int, solve func())) {
+	t.Helper()
+	w.deliveries = make(map[int]int)
+	tasks, err := b.ConsumeTasks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for d := range tasks {
+			d := d
+			w.mu.Lock()
+			w.deliveries[d.Task.Index]++
+			n := w.deliveries[d.Task.Index]
+			w.mu.Unlock()
+			go handle(d, n, func() { handler.Process(ctx, b, handler.Env{}, d) })
+		}
+	}()
+}
+
+func (w *faultyWorker) count(index int) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.deliveries[index]
+}
+
+// Run the job on the memory broker with the faulty worker
+func runFaulty(t *testing.T, cfg Config, w *faultyWorker, handle func(d broker.TaskDelivery, delivery int, solve func())) (Summary, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	b := broker.NewMemory()
+	defer b.Close()
+	w.run(t, ctx, b, handle)
+	return Run(ctx, b, cfg)
+}
+
+// The first delivery of every task is lost: each is sent again after the timeout, and the run ends
+// as if nothing happened
+func TestStragglerResend(t *testing.T) {
+	cfg := Config{JobID: "resend", Function: "F1", N: 8, K: 2, T: 20, Dim: 3, Seed: 1}
+	want := run(t, cfg, 1)
+
+	cfg.TaskTimeout, cfg.Retries = 200*time.Millisecond, 1
+	var w faultyWorker
+	summary, err := runFaulty(t, cfg, &w, func(d broker.TaskDelivery, delivery int, solve func()) {
+		if delivery == 1 {
+			d.Ack()
+			return
+		}
+		solve()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Resent != 2 || len(summary.Degraded) != 0 || w.count(0) != 2 || w.count(1) != 2 {
+		t.Errorf("resent %d, degraded %v, deliveries %d and %d; want 2, none, 2 and 2",
+			summary.Resent, summary.Degraded, w.count(0), w.count(1))
+	}
+	if summary.BestFitness != want.BestFitness || !reflect.DeepEqual(summary.GlobalConverge, want.GlobalConverge) {
+		t.Errorf("best fitness %g after the resends, %g without", summary.BestFitness, want.BestFitness)
+	}
+}
+
+// A sub-population that never comes back is sent Retries more times, then given up: the epoch goes
+// on without it when MaxMissing allows, the run fails otherwise
+func TestStragglerRetriesExhausted(t *testing.T) {
+	drop := func(d broker.TaskDelivery, _ int, solve func()) {
+		if d.Task.Index == 1 {
+			d.Ack()
+			return
+		}
+		solve()
+	}
+	cfg := Config{JobID: "exhausted", Function: "F1", N: 9, K: 3, T: 20, Dim: 3, EpochLength: 10, Seed: 2,
+		TaskTimeout: 150 * time.Millisecond, Retries: 1, MaxMissing: 1}
+
+	var w faultyWorker
+	summary, err := runFaulty(t, cfg, &w, drop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Degraded{{Epoch: 0, Missing: []int{1}}, {Epoch: 1, Missing: []int{1}}}; !reflect.DeepEqual(summary.Degraded, want) {
+		t.Errorf("degraded %v, want %v", summary.Degraded, want)
+	}
+	if summary.Resent != 2 || w.count(1) != 4 || w.count(0) != 2 {
+		t.Errorf("resent %d, deliveries of the lost sub-population %d (%d of another); want 2, 4 and 2", summary.Resent, w.count(1), w.count(0))
+	}
+	if summary.Epochs != 2 || len(summary.GlobalConverge) != cfg.T {
+		t.Errorf("%d epochs, %d iterations", summary.Epochs, len(summary.GlobalConverge))
+	}
+
+	cfg.MaxMissing = 0
+	_, err = runFaulty(t, cfg, &w, drop)
+	if err == nil || !strings.Contains(err.Error(), "sub-population 1 of epoch 0 timed out after 2 attempts") {
+		t.Errorf("without missing sub-populations allowed: %v, want the run to fail", err)
+	}
+}
+
+// A task much slower than the others gets a speculative duplicate, whose result ends the epoch while
+// the first copy is still running
+func TestStragglerSpeculation(t *testing.T) {
+	cfg := Config{JobID: "speculate", Function: "F1", N: 16, K: 4, T: 20, Dim: 3, Seed: 3}
+	want := run(t, cfg, 1)
+
+	cfg.SpeculatePercentile = 0.5
+	var w faultyWorker
+	summary, err := runFaulty(t, cfg, &w, func(d broker.TaskDelivery, delivery int, solve func()) {
+		if d.Task.Index == 0 && delivery == 1 {
+			return // Held until the run is over
+		}
+		solve()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Speculated < 1 || summary.Resent != 0 || w.count(0) != 2 {
+		t.Errorf("speculated %d, resent %d, deliveries of the slow sub-population %d; want a duplicate of it",
+			summary.Speculated, summary.Resent, w.count(0))
+	}
+	if summary.BestFitness != want.BestFitness {
+		t.Errorf("best fitness %g with the duplicate, %g without", summary.BestFitness, want.BestFitness)
+	}
+}
+
+func TestSpeculationThreshold(t *testing.T) {
+	ms := func(n ...int) []time.Duration {
+		var d []time.Duration
+		for _, v := range n {
+			d = append(d, time.Duration(v)*time.Millisecond)
+		}
+		return d
+	}
+	tests := []struct {
+		percentile float64
+		latencies  []time.Duration
+		want       time.Duration
+	}{
+		{0, ms(1, 2, 3, 4), 0}, // Off
+		{0.9, ms(5), 0},        // Too few latencies for K=4
+		{0.5, ms(40, 10), 10 * time.Millisecond},
+		{0.9, ms(10, 50, 20, 30, 40), 50 * time.Millisecond},
+		{0.5, ms(10, 50, 20, 30, 40), 30 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		c := &Coordinator{cfg: Config{K: 4, SpeculatePercentile: tt.percentile}, latencies: tt.latencies}
+		if got := c.speculationThreshold(); got != tt.want {
+			t.Errorf("percentile %g of %v: %v, want %v", tt.percentile, tt.latencies, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package globalbest shares the best position of an asynchronous distributed run: workers pull it
+// whenever they start a chunk of iterations and push their improvements right away, without waiting
+// for each other. Every improvement bumps a version, which tells a worker how stale its view was.
+package globalbest
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Best is the global best of a job as stored
+type Best struct {
+	Position []float64
+	Fitness  float64
+	Version  int64 // Number of improvements so far, 0 when nothing was offered yet
+	Updated  time.Time
+}
+
+// Store keeps the global best of every job
+type Store interface {
+	// Get returns the current best (Fitness is +Inf and Version 0 when there is none)
+	Get(ctx context.Context, jobID string) (Best, error)
+	// Offer replaces the best if the position is better and returns the best as it is afterwards
+	Offer(ctx context.Context, jobID string, position []float64, fitness float64) (Best, bool, error)
+}
+
+// Memory is a Store for in-process runs and tests
+type Memory struct {
+	mu   sync.Mutex
+	jobs map[string]Best
+}
+
+func NewMemory() *Memory {
+	return &Memory{jobs: make(map[string]Best)}
+}
+
+func (m *Memory) Get(ctx context.Context, jobID string) (Best, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.jobs[jobID]
+	if !ok {
+		return Best{Fitness: math.Inf(1)}, nil
+	}
+	b.Position = append([]float64(nil), b.Position...)
+	return b, nil
+}
+
+func (m *Memory) Offer(ctx context.Context, jobID string, position []float64, fitness float64) (Best, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.jobs[jobID]
+	improved := !ok || fitness < b.Fitness
+	if improved {
+		b = Best{
+			Position: append([]float64(nil), position...),
+			Fitness:  fitness,
+			Version:  b.Version + 1,
+			Updated:  time.Now(),
+		}
+		m.jobs[jobID] = b
+	}
+	b.Position = append([]float64(nil), b.Position...)
+	return b, improved, nil
+}
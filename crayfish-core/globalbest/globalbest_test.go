@@ -0,0 +1,178 @@
+package globalbest
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	if b, _ := m.Get(ctx, "job"); !math.IsInf(b.Fitness, 1) || b.Version != 0 || b.Position != nil {
+		t.Errorf("before any offer: %+v, want +Inf at version 0", b)
+	}
+
+	offers := []struct {
+		fitness  float64
+		improved bool
+		best     float64
+		version  int64
+	}{
+		{5, true, 5, 1},
+		{7, false, 5, 1}, // Worse
+		{5, false, 5, 1}, // Only as good
+		{2, true, 2, 2},
+		{3, false, 2, 2},
+	}
+	for _, o := range offers {
+		position := []float64{o.fitness, -o.fitness}
+		b, improved, err := m.Offer(ctx, "job", position, o.fitness)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if improved != o.improved || b.Fitness != o.best || b.Version != o.version || b.Position[0] != o.best {
+			t.Errorf("offering %g: %+v, improved %v; want %g at version %d, improved %v", o.fitness, b, improved, o.best, o.version, o.improved)
+		}
+		position[0] = 100 // The store keeps its own copy
+	}
+	b, _ := m.Get(ctx, "job")
+	if !reflect.DeepEqual(b.Position, []float64{2, -2}) || b.Fitness != 2 || b.Updated.IsZero() {
+		t.Errorf("best %+v, want [2 -2] at 2", b)
+	}
+	b.Position[0] = 100
+	if again, _ := m.Get(ctx, "job"); again.Position[0] != 2 {
+		t.Error("Get handed out the stored position")
+	}
+	if other, _ := m.Get(ctx, "other"); other.Version != 0 {
+		t.Errorf("another job: %+v, want nothing", other)
+	}
+}
+
+// However the offers interleave, the best of them ends up stored, with one version per improvement
+func TestMemoryConcurrentOffers(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	const workers, offers = 8, 200
+	var wg sync.WaitGroup
+	improvements := make([]int64, workers)
+	for w := 0; w < workers; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := offers; i > 0; i-- {
+				fitness := float64(i*workers + w)
+				b, improved, err := m.Offer(ctx, "job", []float64{fitness}, fitness)
+				if err != nil || b.Fitness > fitness {
+					t.Errorf("offering %g: best %g (%v)", fitness, b.Fitness, err)
+					return
+				}
+				if improved {
+					improvements[w]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var total int64
+	for _, n := range improvements {
+		total += n
+	}
+	b, _ := m.Get(ctx, "job")
+	if b.Fitness != workers || b.Position[0] != workers || b.Version != total {
+		t.Errorf("best %+v after %d improvements, want %d", b, total, workers)
+	}
+}
+
+func TestView(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	v := &View{Store: m, JobID: "job"}
+	other := &View{Store: m, JobID: "job"}
+
+	if _, err := v.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	other.Refresh(ctx)
+	if s := v.Stats(); s.Refreshes != 0 {
+		t.Errorf("the first pull counted: %+v", s)
+	}
+
+	if err := v.Push(ctx, []float64{1}, 10); err != nil {
+		t.Fatal(err)
+	}
+	v.Push(ctx, []float64{2}, 20) // Worse than the copy, not even offered
+	other.Push(ctx, []float64{3}, 8)
+	other.Push(ctx, []float64{4}, 6)
+	if v.Best.Fitness != 10 || v.Best.Version != 1 {
+		t.Errorf("after its own push: %+v, want 10 at version 1", v.Best)
+	}
+
+	// The other worker improved twice: missed at the next pull
+	if b, _ := v.Refresh(ctx); b.Fitness != 6 || b.Version != 3 {
+		t.Errorf("refreshed %+v, want 6 at version 3", b)
+	}
+	s := v.Stats()
+	if s.Refreshes != 1 || s.Pushes != 1 || s.Missed != 2 || s.MaxMissed != 2 || s.MeanMissed() != 2 {
+		t.Errorf("stats %+v, want 1 pull that missed 2 improvements and 1 push", s)
+	}
+
+	// Beating a stale copy but not the global best: the copy takes the global best
+	stale := &View{Store: m, JobID: "job", Best: Best{Fitness: 9, Version: 1}}
+	stale.Push(ctx, []float64{5}, 7)
+	if stale.Best.Fitness != 6 || stale.Best.Version != 1 || stale.Stats().Pushes != 0 {
+		t.Errorf("losing push: %+v, %+v", stale.Best, stale.Stats())
+	}
+
+	v.MaxAge = time.Hour
+	if _, ok, _ := v.RefreshIfExpired(ctx); ok || v.Expired() {
+		t.Error("refreshed a fresh copy")
+	}
+	v.MaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if _, ok, _ := v.RefreshIfExpired(ctx); !ok {
+		t.Error("kept an expired copy")
+	}
+	if s := v.Stats(); s.Refreshes != 2 || s.ForcedRefreshes != 1 || s.MaxAge < time.Millisecond || s.MeanAge() <= 0 {
+		t.Errorf("stats %+v, want a second, forced pull", s)
+	}
+	if (&View{}).Expired() {
+		t.Error("a view without MaxAge expired")
+	}
+}
+
+func TestStalenessAdd(t *testing.T) {
+	s := Staleness{Refreshes: 2, Pushes: 1, Missed: 4, MaxMissed: 3, TotalAge: 2 * time.Second, MaxAge: time.Second}
+	s.Add(Staleness{Refreshes: 2, Missed: 2, MaxMissed: 2, TotalAge: 6 * time.Second, MaxAge: 5 * time.Second, ForcedRefreshes: 1})
+	want := Staleness{Refreshes: 4, Pushes: 1, Missed: 6, MaxMissed: 3, TotalAge: 8 * time.Second, MaxAge: 5 * time.Second, ForcedRefreshes: 1}
+	if s != want || s.MeanMissed() != 1.5 || s.MeanAge() != 2*time.Second {
+		t.Errorf("%+v, want %+v", s, want)
+	}
+	if (Staleness{}).MeanMissed() != 0 || (Staleness{}).MeanAge() != 0 {
+		t.Error("means without pulls")
+	}
+}
+
+// The replies of HMGET and of the offer script, which hold strings or nil
+func TestParseBest(t *testing.T) {
+	b, err := parseBest([]interface{}{"0.25", "[1,-2.5]", "3", "1700000000000000000"})
+	want := Best{Position: []float64{1, -2.5}, Fitness: 0.25, Version: 3, Updated: time.Unix(0, 1700000000000000000)}
+	if err != nil || !reflect.DeepEqual(b, want) {
+		t.Errorf("%+v (%v), want %+v", b, err, want)
+	}
+
+	if b, err := parseBest([]interface{}{nil, nil, nil, nil}); err != nil || !math.IsInf(b.Fitness, 1) || b.Version != 0 {
+		t.Errorf("nothing offered: %+v (%v)", b, err)
+	}
+	if _, err := parseBest([]interface{}{"nope", "[1]", "1", "0"}); err == nil {
+		t.Error("accepted a fitness that isn't a number")
+	}
+	if _, err := parseBest([]interface{}{"1", "[1,", "1", "0"}); err == nil {
+		t.Error("accepted a broken position")
+	}
+}
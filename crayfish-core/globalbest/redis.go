@@ -0,0 +1,113 @@
+package globalbest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"crayfish/config"
+
+	"github.com/go-redis/redis"
+)
+
+// How long a job's best is kept after its last improvement
+const redisTTL = 24 * time.Hour
+
+// Replace the best only if the offered fitness is lower, atomically, and answer with the stored best.
+// The hash holds fitness, position (JSON), version and updated (unix nanoseconds).
+var offerScript = redis.NewScript(`
+local cur = redis.call('HGET', KEYS[1], 'fitness')
+local improved = 0
+if not cur or tonumber(ARGV[1]) < tonumber(cur) then
+	redis.call('HINCRBY', KEYS[1], 'version', 1)
+	redis.call('HMSET', KEYS[1], 'fitness', ARGV[1], 'position', ARGV[2], 'updated', ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], ARGV[4])
+	improved = 1
+end
+local b = redis.call('HMGET', KEYS[1], 'fitness', 'position', 'version', 'updated')
+return {improved, b[1], b[2], b[3], b[4]}
+`)
+
+// Redis keeps the global best of every job in the hash "crayfish:best:<job>"
+type Redis struct {
+	client redis.UniversalClient
+}
+
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client}
+}
+
+// DialRedis connects to the configured Redis
+func DialRedis(c config.Redis) (*Redis, error) {
+	client, err := c.Client()
+	if err != nil {
+		return nil, err
+	}
+	return NewRedis(client), nil
+}
+
+func key(jobID string) string {
+	return "crayfish:best:" + jobID
+}
+
+func (r *Redis) Get(ctx context.Context, jobID string) (Best, error) {
+	values, err := r.client.HMGet(key(jobID), "fitness", "position", "version", "updated").Result()
+	if err != nil {
+		return Best{}, err
+	}
+	return parseBest(values)
+}
+
+func (r *Redis) Offer(ctx context.Context, jobID string, position []float64, fitness float64) (Best, bool, error) {
+	pos, err := json.Marshal(position)
+	if err != nil {
+		return Best{}, false, err
+	}
+	res, err := offerScript.Run(r.client, []string{key(jobID)},
+		strconv.FormatFloat(fitness, 'g', -1, 64),
+		string(pos),
+		time.Now().UnixNano(),
+		redisTTL.Milliseconds(),
+	).Result()
+	if err != nil {
+		return Best{}, false, err
+	}
+
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 5 {
+		return Best{}, false, fmt.Errorf("globalbest: unexpected reply %v", res)
+	}
+	improved, _ := values[0].(int64)
+	b, err := parseBest(values[1:])
+	return b, improved == 1, err
+}
+
+// Parse fitness, position, version and updated as read from the hash (nil when missing)
+func parseBest(values []interface{}) (Best, error) {
+	b := Best{Fitness: math.Inf(1)}
+	fitness, ok := values[0].(string)
+	if !ok {
+		return b, nil // Nothing offered yet
+	}
+
+	var err error
+	if b.Fitness, err = strconv.ParseFloat(fitness, 64); err != nil {
+		return b, fmt.Errorf("globalbest: fitness: %w", err)
+	}
+	if pos, ok := values[1].(string); ok {
+		if err = json.Unmarshal([]byte(pos), &b.Position); err != nil {
+			return b, fmt.Errorf("globalbest: position: %w", err)
+		}
+	}
+	if v, ok := values[2].(string); ok {
+		b.Version, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if u, ok := values[3].(string); ok {
+		ns, _ := strconv.ParseInt(u, 10, 64)
+		b.Updated = time.Unix(0, ns)
+	}
+	return b, nil
+}
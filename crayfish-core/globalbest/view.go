@@ -0,0 +1,120 @@
+package globalbest
+
+import (
+	"context"
+	"time"
+)
+
+// View is a worker's copy of the global best. Staleness is measured every time the copy is
+// refreshed: how many improvements the worker missed and how old its copy was.
+type View struct {
+	Store  Store
+	JobID  string
+	MaxAge time.Duration // Refresh during a chunk when the copy gets older, never when 0
+
+	Best    Best
+	fetched time.Time
+	stats   Staleness
+}
+
+// Staleness sums up how stale a worker's views of the global best were
+type Staleness struct {
+	Refreshes       int           // Pulls of the global best
+	Pushes          int           // Improvements pushed
+	Missed          int64         // Improvements made elsewhere since the previous pull, summed
+	MaxMissed       int64         // ... the most at one pull
+	TotalAge        time.Duration // Age of the copy at each pull, summed
+	MaxAge          time.Duration // ... the oldest
+	ForcedRefreshes int           // Pulls during a chunk because the copy was older than the bound
+}
+
+// MeanMissed is the average number of improvements missed per pull
+func (s Staleness) MeanMissed() float64 {
+	if s.Refreshes == 0 {
+		return 0
+	}
+	return float64(s.Missed) / float64(s.Refreshes)
+}
+
+// MeanAge is the average age of the copy at a pull
+func (s Staleness) MeanAge() time.Duration {
+	if s.Refreshes == 0 {
+		return 0
+	}
+	return s.TotalAge / time.Duration(s.Refreshes)
+}
+
+// Add merges the staleness of another worker
+func (s *Staleness) Add(o Staleness) {
+	s.Refreshes += o.Refreshes
+	s.Pushes += o.Pushes
+	s.Missed += o.Missed
+	s.MaxMissed = max(s.MaxMissed, o.MaxMissed)
+	s.TotalAge += o.TotalAge
+	s.MaxAge = max(s.MaxAge, o.MaxAge)
+	s.ForcedRefreshes += o.ForcedRefreshes
+}
+
+// Refresh pulls the current global best
+func (v *View) Refresh(ctx context.Context) (Best, error) {
+	b, err := v.Store.Get(ctx, v.JobID)
+	if err != nil {
+		return v.Best, err
+	}
+	now := time.Now()
+	if !v.fetched.IsZero() { // The first pull has nothing to be stale against
+		age := now.Sub(v.fetched)
+		missed := max(b.Version-v.Best.Version, 0)
+		v.stats.Refreshes++
+		v.stats.Missed += missed
+		v.stats.MaxMissed = max(v.stats.MaxMissed, missed)
+		v.stats.TotalAge += age
+		v.stats.MaxAge = max(v.stats.MaxAge, age)
+	}
+	v.Best, v.fetched = b, now
+	return b, nil
+}
+
+// Expired reports whether the copy is older than MaxAge
+func (v *View) Expired() bool {
+	return v.MaxAge > 0 && time.Since(v.fetched) > v.MaxAge
+}
+
+// RefreshIfExpired pulls the global best when the copy is older than MaxAge, ok tells whether it did
+func (v *View) RefreshIfExpired(ctx context.Context) (b Best, ok bool, err error) {
+	if !v.Expired() {
+		return v.Best, false, nil
+	}
+	v.stats.ForcedRefreshes++
+	b, err = v.Refresh(ctx)
+	return b, err == nil, err
+}
+
+// Push offers a position if it beats the copy. When it becomes the global best the copy is
+// updated too, so the worker's own improvement doesn't count as missed at the next pull.
+func (v *View) Push(ctx context.Context, position []float64, fitness float64) error {
+	if fitness >= v.Best.Fitness {
+		return nil
+	}
+	b, improved, err := v.Store.Offer(ctx, v.JobID, position, fitness)
+	if err != nil {
+		return err
+	}
+	if improved {
+		v.stats.Pushes++
+		// Only our own improvement is accounted as seen, whatever came before it still counts
+		if b.Version == v.Best.Version+1 {
+			v.Best = b
+		} else {
+			v.Best.Position, v.Best.Fitness = b.Position, b.Fitness
+		}
+	} else { // Someone else got there first
+		v.Best.Position, v.Best.Fitness = b.Position, b.Fitness
+	}
+	return nil
+}
+
+// Stats returns the staleness measured so far
+func (v *View) Stats() Staleness {
+	return v.stats
+}
@@ -0,0 +1,828 @@
+// Package handler is the serverless entrypoint of a worker: one invocation receives one
+// sub-population task, runs COA on it and returns (and optionally publishes) the result.
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"crayfish/benchmarks"
+	"crayfish/broker"
+	"crayfish/checkpoint"
+	"crayfish/coa"
+	"crayfish/globalbest"
+	"crayfish/logging"
+	"crayfish/metrics"
+	"crayfish/tracing"
+	"crayfish/wire"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Largest request body accepted (a 60 x 500 sub-population is about 600 KB of JSON)
+const maxBodySize = 64 << 20
+
+// Iterations between pulls of the global best for asynchronous tasks that don't set them
+const defaultChunk = 10
+
+// ErrInvalidTask is wrapped by the errors of tasks that can't be run as sent (unknown benchmark or
+// algorithm, bad parameters, an epoch outside the run, a checkpoint of another benchmark...), as
+// opposed to the failures of the worker or of its stores
+var ErrInvalidTask = errors.New("handler: invalid task")
+
+type invalidTask struct{ error }
+
+func (e invalidTask) Is(target error) bool { return target == ErrInvalidTask }
+
+func (e invalidTask) Unwrap() error { return e.error }
+
+// Mark err as the task's fault
+func invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return invalidTask{err}
+}
+
+// A task ready to run: its benchmark, bounds, crayfish, parameters and random source
+type prepared struct {
+	specs     benchmarks.FunctionData
+	T         int
+	lb, ub    []float64
+	X         [][]float64
+	params    coa.Params
+	variant   string
+	seed      int64
+	rng       *rand.Rand
+	stages    bool // Record what every iteration did
+	diversity bool // ... and the diversity after it
+	restart   coa.Restart
+
+	problem     benchmarks.Constrained // Constraints of the benchmark, nil when it has none
+	constraints coa.Constraints
+
+	multi  *benchmarks.MultiObjective // Objectives of a multi-objective benchmark, nil when it has one
+	pareto coa.Pareto
+}
+
+// Check the task and regenerate or take over its sub-population
+func prepare(t wire.Task, T int) (prepared, error) {
+	var p prepared
+
+	if t.Algorithm != "" && t.Algorithm != wire.AlgorithmCOA {
+		return p, fmt.Errorf("handler: unknown algorithm %q", t.Algorithm)
+	}
+	params, err := coa.ParseParams(t.Params)
+	if err != nil {
+		return p, err
+	}
+	if err := coa.ValidateVariant(t.Variant); err != nil {
+		return p, err
+	}
+	if err := restart(t).Validate(); err != nil {
+		return p, err
+	}
+	if err := constraints(t).Validate(); err != nil {
+		return p, err
+	}
+	if err := pareto(t).Validate(); err != nil {
+		return p, err
+	}
+	specs, err := benchmarks.Get(t.Function)
+	if err != nil {
+		return p, err
+	}
+	var multi *benchmarks.MultiObjective
+	if m, ok := benchmarks.GetMulti(t.Function); ok {
+		if (t.Variant != "" && t.Variant != coa.VariantCOA) || t.Restart != nil {
+			return p, fmt.Errorf("handler: multi-objective benchmark %s has neither variants nor restarts", t.Function)
+		}
+		multi = &m
+	}
+	if t.T > 0 {
+		T = t.T
+	}
+	if T <= 0 {
+		return p, fmt.Errorf("handler: invalid number of iterations %d", T)
+	}
+
+	lb, ub := specs.LB, specs.UB
+	if len(t.LB) > 0 || len(t.UB) > 0 {
+		lb, ub = t.LB, t.UB
+	}
+
+	// The optimizer moves the crayfish in place, and a task sent again or speculatively shares its
+	// rows with the first copy (the memory broker passes them as they are)
+	X := make([][]float64, len(t.SubPopulation))
+	for i, x := range t.SubPopulation {
+		X[i] = append([]float64(nil), x...)
+	}
+	if len(X) == 0 { // Seed form: regenerate the crayfish here instead of shipping them
+		dim := specs.Dimension(t.Dim)
+		if err := checkBounds(lb, ub, dim); err != nil {
+			return p, err
+		}
+		if X, err = seededSubPopulation(t, dim, lb, ub, specs.Function); err != nil {
+			return p, err
+		}
+	}
+
+	dim := len(X[0])
+	for i := range X {
+		if len(X[i]) != dim || dim == 0 {
+			return p, fmt.Errorf("handler: crayfish %d has dimension %d, expected %d", i, len(X[i]), dim)
+		}
+	}
+	if err := checkBounds(lb, ub, dim); err != nil {
+		return p, err
+	}
+	if t.Hash != "" { // Make sure we start where the coordinator thinks we do
+		if hash := wire.PopulationHash(X); hash != t.Hash {
+			return p, fmt.Errorf("handler: sub-population %d hashes to %s, task says %s", t.Index, hash, t.Hash)
+		}
+	}
+
+	// Every sub-population (and epoch) of a seeded job gets its own stream
+	seed := t.Seed + int64(t.Epoch*max(t.Workers, 1)+t.Index)
+	if t.Seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	problem, _ := benchmarks.GetConstrained(t.Function)
+	return prepared{
+		specs:     specs,
+		T:         T,
+		lb:        lb,
+		ub:        ub,
+		X:         X,
+		params:    params,
+		variant:   t.Variant,
+		seed:      seed,
+		rng:       rand.New(rand.NewSource(seed)),
+		stages:    t.Stages,
+		diversity: t.Diversity,
+		restart:   restart(t),
+
+		problem:     problem,
+		constraints: constraints(t),
+
+		multi:  multi,
+		pareto: pareto(t),
+	}, nil
+}
+
+// The optimizer of the task, drawing from its random source
+func (p prepared) optimizer() *coa.Optimizer {
+	o := coa.NewOptimizer(p.T, p.lb, p.ub, p.X, p.specs.Function, p.rng)
+	o.Params = p.params
+	o.Variant = p.variant
+	o.RecordStages = p.stages
+	o.RecordDiversity = p.diversity
+	o.Restart = p.restart
+	o.Constrain(p.problem, p.constraints)
+	return o
+}
+
+// What every iteration of the optimizer did, nil when it didn't record it
+func stages(o *coa.Optimizer) []wire.Stage {
+	if !o.RecordStages {
+		return nil
+	}
+	return wireStages(o.Stages)
+}
+
+func wireStages(stages []coa.Stage) []wire.Stage {
+	s := make([]wire.Stage, len(stages))
+	for i, st := range stages {
+		s[i] = wire.Stage(st)
+	}
+	return s
+}
+
+// Pareto archive of the task, the defaults when it has none
+func pareto(t wire.Task) coa.Pareto {
+	if t.Pareto == nil {
+		return coa.Pareto{}
+	}
+	return coa.Pareto(*t.Pareto)
+}
+
+// Constraint handling of the task, Deb's rules when it has none
+func constraints(t wire.Task) coa.Constraints {
+	if t.Constraints == nil {
+		return coa.Constraints{}
+	}
+	return coa.Constraints(*t.Constraints)
+}
+
+// Restart policy of the task, none when it has none
+func restart(t wire.Task) coa.Restart {
+	if t.Restart == nil {
+		return coa.Restart{}
+	}
+	return coa.Restart(*t.Restart)
+}
+
+// The restarts of the optimizer, for the result
+func restarts(o *coa.Optimizer) []wire.RestartEvent {
+	var events []wire.RestartEvent
+	for _, e := range o.Restarts {
+		events = append(events, wire.RestartEvent(e))
+	}
+	return events
+}
+
+// The diversity after every iteration, nil when the optimizer didn't record it
+func diversity(o *coa.Optimizer) []wire.Diversity {
+	if !o.RecordDiversity {
+		return nil
+	}
+	d := make([]wire.Diversity, len(o.Diversity))
+	for i, div := range o.Diversity {
+		d[i] = wire.Diversity(div)
+	}
+	return d
+}
+
+// Run solves one sub-population task, T is used when the task doesn't set its own. Asynchronous
+// and checkpointed tasks need the stores of an Env, see Env.Solve.
+func Run(t wire.Task, T int) (wire.Result, error) {
+	var result wire.Result
+	if t.Mode == wire.ModeAsync {
+		return result, fmt.Errorf("handler: asynchronous task %d of job %q needs a global best store", t.Index, t.JobID)
+	}
+	if t.Checkpoint != "" {
+		return result, fmt.Errorf("handler: checkpointed task %d of job %q needs a checkpoint store", t.Index, t.JobID)
+	}
+
+	p, err := prepare(t, T)
+	if err != nil {
+		return result, invalid(err)
+	}
+	T = p.T
+	if p.multi != nil {
+		return runMulti(t, p)
+	}
+
+	if t.Iterations <= 0 { // The whole run at once
+		o := p.optimizer()
+		o.Run(T)
+		metrics.ObserveOptimizer(t.Function, o.Counters)
+		return wire.Result{
+			JobID:          t.JobID,
+			Index:          t.Index,
+			Workers:        t.Workers,
+			BestPosition:   o.BestPos,
+			BestFitness:    o.BestFitness,
+			Violation:      o.BestViolation,
+			GlobalConverge: o.GlobalCov,
+			Stages:         stages(o),
+			Diversity:      diversity(o),
+			Restarts:       restarts(o),
+			Collapsed:      o.Collapsed,
+		}, nil
+	}
+
+	// One epoch of a coordinated run
+	if t.Start < 0 || t.Start+t.Iterations > T {
+		return result, invalid(fmt.Errorf("handler: epoch [%d, %d) is outside the %d iterations", t.Start, t.Start+t.Iterations, T))
+	}
+	o := p.optimizer()
+	o.Iteration = t.Start
+	if t.State != nil {
+		o.ResumeEpoch(coa.EpochState(*t.State))
+	}
+	o.Share(t.GlobalPosition, t.GlobalFitness)
+	o.Run(t.Iterations)
+	metrics.ObserveOptimizer(t.Function, o.Counters)
+	state := wire.EpochState(o.EpochState())
+
+	return wire.Result{
+		JobID:          t.JobID,
+		Index:          t.Index,
+		Workers:        t.Workers,
+		Epoch:          t.Epoch,
+		BestPosition:   o.BestPos,
+		BestFitness:    o.BestFitness,
+		Violation:      o.BestViolation,
+		GlobalConverge: o.GlobalCov[t.Start : t.Start+t.Iterations], // Filled to the end when collapsed
+		Population:     o.X,
+		State:          &state,
+		Stages:         stages(o),
+		Diversity:      diversity(o),
+		Restarts:       restarts(o),
+		Collapsed:      o.Collapsed,
+	}, nil
+}
+
+// Solve a task of a multi-objective benchmark, the whole run or one epoch starting from the front of
+// all islands
+func runMulti(t wire.Task, p prepared) (wire.Result, error) {
+	o := coa.NewMultiOptimizer(p.T, p.lb, p.ub, p.X, p.multi.Objectives, p.pareto, p.rng)
+	o.Params = p.params
+	o.RecordStages = p.stages
+
+	start, iterations := 0, p.T
+	if t.Iterations > 0 {
+		if t.Start < 0 || t.Start+t.Iterations > p.T {
+			return wire.Result{}, invalid(fmt.Errorf("handler: epoch [%d, %d) is outside the %d iterations", t.Start, t.Start+t.Iterations, p.T))
+		}
+		start, iterations = t.Start, t.Iterations
+		o.Iteration = start
+		if t.Front != nil {
+			o.Share((*coa.Front)(t.Front))
+		}
+	}
+	o.Run(iterations)
+	metrics.ObserveOptimizer(t.Function, o.Counters)
+
+	best, fitness := o.BestPosition()
+	result := wire.Result{
+		JobID:          t.JobID,
+		Index:          t.Index,
+		Workers:        t.Workers,
+		BestPosition:   best,
+		BestFitness:    fitness,
+		GlobalConverge: o.GlobalCov[start : start+iterations],
+		Front:          &wire.Front{Positions: o.Archive.Positions, Objectives: o.Archive.Objectives},
+	}
+	if t.Iterations > 0 {
+		result.Epoch, result.Population = t.Epoch, o.X
+	}
+	if o.RecordStages {
+		result.Stages = wireStages(o.Stages)
+	}
+	return result, nil
+}
+
+// Env is what a worker brings to the tasks besides the task itself
+type Env struct {
+	T           int              // Iterations for tasks that don't set them
+	Best        globalbest.Store // Global best of asynchronous tasks
+	Checkpoints checkpoint.Store // Where checkpointed tasks save their state
+}
+
+// Solve solves a task of any mode, the result goes where the task says (wire.Task.ReplyTo)
+func (e Env) Solve(ctx context.Context, t wire.Task) (wire.Result, error) {
+	var r wire.Result
+	var err error
+	switch {
+	case t.Mode == wire.ModeAsync:
+		r, err = e.runAsync(ctx, t)
+	case t.Checkpoint != "":
+		r, err = e.runCheckpointed(ctx, t)
+	default:
+		r, err = Run(t, e.T)
+	}
+	r.ReplyTo = t.ReplyTo
+	return r, err
+}
+
+// Push the best position of the optimizer to the global best, unless it violates the constraints:
+// the store only compares fitness
+func push(ctx context.Context, view *globalbest.View, o *coa.Optimizer) error {
+	if o.BestViolation > 0 {
+		return nil
+	}
+	return view.Push(ctx, o.BestPos, o.BestFitness)
+}
+
+// Run all T iterations in chunks, pulling the job's global best from the store before each chunk
+// and pushing every improvement right away
+func (e Env) runAsync(ctx context.Context, t wire.Task) (wire.Result, error) {
+	var result wire.Result
+	store := e.Best
+	if store == nil {
+		return result, fmt.Errorf("handler: asynchronous task %d of job %q needs a global best store", t.Index, t.JobID)
+	}
+
+	p, err := prepare(t, e.T)
+	if err != nil {
+		return result, invalid(err)
+	}
+	if p.multi != nil {
+		return result, invalid(fmt.Errorf("handler: task %d of job %q: multi-objective runs can't be asynchronous", t.Index, t.JobID))
+	}
+	chunk := t.Chunk
+	if chunk <= 0 {
+		chunk = defaultChunk
+	}
+
+	o := p.optimizer()
+	defer func() { metrics.ObserveOptimizer(t.Function, o.Counters) }()
+	view := &globalbest.View{
+		Store:  store,
+		JobID:  t.JobID,
+		MaxAge: time.Duration(t.MaxAgeMs) * time.Millisecond,
+	}
+	if _, err := view.Refresh(ctx); err != nil {
+		return result, err
+	}
+	if err := push(ctx, view, o); err != nil { // Our initial best may already help the others
+		return result, err
+	}
+
+	for !o.Done() {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		best, err := view.Refresh(ctx)
+		if err != nil {
+			return result, err
+		}
+		o.Share(best.Position, best.Fitness)
+
+		for i := 0; i < chunk && !o.Done(); i++ {
+			if i > 0 { // Staleness bound: don't run on a copy older than MaxAge
+				best, refreshed, err := view.RefreshIfExpired(ctx)
+				if err != nil {
+					return result, err
+				}
+				if refreshed {
+					o.Share(best.Position, best.Fitness)
+				}
+			}
+			o.Step()
+			if err := push(ctx, view, o); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	stats := view.Stats()
+	return wire.Result{
+		JobID:          t.JobID,
+		Index:          t.Index,
+		Workers:        t.Workers,
+		BestPosition:   o.BestPos,
+		BestFitness:    o.BestFitness,
+		Violation:      o.BestViolation,
+		GlobalConverge: o.GlobalCov,
+		Stages:         stages(o),
+		Diversity:      diversity(o),
+		Restarts:       restarts(o),
+		Collapsed:      o.Collapsed,
+		Staleness: &wire.Staleness{
+			Refreshes:  stats.Refreshes,
+			Forced:     stats.ForcedRefreshes,
+			Pushes:     stats.Pushes,
+			Missed:     stats.Missed,
+			MaxMissed:  stats.MaxMissed,
+			TotalAgeMs: float64(stats.TotalAge) / float64(time.Millisecond),
+			MaxAgeMs:   float64(stats.MaxAge) / float64(time.Millisecond),
+		},
+	}, nil
+}
+
+// Run the whole task from its checkpoint (or from the start when there is none yet), saving the state
+// every CheckpointEvery iterations. Once BudgetMs is spent the state is saved and a partial result
+// returned; solving the same task again continues from there, exactly as if it had never stopped.
+func (e Env) runCheckpointed(ctx context.Context, t wire.Task) (wire.Result, error) {
+	var result wire.Result
+	if e.Checkpoints == nil {
+		return result, fmt.Errorf("handler: checkpointed task %d of job %q needs a checkpoint store", t.Index, t.JobID)
+	}
+	if t.Iterations > 0 {
+		return result, invalid(fmt.Errorf("handler: task %d of job %q: only whole runs can be checkpointed", t.Index, t.JobID))
+	}
+	specs, err := benchmarks.Get(t.Function)
+	if err != nil {
+		return result, invalid(err)
+	}
+	if _, ok := benchmarks.GetMulti(t.Function); ok {
+		return result, invalid(fmt.Errorf("handler: task %d of job %q: multi-objective runs can't be checkpointed", t.Index, t.JobID))
+	}
+
+	var o *coa.Optimizer
+	cp, err := e.Checkpoints.Load(ctx, t.Checkpoint)
+	switch {
+	case err == checkpoint.ErrNotFound: // First invocation
+		p, err := prepare(t, e.T)
+		if err != nil {
+			return result, invalid(err)
+		}
+		o = coa.NewSeededOptimizer(p.T, p.lb, p.ub, p.X, specs.Function, p.seed)
+		o.Params = p.params
+		o.Constrain(p.problem, p.constraints)
+	case err != nil:
+		return result, fmt.Errorf("handler: loading checkpoint %q: %w", t.Checkpoint, err)
+	case cp.Function != t.Function:
+		return result, invalid(fmt.Errorf("handler: checkpoint %q is a run of %s, task is %s", t.Checkpoint, cp.Function, t.Function))
+	default:
+		if o, err = coa.RestoreOptimizer(cp, specs.Function); err != nil {
+			return result, err
+		}
+		o.Problem, _ = benchmarks.GetConstrained(t.Function)
+		o.Constraints = constraints(t)
+		logging.ForTask(t).Info("Resuming from the checkpoint", "iteration", o.Iteration, "t", o.T)
+	}
+	o.RecordStages, o.RecordDiversity, o.Restart = t.Stages, t.Diversity, restart(t)
+	o.Variant = t.Variant
+	defer func() { metrics.ObserveOptimizer(t.Function, o.Counters) }() // This invocation's share
+
+	save := func() error {
+		cp, err := o.Checkpoint(t.Function)
+		if err != nil {
+			return err
+		}
+		if err := e.Checkpoints.Save(ctx, t.Checkpoint, cp); err != nil {
+			return fmt.Errorf("handler: saving checkpoint %q: %w", t.Checkpoint, err)
+		}
+		return nil
+	}
+
+	start := time.Now()
+	budget := time.Duration(t.BudgetMs) * time.Millisecond
+	for !o.Done() {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		o.Step()
+		if o.Done() {
+			break
+		}
+		if budget > 0 && time.Since(start) >= budget { // Out of time, the next invocation goes on
+			if err := save(); err != nil {
+				return result, err
+			}
+			return wire.Result{
+				JobID:          t.JobID,
+				Index:          t.Index,
+				Workers:        t.Workers,
+				BestPosition:   o.BestPos,
+				BestFitness:    o.BestFitness,
+				Violation:      o.BestViolation,
+				GlobalConverge: o.GlobalCov[:o.Iteration],
+				Partial:        true,
+				Iteration:      o.Iteration,
+				Stages:         stages(o),
+				Diversity:      diversity(o),
+				Restarts:       restarts(o),
+			}, nil
+		}
+		if t.CheckpointEvery > 0 && o.Iteration%t.CheckpointEvery == 0 {
+			if err := save(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := e.Checkpoints.Delete(ctx, t.Checkpoint); err != nil {
+		logging.ForTask(t).Warn("Deleting the checkpoint failed", "checkpoint", t.Checkpoint, "err", err)
+	}
+	return wire.Result{
+		JobID:          t.JobID,
+		Index:          t.Index,
+		Workers:        t.Workers,
+		BestPosition:   o.BestPos,
+		BestFitness:    o.BestFitness,
+		Violation:      o.BestViolation,
+		GlobalConverge: o.GlobalCov,
+		Iteration:      o.Iteration,
+		Stages:         stages(o),
+		Diversity:      diversity(o),
+		Restarts:       restarts(o),
+		Collapsed:      o.Collapsed,
+	}, nil
+}
+
+// Work consumes tasks from the broker, solves them and publishes the results until ctx is cancelled.
+// A task is acked once its result is published; a failing task is dropped (dead-lettered where the
+// transport can) and a failed publish gives the task back for another worker. A checkpointed task
+// that ran out of budget is published again, so it continues on the next free worker.
+func Work(ctx context.Context, b broker.Broker, env Env) error {
+	tasks, err := b.ConsumeTasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for d := range tasks {
+		Process(ctx, b, env, d)
+	}
+	return ctx.Err()
+}
+
+// Process solves one delivery, publishes its result and settles it as Work does, for consumers that
+// take their tasks themselves. It returns the outcome, see metrics.Tasks.
+func Process(ctx context.Context, b broker.Broker, env Env, d broker.TaskDelivery) string {
+	start := time.Now()
+	outcome := work(ctx, b, env, d)
+	metrics.Tasks.WithLabelValues(outcome).Inc()
+	metrics.TaskDuration.WithLabelValues(mode(d.Task)).Observe(time.Since(start).Seconds())
+	return outcome
+}
+
+// Solve one delivery and settle it, returns the outcome for the metrics
+func work(ctx context.Context, b broker.Broker, env Env, d broker.TaskDelivery) string {
+	start := time.Now()
+	ctx, span := startRun(ctx, d.Task, d.Trace)
+	defer span.End()
+	logger := logging.ForTask(d.Task)
+	logger.Debug("Task received", "benchmark", d.Task.Function, "mode", mode(d.Task), "crayfish", max(len(d.Task.SubPopulation), d.Task.Size))
+
+	result, err := env.Solve(ctx, d.Task)
+	if err != nil {
+		logger.Error("Task failed", "err", err)
+		tracing.Fail(span, err)
+		d.Nack(false)
+		return metrics.OutcomeFailed
+	}
+	span.SetAttributes(attribute.Float64("best_fitness", result.BestFitness), attribute.Bool("partial", result.Partial))
+	if result.Partial {
+		if err := b.PublishTask(ctx, d.Task); err != nil {
+			logger.Error("Requeueing the task failed", "err", err)
+			d.Nack(true) // The checkpoint is saved, whoever gets it next resumes from there
+			return metrics.OutcomeUnpublished
+		}
+		d.Ack()
+		logger.Info("Task checkpointed", "iteration", result.Iteration, "best_fitness", result.BestFitness)
+		return metrics.OutcomeCheckpointed
+	}
+	// The result may reach the coordinator twice (see broker.ResultOutbox), which keeps the first
+	publish, settle := b.PublishResult, d.Ack
+	if o, ok := b.(broker.ResultOutbox); ok { // A result kept for later is lost with this process, ack once it left
+		settle = func() error { return nil }
+		publish = func(ctx context.Context, r wire.Result) error {
+			return o.PublishResultThen(ctx, r, func(err error) {
+				if err != nil {
+					logger.Error("The result never left the outbox", "err", err)
+					d.Nack(true)
+					return
+				}
+				d.Ack()
+			})
+		}
+	}
+	if err := publishResult(ctx, result, publish); err != nil {
+		logger.Error("Publishing the result failed", "err", err)
+		d.Nack(true)
+		return metrics.OutcomeUnpublished
+	}
+	settle()
+	logger.Info("Task solved", "elapsed", time.Since(start), "best_fitness", result.BestFitness, "best_position", result.BestPosition)
+	return metrics.OutcomeSolved
+}
+
+// Whether this process has run a task already, the first one pays for the cold start
+var warm atomic.Bool
+
+// Trace a task's run as a child of the span that published it
+func startRun(ctx context.Context, t wire.Task, carrier map[string]string) (context.Context, trace.Span) {
+	if carrier == nil {
+		carrier = t.Trace
+	}
+	return tracing.Start(tracing.Extract(ctx, carrier), "subpopulation.run",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("job.id", t.JobID),
+			attribute.Int("subpopulation", t.Index),
+			attribute.Int("epoch", t.Epoch),
+			attribute.String("benchmark", t.Function),
+			attribute.String("mode", mode(t)),
+			attribute.Bool("cold_start", !warm.Swap(true)),
+		))
+}
+
+// Publish a result in its own span
+func publishResult(ctx context.Context, r wire.Result, publish func(context.Context, wire.Result) error) error {
+	ctx, span := tracing.Start(ctx, "result.publish", trace.WithSpanKind(trace.SpanKindProducer))
+	defer span.End()
+	return tracing.Fail(span, publish(ctx, r))
+}
+
+// Kind of task, for the metrics
+func mode(t wire.Task) string {
+	switch {
+	case t.Mode == wire.ModeAsync:
+		return wire.ModeAsync
+	case t.Checkpoint != "":
+		return "checkpointed"
+	case t.Iterations > 0:
+		return "epoch"
+	}
+	return "run"
+}
+
+// Regenerate the task's slice of the seeded population (see wire/SCHEMA.md), F is the benchmark for
+// the initializers that evaluate it
+func seededSubPopulation(t wire.Task, dim int, lb, ub []float64, F benchmarks.FunctionType) ([][]float64, error) {
+	initializer, err := coa.NewInitializer(t.Init, t.Seed, F, nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.PopulationSize <= 0 {
+		if t.Size <= 0 {
+			return nil, fmt.Errorf("handler: task has neither a sub-population nor a size")
+		}
+		return initializer.Initialize(t.Size, dim, lb, ub)
+	}
+
+	if t.Workers <= 0 || t.Index < 0 || t.Index >= t.Workers || t.Workers > t.PopulationSize {
+		return nil, fmt.Errorf("handler: sub-population %d of %d doesn't fit a population of %d", t.Index, t.Workers, t.PopulationSize)
+	}
+	var X [][]float64
+	if _, uniform := initializer.(coa.Uniform); uniform { // Only the task's rows of the stream
+		X = coa.SeededSubPopulation(t.Seed, t.PopulationSize, t.Workers, t.Index, dim, lb, ub)
+	} else { // The others need the whole population to draw a slice of it
+		all, err := initializer.Initialize(t.PopulationSize, dim, lb, ub)
+		if err != nil {
+			return nil, err
+		}
+		start, size := coa.SubPopulationRange(t.PopulationSize, t.Workers, t.Index)
+		X = all[start : start+size]
+	}
+	if t.Size > 0 && len(X) != t.Size {
+		return nil, fmt.Errorf("handler: sub-population %d has %d crayfish, task says %d", t.Index, len(X), t.Size)
+	}
+	return X, nil
+}
+
+// Bounds are one value for all dimensions or one per dimension
+func checkBounds(lb, ub []float64, dim int) error {
+	if len(lb) != len(ub) || (len(lb) != 1 && len(lb) != dim) {
+		return fmt.Errorf("handler: bounds of length %d/%d don't fit dimension %d", len(lb), len(ub), dim)
+	}
+	return nil
+}
+
+// Handler serves Run over HTTP: POST a task (JSON or MessagePack, per Content-Type) and get the
+// result back in the same encoding. It is what the serverless platform invokes.
+type Handler struct {
+	Env
+
+	// Publish, when set, also sends the result to the broker (e.g. broker.Broker.PublishResult)
+	Publish func(ctx context.Context, r wire.Result) error
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST a task", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if !wire.Supported(contentType) { // Missing or something like curl's form default
+		contentType = wire.Sniff(body)
+	}
+	task, err := wire.DecodeTask(body, contentType)
+	if err != nil {
+		metrics.DecodeErrors.WithLabelValues("http", "task").Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	ctx, span := startRun(r.Context(), task, tracing.FromHTTP(r.Header))
+	defer span.End()
+	result, err := h.Solve(ctx, task)
+	metrics.TaskDuration.WithLabelValues(mode(task)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		tracing.Fail(span, err)
+		metrics.Tasks.WithLabelValues(metrics.OutcomeFailed).Inc()
+		status := http.StatusInternalServerError // A store the handler can't reach, nothing the caller can fix
+		if errors.Is(err, ErrInvalidTask) {
+			status = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if result.Partial {
+		metrics.Tasks.WithLabelValues(metrics.OutcomeCheckpointed).Inc()
+	} else {
+		metrics.Tasks.WithLabelValues(metrics.OutcomeSolved).Inc()
+	}
+	logging.ForTask(task).Info("Task solved", "benchmark", task.Function, "elapsed", time.Since(start),
+		"best_fitness", result.BestFitness, "best_position", result.BestPosition)
+
+	if h.Publish != nil && !result.Partial { // Partial results go back to the caller only
+		if err := publishResult(ctx, result, h.Publish); err != nil {
+			http.Error(w, "publishing the result: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	// Answer in the request's encoding, legacy gob requests get JSON
+	if contentType != wire.ContentTypeMsgpack {
+		contentType = wire.ContentTypeJSON
+	}
+	out, err := wire.EncodeResult(result, contentType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out)
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"crayfish/benchmarks"
+	"crayfish/broker"
+	"crayfish/coa"
+	"crayfish/wire"
+)
+
+// A task published twice (resent or speculated) reaches two workers with the same rows: each has to
+// run on its own copy. Run with -race to catch them sharing the population.
+func TestDuplicateTask(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	specs, err := benchmarks.Get("F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	X := coa.SeededPopulation(1, 10, 5, specs.LB, specs.UB)
+	before := make([][]float64, len(X))
+	for i, x := range X {
+		before[i] = append([]float64(nil), x...)
+	}
+	task := wire.Task{JobID: "dup", Workers: 1, Function: "F1", T: 50, Seed: 7, SubPopulation: X}
+
+	b := broker.NewMemory()
+	defer b.Close()
+	results, err := b.ConsumeResults(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := b.PublishTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+		go Work(ctx, b, Env{T: 50})
+	}
+
+	var got []wire.Result
+	for len(got) < 2 {
+		select {
+		case d := <-results:
+			d.Ack()
+			got = append(got, d.Result)
+		case <-ctx.Done():
+			t.Fatalf("got %d results of 2: %v", len(got), ctx.Err())
+		}
+	}
+	if got[0].BestFitness != got[1].BestFitness || !reflect.DeepEqual(got[0].BestPosition, got[1].BestPosition) {
+		t.Errorf("the same task gave %g and %g", got[0].BestFitness, got[1].BestFitness)
+	}
+	if !reflect.DeepEqual(X, before) {
+		t.Error("the workers moved the crayfish of the published task")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	var published []wire.Result
+	h := &Handler{Env: Env{T: 30}, Publish: func(_ context.Context, r wire.Result) error {
+		published = append(published, r)
+		return nil
+	}}
+	task := wire.Task{JobID: "http", Index: 1, Workers: 2, Function: "F1", Seed: 3, Size: 6, Dim: 4, PopulationSize: 12}
+
+	for _, contentType := range []string{wire.ContentTypeJSON, wire.ContentTypeMsgpack} {
+		body, err := wire.EncodeTask(task, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != contentType {
+			t.Fatalf("%s: status %d, content type %q: %s", contentType, rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+		}
+		result, err := wire.DecodeResult(rec.Body.Bytes(), contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Run(task, 30)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.JobID != "http" || result.Index != 1 || result.BestFitness != want.BestFitness || len(result.GlobalConverge) != 30 {
+			t.Errorf("%s: result %+v, want the fitness %g of Run", contentType, result, want.BestFitness)
+		}
+	}
+	if len(published) != 2 {
+		t.Errorf("published %d results, want 2", len(published))
+	}
+
+	for _, tt := range []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"GET", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"garbage", http.MethodPost, "{not json", http.StatusBadRequest},
+		{"unknown benchmark", http.MethodPost, `{"version": 1, "function": "F99", "subPopulation": [[1, 2]]}`, http.StatusBadRequest},
+		{"epoch past the end", http.MethodPost, `{"version": 1, "function": "F1", "seed": 1, "size": 4, "dim": 2, "populationSize": 4, "start": 25, "iterations": 10}`, http.StatusBadRequest},
+		{"bad parameters", http.MethodPost, `{"version": 1, "function": "F1", "subPopulation": [[1, 2]], "variant": "pso"}`, http.StatusBadRequest},
+		// Valid tasks this handler has no store for
+		{"asynchronous", http.MethodPost, `{"version": 1, "function": "F1", "subPopulation": [[1, 2]], "mode": "async"}`, http.StatusInternalServerError},
+		{"checkpointed", http.MethodPost, `{"version": 1, "function": "F1", "subPopulation": [[1, 2]], "checkpoint": "c"}`, http.StatusInternalServerError},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, "/", bytes.NewBufferString(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+		}
+	}
+	if len(published) != 2 {
+		t.Errorf("the failed requests published results: %d in all", len(published))
+	}
+}
+
+// The stagnation count outlives the epoch: an island that can't beat the global best (F1's optimum)
+// restarts in its second epoch of 10 iterations with a stagnation of 15, not never
+func TestEpochStateAcrossEpochs(t *testing.T) {
+	task := wire.Task{
+		JobID: "epochs", Workers: 1, Function: "F1", T: 40, Seed: 5, Size: 8, Dim: 3, PopulationSize: 8,
+		Iterations:     10,
+		GlobalPosition: []float64{0, 0, 0},
+		Restart:        &wire.Restart{Strategy: coa.RestartElite, Stagnation: 15},
+	}
+	first, err := Run(task, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Restarts) != 0 || first.State == nil || first.State.Stagnant != 10 {
+		t.Fatalf("first epoch: restarts %v, state %+v; want none and 10 stagnant iterations", first.Restarts, first.State)
+	}
+
+	next := task
+	next.Epoch, next.Start = 1, 10
+	next.SubPopulation, next.Size, next.PopulationSize = first.Population, 0, 0
+	second, err := Run(next, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Restarts) != 0 {
+		t.Errorf("second epoch without the state: restarts %v, want none", second.Restarts)
+	}
+	next.State = first.State
+	second, err = Run(next, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Restarts) != 1 || second.Restarts[0].Iteration != 14 || second.Restarts[0].Reason != "stagnation" {
+		t.Errorf("second epoch: restarts %+v, want one for stagnation after iteration 14", second.Restarts)
+	}
+}
@@ -0,0 +1,148 @@
+// Package logging sets up the structured logs of every crayfish program (log/slog): a level, text or
+// JSON lines, and the ID of the worker on every record, so the logs of many workers can be merged and
+// filtered by job, sub-population or worker. The standard log package goes through the same handler.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strings"
+
+	"crayfish/wire"
+
+	"github.com/rs/xid"
+)
+
+// Keys of the correlation attributes
+const (
+	KeyJob           = "job"
+	KeySubPopulation = "subpopulation"
+	KeyEpoch         = "epoch"
+	KeyWorker        = "worker"
+)
+
+// Formats understood by Setup
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Longest vector logged in full, longer ones (and matrices) are summarized
+const MaxValues = 8
+
+// Config of the logs
+type Config struct {
+	Level  string `yaml:"level"`  // debug, info, warn or error
+	Format string `yaml:"format"` // text or json
+	Worker string `yaml:"worker"` // ID of this process in the logs, a new xid when empty
+}
+
+// DefaultConfig logs text from the info level up
+func DefaultConfig() Config {
+	return Config{Level: "info", Format: FormatText}
+}
+
+// RegisterFlags binds the configuration to -log-* flags
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Level, "log-level", c.Level, "lowest level logged: debug, info, warn or error")
+	fs.StringVar(&c.Format, "log-format", c.Format, "log lines as text or json")
+	fs.StringVar(&c.Worker, "worker-id", c.Worker, "ID of this process in the logs (default: a new xid)")
+}
+
+var worker = xid.New().String()
+
+// Worker is the ID of this process: the configured one once Setup ran, a fresh xid before
+func Worker() string {
+	return worker
+}
+
+// Setup makes the configured handler the default of slog and of the log package
+func Setup(c Config) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return fmt.Errorf("logging: level %q: %w", c.Level, err)
+	}
+	if c.Worker != "" {
+		worker = c.Worker
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: summarize}
+	var h slog.Handler
+	switch strings.ToLower(c.Format) {
+	case FormatText, "":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("logging: unknown format %q (text or json)", c.Format)
+	}
+	slog.SetDefault(slog.New(h).With(KeyWorker, worker))
+	return nil
+}
+
+// ForJob is the default logger with the job's ID
+func ForJob(jobID string) *slog.Logger {
+	return slog.With(KeyJob, jobID)
+}
+
+// ForTask is the default logger with the job, sub-population and epoch of a task
+func ForTask(t wire.Task) *slog.Logger {
+	return slog.With(KeyJob, t.JobID, KeySubPopulation, t.Index, KeyEpoch, t.Epoch)
+}
+
+// Replace vectors longer than MaxValues and matrices by a summary
+func summarize(groups []string, a slog.Attr) slog.Attr {
+	if a.Value.Kind() != slog.KindAny {
+		return a
+	}
+	switch v := a.Value.Any().(type) {
+	case []float64:
+		if len(v) > MaxValues {
+			a.Value = Vector(v).LogValue()
+		}
+	case [][]float64:
+		a.Value = Matrix(v).LogValue()
+	}
+	return a
+}
+
+// Vector logs as its length, range, mean and first values when it is long
+type Vector []float64
+
+func (v Vector) LogValue() slog.Value {
+	if len(v) <= MaxValues {
+		return slog.AnyValue([]float64(v))
+	}
+	lo, hi, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, x := range v {
+		lo, hi, sum = math.Min(lo, x), math.Max(hi, x), sum+x
+	}
+	return slog.GroupValue(
+		slog.Int("len", len(v)),
+		slog.Float64("min", lo),
+		slog.Float64("max", hi),
+		slog.Float64("mean", sum/float64(len(v))),
+		slog.Any("head", []float64(v[:3])),
+	)
+}
+
+// Matrix (a population) logs as its shape and the range of its values
+type Matrix [][]float64
+
+func (m Matrix) LogValue() slog.Value {
+	cols := 0
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range m {
+		cols = max(cols, len(row))
+		for _, x := range row {
+			lo, hi = math.Min(lo, x), math.Max(hi, x)
+		}
+	}
+	if len(m) == 0 || cols == 0 {
+		return slog.GroupValue(slog.Int("rows", len(m)), slog.Int("cols", cols))
+	}
+	return slog.GroupValue(slog.Int("rows", len(m)), slog.Int("cols", cols), slog.Float64("min", lo), slog.Float64("max", hi))
+}
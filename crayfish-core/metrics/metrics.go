@@ -0,0 +1,161 @@
+// Package metrics exposes what the optimizers, workers and brokers do as Prometheus metrics, served
+// on /metrics by every program given -metrics-addr (see Serve). The optimizers count their own work
+// (coa.Counters) and report it once a task is done, so the hot loops don't touch shared counters.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"crayfish/coa"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crayfish"
+
+var (
+	// Evaluations of the benchmark, per benchmark; rate() of it is the evaluations per second
+	Evaluations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "function_evaluations_total",
+		Help:      "Evaluations of the benchmark function.",
+	}, []string{"benchmark"})
+
+	// Iterations run, per benchmark; rate() of it is the iterations per second
+	Iterations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "iterations_total",
+		Help:      "COA iterations run, over all sub-populations.",
+	}, []string{"benchmark"})
+
+	// Stages counts the crayfish moves of every stage: summer (to the cave), competition or foraging
+	Stages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stage_moves_total",
+		Help:      "Crayfish moves per stage of COA (summer, competition, foraging).",
+	}, []string{"stage"})
+
+	// Restarts of the optimizers once their crayfish stagnated or collapsed, per benchmark
+	Restarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "restarts_total",
+		Help:      "Restarts of COA runs whose population stagnated or collapsed.",
+	}, []string{"benchmark"})
+
+	// BestFitness of every job the coordinator (or result consumer) knows about
+	BestFitness = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "best_fitness",
+		Help:      "Best fitness found so far, per job.",
+	}, []string{"job"})
+
+	// QueueDepth is the number of tasks waiting in the broker, sampled by WatchDepth
+	QueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "task_queue_depth",
+		Help:      "Tasks waiting in the broker (delivered but unacknowledged included where the broker tells).",
+	}, []string{"broker"})
+
+	// TaskDuration is the time a worker spends on a task, from delivery to ack
+	TaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Time from the delivery of a task to its acknowledgement.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10), // 1ms to about 4.4 minutes
+	}, []string{"mode"})
+
+	// Tasks handled by the workers, by outcome: solved, checkpointed, failed or unpublished
+	Tasks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_total",
+		Help:      "Tasks handled by the workers, by outcome.",
+	}, []string{"outcome"})
+
+	// Redeliveries counts the messages given back to the broker to be delivered again (or, for
+	// RabbitMQ, received with the redelivered flag)
+	Redeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redeliveries_total",
+		Help:      "Messages delivered again after a nack, a timeout or a lost connection.",
+	}, []string{"broker"})
+
+	// DecodeErrors counts the messages that couldn't be decoded (and were dead-lettered or dropped)
+	DecodeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decode_errors_total",
+		Help:      "Messages that could not be decoded.",
+	}, []string{"broker", "kind"})
+)
+
+// Task outcomes of Tasks
+const (
+	OutcomeSolved       = "solved"
+	OutcomeCheckpointed = "checkpointed"
+	OutcomeFailed       = "failed"
+	OutcomeUnpublished  = "unpublished"
+)
+
+// ObserveOptimizer adds the work counted by an optimizer of the benchmark
+func ObserveOptimizer(benchmark string, c coa.Counters) {
+	Evaluations.WithLabelValues(benchmark).Add(float64(c.Evaluations))
+	Iterations.WithLabelValues(benchmark).Add(float64(c.Iterations))
+	Stages.WithLabelValues("summer").Add(float64(c.Summer))
+	Stages.WithLabelValues("competition").Add(float64(c.Competition))
+	Stages.WithLabelValues("foraging").Add(float64(c.Foraging))
+	Restarts.WithLabelValues(benchmark).Add(float64(c.Restarts))
+}
+
+// Counted wraps F so that every evaluation is counted, for the optimizers that don't keep
+// coa.Counters (the scripts' own copies of COA)
+func Counted(benchmark string, F func([]float64) float64) func([]float64) float64 {
+	evaluations := Evaluations.WithLabelValues(benchmark)
+	return func(x []float64) float64 {
+		evaluations.Inc()
+		return F(x)
+	}
+}
+
+// WatchDepth samples the depth of the task queue every interval until ctx is cancelled
+func WatchDepth(ctx context.Context, broker string, every time.Duration, depth func(context.Context) (int, error)) {
+	gauge := QueueDepth.WithLabelValues(broker)
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		n, err := depth(ctx)
+		if err != nil {
+			slog.Warn("metrics: sampling the depth of the task queue failed", "broker", broker, "err", err)
+		} else {
+			gauge.Set(float64(n))
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Handler serves the metrics in the Prometheus format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve exposes /metrics on addr in the background, nothing when addr is empty
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	go func() {
+		slog.Info("Serving metrics", "url", "http://"+addr+"/metrics")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("metrics: serving failed", "err", err)
+		}
+	}()
+}
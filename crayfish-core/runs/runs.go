@@ -0,0 +1,260 @@
+// Package runs keeps the history of optimization runs in an embedded BoltDB file: what was run
+// (algorithm, benchmark, parameters, seed), what came out (best fitness and position, convergence
+// curve), how long it took and where, so past runs can be listed, filtered and compared. Runs are
+// stored as MessagePack (with the JSON field names), which keeps an Inf in a convergence curve.
+package runs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/vmihailenco/msgpack/v5"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("runs")
+
+// ErrNotFound is returned for unknown run IDs
+var ErrNotFound = errors.New("runs: no such run")
+
+// Run is one recorded optimization run
+type Run struct {
+	ID        string        `json:"id"` // xid, so IDs sort by start time
+	JobID     string        `json:"jobId,omitempty"`
+	Algorithm string        `json:"algorithm"`      // e.g. "COA"
+	Mode      string        `json:"mode,omitempty"` // e.g. "sync", "async"
+	Function  string        `json:"function"`
+	N         int           `json:"n"`
+	K         int           `json:"k,omitempty"`
+	T         int           `json:"t"`
+	Dim       int           `json:"dim"`
+	Seed      int64         `json:"seed"`
+	Params    Params        `json:"params,omitempty"` // Everything else the run was configured with
+	Started   time.Time     `json:"started"`
+	Elapsed   time.Duration `json:"elapsed"`
+	Host      string        `json:"host"`
+
+	BestFitness    float64   `json:"bestFitness"`
+	BestPosition   []float64 `json:"bestPosition"`
+	Violation      float64   `json:"violation,omitempty"` // Of the constraints at BestPosition, 0 when feasible
+	Front          *Front    `json:"front,omitempty"`     // Multi-objective benchmarks
+	GlobalConverge []float64 `json:"globalConverge"`
+	Degraded       bool      `json:"degraded,omitempty"`
+	Restarts       []Restart `json:"restarts,omitempty"`
+}
+
+// Restart of an island during the run
+type Restart struct {
+	Island      int     `json:"island"`
+	Iteration   int     `json:"iteration"` // Last iteration before the restart
+	Reason      string  `json:"reason"`    // "stagnation" or "diversity"
+	Size        int     `json:"size"`      // Crayfish after the restart
+	BestFitness float64 `json:"bestFitness"`
+}
+
+// Front is the Pareto front of a multi-objective run, by its quality
+type Front struct {
+	Size        int     `json:"size"`
+	Hypervolume float64 `json:"hypervolume"`
+	IGD         float64 `json:"igd"`
+}
+
+// Params are the other settings of a run, by flag name
+type Params map[string]string
+
+// A float that JSON can't hold (an Inf in a convergence curve still waiting for its first result,
+// the IGD of an empty front) is written as the string "+Inf", "-Inf" or "NaN"
+type number float64
+
+func (n number) MarshalJSON() ([]byte, error) {
+	f := float64(n)
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return json.Marshal(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return json.Marshal(f)
+}
+
+func numbers(fs []float64) []number {
+	if fs == nil {
+		return nil
+	}
+	ns := make([]number, len(fs))
+	for i, f := range fs {
+		ns[i] = number(f)
+	}
+	return ns
+}
+
+func (r Run) MarshalJSON() ([]byte, error) {
+	type plain Run
+	return json.Marshal(struct {
+		plain
+		BestFitness    number   `json:"bestFitness"`
+		BestPosition   []number `json:"bestPosition"`
+		Violation      number   `json:"violation,omitempty"`
+		GlobalConverge []number `json:"globalConverge"`
+	}{plain(r), number(r.BestFitness), numbers(r.BestPosition), number(r.Violation), numbers(r.GlobalConverge)})
+}
+
+func (r Restart) MarshalJSON() ([]byte, error) {
+	type plain Restart
+	return json.Marshal(struct {
+		plain
+		BestFitness number `json:"bestFitness"`
+	}{plain(r), number(r.BestFitness)})
+}
+
+func (f Front) MarshalJSON() ([]byte, error) {
+	type plain Front
+	return json.Marshal(struct {
+		plain
+		Hypervolume number `json:"hypervolume"`
+		IGD         number `json:"igd"`
+	}{plain(f), number(f.Hypervolume), number(f.IGD)})
+}
+
+func encode(r *Run) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	err := enc.Encode(r)
+	return buf.Bytes(), err
+}
+
+func decode(data []byte, r *Run) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(r)
+}
+
+// Store is the run history file
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens (or creates) the history at path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("runs: opening %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save records a run, filling in its ID (and host) when missing
+func (s *Store) Save(r *Run) error {
+	if r.ID == "" {
+		r.ID = xid.NewWithTime(r.Started).String()
+	}
+	if r.Host == "" {
+		r.Host, _ = os.Hostname()
+	}
+	data, err := encode(r)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(r.ID), data)
+	})
+}
+
+// Get returns the run with the ID
+func (s *Store) Get(id string) (Run, error) {
+	var r Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		return decode(data, &r)
+	})
+	return r, err
+}
+
+// Delete forgets a run
+func (s *Store) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// Filter selects runs, zero fields match anything
+type Filter struct {
+	Function  string
+	Algorithm string
+	Mode      string
+	N, K, T   int
+	Dim       int
+	Params    Params // Every one must match
+	Since     time.Time
+	Until     time.Time
+	Limit     int // Most recent runs only
+}
+
+// Match reports whether the run passes the filter
+func (f Filter) Match(r Run) bool {
+	switch {
+	case f.Function != "" && r.Function != f.Function,
+		f.Algorithm != "" && r.Algorithm != f.Algorithm,
+		f.Mode != "" && r.Mode != f.Mode,
+		f.N != 0 && r.N != f.N,
+		f.K != 0 && r.K != f.K,
+		f.T != 0 && r.T != f.T,
+		f.Dim != 0 && r.Dim != f.Dim,
+		!f.Since.IsZero() && r.Started.Before(f.Since),
+		!f.Until.IsZero() && !r.Started.Before(f.Until):
+		return false
+	}
+	for k, v := range f.Params {
+		if r.Params[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the matching runs, most recent first
+func (s *Store) List(f Filter) ([]Run, error) {
+	var runs []Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			var r Run
+			if err := decode(v, &r); err != nil {
+				return fmt.Errorf("runs: run %s: %w", k, err)
+			}
+			if !f.Match(r) {
+				continue
+			}
+			runs = append(runs, r)
+			if f.Limit > 0 && len(runs) == f.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return runs, err
+}
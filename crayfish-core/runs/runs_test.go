@@ -0,0 +1,128 @@
+package runs
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "runs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStore(t *testing.T) {
+	s := openStore(t)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	saved := []Run{
+		{Algorithm: "COA", Mode: "sync", Function: "F1", N: 30, K: 3, T: 100, Dim: 10, Seed: 1, Started: start,
+			Params: Params{"chunk": "10"}, BestFitness: 0.5, BestPosition: []float64{1, 2},
+			GlobalConverge: []float64{math.Inf(1), 3, 0.5}, Restarts: []Restart{{Island: 1, Iteration: 40, Reason: "stagnation", Size: 10, BestFitness: 2}}},
+		{Algorithm: "COA", Mode: "async", Function: "F6", N: 30, T: 100, Dim: 500, Started: start.Add(time.Hour), Host: "worker-1",
+			Front: &Front{Size: 4, Hypervolume: 0.8, IGD: 0.1}, Degraded: true},
+		{Algorithm: "COA", Mode: "async", Function: "F1", N: 60, T: 100, Dim: 10, Started: start.Add(2 * time.Hour), Elapsed: time.Minute},
+	}
+	for i := range saved {
+		if err := s.Save(&saved[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if saved[0].ID == "" || saved[0].ID >= saved[1].ID || saved[1].ID >= saved[2].ID {
+		t.Errorf("IDs %q, %q, %q; want them given in order of start", saved[0].ID, saved[1].ID, saved[2].ID)
+	}
+	if saved[0].Host == "" || saved[1].Host != "worker-1" {
+		t.Errorf("hosts %q and %q, want this one and worker-1", saved[0].Host, saved[1].Host)
+	}
+
+	for _, want := range saved {
+		got, err := s.Get(want.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Started.Equal(want.Started) {
+			t.Errorf("%s: started %v, want %v", want.ID, got.Started, want.Started)
+		}
+		got.Started = want.Started
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got back %+v, want %+v", want.ID, got, want)
+		}
+	}
+	if _, err := s.Get("nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown run: %v, want ErrNotFound", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int // Of saved
+	}{
+		{"everything", Filter{}, []int{2, 1, 0}},
+		{"benchmark", Filter{Function: "F1"}, []int{2, 0}},
+		{"mode and dimension", Filter{Mode: "async", Dim: 500}, []int{1}},
+		{"parameter", Filter{Params: Params{"chunk": "10"}}, []int{0}},
+		{"dates", Filter{Since: start.Add(time.Hour), Until: start.Add(2 * time.Hour)}, []int{1}},
+		{"limit", Filter{Limit: 2}, []int{2, 1}},
+		{"nothing", Filter{Function: "F2"}, nil},
+	}
+	for _, tt := range tests {
+		found, err := s.List(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids, want []string
+		for _, r := range found {
+			ids = append(ids, r.ID)
+		}
+		for _, i := range tt.want {
+			want = append(want, saved[i].ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: %v, want %v", tt.name, ids, want)
+		}
+	}
+
+	if err := s.Delete(saved[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(saved[1].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting twice: %v, want ErrNotFound", err)
+	}
+	if found, _ := s.List(Filter{}); len(found) != 2 {
+		t.Errorf("%d runs after the delete, want 2", len(found))
+	}
+}
+
+// What crayfish-runs show -json prints: the values JSON has no number for become strings
+func TestRunJSON(t *testing.T) {
+	r := Run{
+		ID: "r", Function: "F1", BestFitness: math.Inf(1), BestPosition: []float64{1, math.NaN()},
+		GlobalConverge: []float64{math.Inf(1), 2.5},
+		Front:          &Front{IGD: math.Inf(1)},
+		Restarts:       []Restart{{Reason: "diversity", BestFitness: math.Inf(-1)}},
+	}
+	out, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"bestFitness":"+Inf"`, `"bestPosition":[1,"NaN"]`, `"globalConverge":["+Inf",2.5]`,
+		`"igd":"+Inf"`, `"hypervolume":0`, `"bestFitness":"-Inf"`, `"reason":"diversity"`, `"function":"F1"`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("%s has no %s", out, want)
+		}
+	}
+	if strings.Contains(string(out), "violation") || strings.Count(string(out), `"bestFitness"`) != 2 {
+		t.Errorf("%s: want the fields once, violation left out", out)
+	}
+}
@@ -0,0 +1,208 @@
+// Package tracing follows a job across the publisher, the broker and the workers with OpenTelemetry.
+// The trace context travels with every message, in the transport's own headers where it has some
+// (AMQP headers, Redis stream fields, HTTP headers) and in the message's "trace" field otherwise
+// (Redis Pub/Sub). Spans go to an OTLP collector, or to a file for testing; without an exporter
+// the spans are no-ops.
+package tracing
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters understood by Setup
+const (
+	ExporterNone = ""
+	ExporterOTLP = "otlp" // OTLP over HTTP to Endpoint
+	ExporterFile = "file" // One JSON document per span in File
+)
+
+// Config of the tracing
+type Config struct {
+	Exporter    string  `yaml:"exporter"`     // "", "otlp" or "file"
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP collector host:port, localhost:4318 when empty
+	Insecure    bool    `yaml:"insecure"`     // Plain HTTP to the collector
+	File        string  `yaml:"file"`         // For the file exporter
+	Service     string  `yaml:"service"`      // service.name, the program's name when empty
+	SampleRatio float64 `yaml:"sample_ratio"` // Share of the jobs traced, 1: all
+}
+
+// DefaultConfig traces nothing until an exporter is chosen
+func DefaultConfig() Config {
+	return Config{Insecure: true, SampleRatio: 1}
+}
+
+// RegisterFlags binds the configuration to -trace-* flags
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Exporter, "trace-exporter", c.Exporter, "export spans: otlp or file (empty: no tracing)")
+	fs.StringVar(&c.Endpoint, "trace-endpoint", c.Endpoint, "OTLP/HTTP collector host:port (default localhost:4318)")
+	fs.BoolVar(&c.Insecure, "trace-insecure", c.Insecure, "send the spans to the collector over plain HTTP")
+	fs.StringVar(&c.File, "trace-file", c.File, "file the spans are written to with -trace-exporter file")
+	fs.StringVar(&c.Service, "trace-service", c.Service, "service name of the spans (default: the program's name)")
+	fs.Float64Var(&c.SampleRatio, "trace-sample", c.SampleRatio, "share of the jobs traced, between 0 and 1")
+}
+
+var provider *sdktrace.TracerProvider
+
+// Setup installs the tracer provider and the W3C propagators, Shutdown flushes the spans
+func Setup(ctx context.Context, c Config) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch c.Exporter {
+	case ExporterNone:
+		return nil
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	case ExporterFile:
+		if c.File == "" {
+			return fmt.Errorf("tracing: the file exporter needs a file")
+		}
+		var f *os.File
+		if f, err = os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+		}
+	default:
+		return fmt.Errorf("tracing: unknown exporter %q (otlp or file)", c.Exporter)
+	}
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+
+	service := c.Service
+	if service == "" {
+		service = filepath.Base(os.Args[0])
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service)))
+	if err != nil {
+		return fmt.Errorf("tracing: %w", err)
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// Shutdown sends the spans still buffered, nothing when tracing is off
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start starts a span of the crayfish tracer
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer("crayfish").Start(ctx, name, opts...)
+}
+
+// Fail records err on the span and passes it on
+func Fail(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// Inject returns the trace context of ctx as a message field (nil outside of a trace)
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract continues the trace context of a message in ctx
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if len(carrier) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// Table turns a trace context into AMQP headers (nil without one)
+func Table(carrier map[string]string) amqp.Table {
+	if len(carrier) == 0 {
+		return nil
+	}
+	headers := make(amqp.Table, len(carrier))
+	for k, v := range carrier {
+		headers[k] = v
+	}
+	return headers
+}
+
+// FromTable picks the trace context out of AMQP headers
+func FromTable(headers amqp.Table) map[string]string {
+	return fromValues(headers)
+}
+
+// AddFields adds a trace context to the fields of a Redis stream entry
+func AddFields(values map[string]interface{}, carrier map[string]string) {
+	for k, v := range carrier {
+		values[k] = v
+	}
+}
+
+// FromFields picks the trace context out of the fields of a Redis stream entry
+func FromFields(values map[string]interface{}) map[string]string {
+	return fromValues(values)
+}
+
+// FromHTTP picks the trace context out of HTTP request headers
+func FromHTTP(header http.Header) map[string]string {
+	var carrier map[string]string
+	for _, key := range otel.GetTextMapPropagator().Fields() {
+		if v := header.Get(key); v != "" {
+			if carrier == nil {
+				carrier = make(map[string]string)
+			}
+			carrier[key] = v
+		}
+	}
+	return carrier
+}
+
+func fromValues(values map[string]interface{}) map[string]string {
+	var carrier map[string]string
+	for _, key := range otel.GetTextMapPropagator().Fields() {
+		if v, ok := values[key].(string); ok {
+			if carrier == nil {
+				carrier = make(map[string]string)
+			}
+			carrier[key] = v
+		}
+	}
+	return carrier
+}
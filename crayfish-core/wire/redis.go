@@ -0,0 +1,101 @@
+package wire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field names of a Redis stream entry carrying an encoded message
+const (
+	FieldContentType = "contentType"
+	FieldPayload     = "payload"
+)
+
+// StreamValues wraps an encoded payload into the fields of a stream entry (for XAdd)
+func StreamValues(payload []byte, contentType string) map[string]interface{} {
+	return map[string]interface{}{
+		FieldContentType: contentType,
+		FieldPayload:     string(payload), // go-redis hands the fields back as strings anyway
+	}
+}
+
+// Pull the payload and its content type out of a stream entry
+func streamPayload(values map[string]interface{}) ([]byte, string, bool) {
+	payload, ok := values[FieldPayload].(string)
+	if !ok {
+		return nil, "", false
+	}
+	contentType, _ := values[FieldContentType].(string)
+	if contentType == "" {
+		contentType = Sniff([]byte(payload))
+	}
+	return []byte(payload), contentType, true
+}
+
+// TaskStreamValues encodes a task as a stream entry
+func TaskStreamValues(t Task, contentType string) (map[string]interface{}, error) {
+	payload, err := EncodeTask(t, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return StreamValues(payload, contentType), nil
+}
+
+// TaskFromStream decodes a task written with TaskStreamValues
+func TaskFromStream(values map[string]interface{}) (Task, error) {
+	payload, contentType, ok := streamPayload(values)
+	if !ok {
+		return Task{}, fmt.Errorf("wire: stream entry has no %q field", FieldPayload)
+	}
+	return DecodeTask(payload, contentType)
+}
+
+// ResultStreamValues encodes a result as a stream entry
+func ResultStreamValues(r Result, contentType string) (map[string]interface{}, error) {
+	payload, err := EncodeResult(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return StreamValues(payload, contentType), nil
+}
+
+// ResultFromStream decodes a result entry. Entries written by the old Streams publisher (one field per
+// value, vectors formatted with %v) are still understood.
+func ResultFromStream(values map[string]interface{}) (Result, error) {
+	if payload, contentType, ok := streamPayload(values); ok {
+		return DecodeResult(payload, contentType)
+	}
+
+	var (
+		r   Result
+		err error
+	)
+	bestFit, _ := values["bestFitness"].(string)
+	if r.BestFitness, err = strconv.ParseFloat(bestFit, 64); err != nil {
+		return r, fmt.Errorf("wire: legacy bestFitness: %w", err)
+	}
+	bestPos, _ := values["bestPosition"].(string)
+	if r.BestPosition, err = parseFloatSlice(bestPos); err != nil {
+		return r, fmt.Errorf("wire: legacy bestPosition: %w", err)
+	}
+	globalCov, _ := values["globalCov"].(string)
+	if r.GlobalConverge, err = parseFloatSlice(globalCov); err != nil {
+		return r, fmt.Errorf("wire: legacy globalCov: %w", err)
+	}
+	return r, nil
+}
+
+// Parse a slice printed with %v ("[1 2 3]")
+func parseFloatSlice(s string) ([]float64, error) {
+	fields := strings.Fields(strings.Trim(s, "[]"))
+	result := make([]float64, 0, len(fields))
+	for _, str := range fields {
+		f, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
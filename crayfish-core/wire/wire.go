@@ -0,0 +1,387 @@
+// Package wire defines the messages exchanged between the publishers and the workers (sub-population
+// tasks and their results) and how they are encoded on RabbitMQ and Redis. The schema is documented
+// in SCHEMA.md so that workers written in other languages can consume the same queues.
+package wire
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Version of the schema written by this package. Messages without a version (0) are legacy ones.
+const SchemaVersion = 1
+
+// Content types understood by the codecs (sent in the AMQP content-type / Redis "contentType" field)
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeMsgpack = "application/msgpack"
+	ContentTypeGob     = "application/octet-stream" // Legacy Go-only encoding, decode only
+)
+
+// Task carries one sub-population to a worker
+type Task struct {
+	Version       int         `json:"version" msgpack:"version"`
+	JobID         string      `json:"jobId,omitempty" msgpack:"jobId,omitempty"`
+	Index         int         `json:"index" msgpack:"index"`     // Index of the sub-population
+	Workers       int         `json:"workers" msgpack:"workers"` // Number of sub-populations in the job
+	Function      string      `json:"function" msgpack:"function"`
+	T             int         `json:"t,omitempty" msgpack:"t,omitempty"` // Iterations, worker default when 0
+	SubPopulation [][]float64 `json:"subPopulation" msgpack:"subPopulation"`
+
+	// Optional: the algorithm (AlgorithmCOA when empty), its variant (see coa.VariantNames) and its
+	// parameters by name, see coa.ParamNames
+	Algorithm string `json:"algorithm,omitempty" msgpack:"algorithm,omitempty"`
+	Variant   string `json:"variant,omitempty" msgpack:"variant,omitempty"`
+	Params    Params `json:"params,omitempty" msgpack:"params,omitempty"`
+
+	// Optional: seed of the worker's random source, bounds overriding the benchmark's, and the size
+	// and dimension of the sub-population the worker generates itself when SubPopulation is empty
+	Seed int64     `json:"seed,omitempty" msgpack:"seed,omitempty"`
+	LB   []float64 `json:"lb,omitempty" msgpack:"lb,omitempty"`
+	UB   []float64 `json:"ub,omitempty" msgpack:"ub,omitempty"`
+	Size int       `json:"size,omitempty" msgpack:"size,omitempty"`
+	Dim  int       `json:"dim,omitempty" msgpack:"dim,omitempty"`
+
+	// Seed form: the sub-population is the Index-th of Workers slices of the PopulationSize x Dim
+	// population drawn from Seed (see SCHEMA.md), Hash is PopulationHash of the slice
+	PopulationSize int    `json:"populationSize,omitempty" msgpack:"populationSize,omitempty"`
+	Hash           string `json:"hash,omitempty" msgpack:"hash,omitempty"`
+	Init           string `json:"init,omitempty" msgpack:"init,omitempty"` // Initializer drawing it, coa.InitUniform when empty
+
+	// Epoch of a coordinated run: run Iterations iterations starting at iteration Start of T,
+	// with GlobalPosition (the best of all islands so far) as the best position
+	Epoch          int       `json:"epoch,omitempty" msgpack:"epoch,omitempty"`
+	Start          int       `json:"start,omitempty" msgpack:"start,omitempty"`
+	Iterations     int       `json:"iterations,omitempty" msgpack:"iterations,omitempty"`
+	GlobalPosition []float64 `json:"globalPosition,omitempty" msgpack:"globalPosition,omitempty"`
+	GlobalFitness  float64   `json:"globalFitness,omitempty" msgpack:"globalFitness,omitempty"`
+
+	// From the second epoch on: the state the island's optimizer ended the previous one with
+	State *EpochState `json:"state,omitempty" msgpack:"state,omitempty"`
+
+	// Asynchronous run (Mode "async"): run all T iterations in chunks of Chunk, pulling the global
+	// best from the shared store before each chunk (and within one once the copy is older than
+	// MaxAgeMs) and pushing improvements right away
+	Mode     string `json:"mode,omitempty" msgpack:"mode,omitempty"`
+	Chunk    int    `json:"chunk,omitempty" msgpack:"chunk,omitempty"`
+	MaxAgeMs int64  `json:"maxAgeMs,omitempty" msgpack:"maxAgeMs,omitempty"`
+
+	// Checkpointed run: the state is saved under the key Checkpoint every CheckpointEvery
+	// iterations and when BudgetMs is spent, and the task continues from it when solved again
+	Checkpoint      string `json:"checkpoint,omitempty" msgpack:"checkpoint,omitempty"`
+	CheckpointEvery int    `json:"checkpointEvery,omitempty" msgpack:"checkpointEvery,omitempty"`
+	BudgetMs        int64  `json:"budgetMs,omitempty" msgpack:"budgetMs,omitempty"`
+
+	// Record what every iteration did and send it back in Result.Stages
+	Stages bool `json:"stages,omitempty" msgpack:"stages,omitempty"`
+
+	// Record the diversity of the sub-population after every iteration in Result.Diversity
+	Diversity bool `json:"diversity,omitempty" msgpack:"diversity,omitempty"`
+
+	// Optional: start over when the sub-population stagnates or collapses
+	Restart *Restart `json:"restart,omitempty" msgpack:"restart,omitempty"`
+
+	// Optional: how the constraints of a constrained benchmark are handled, Deb's rules when missing
+	Constraints *Constraints `json:"constraints,omitempty" msgpack:"constraints,omitempty"`
+
+	// Multi-objective benchmark: the Pareto archive of the run (defaults when missing) and, from the
+	// second epoch of a coordinated run on, the front of all islands so far
+	Pareto *Pareto `json:"pareto,omitempty" msgpack:"pareto,omitempty"`
+	Front  *Front  `json:"front,omitempty" msgpack:"front,omitempty"`
+
+	// Optional: the channel, stream or queue the result goes to instead of the shared results, the
+	// job's own (see broker.JobResults) so that concurrent coordinators don't see each other's results
+	ReplyTo string `json:"replyTo,omitempty" msgpack:"replyTo,omitempty"`
+
+	// Trace context (W3C traceparent and tracestate) where the transport has no headers for it
+	Trace map[string]string `json:"trace,omitempty" msgpack:"trace,omitempty"`
+}
+
+// Params of the algorithm by name, the others keep their defaults
+type Params map[string]float64
+
+// AlgorithmCOA is the crayfish optimization algorithm
+const AlgorithmCOA = "coa"
+
+// Modes of a task
+const (
+	ModeSync  = ""      // Whole run or one epoch, see Iterations
+	ModeAsync = "async" // Steady-state, sharing the global best through a store
+)
+
+// PopulationHash fingerprints a (sub-)population so the coordinator and a worker can check they
+// start from the same crayfish: SHA-256 over the values row by row as little-endian float64, in hex
+func PopulationHash(X [][]float64) string {
+	h := sha256.New()
+	var buf [8]byte
+	for _, row := range X {
+		for _, v := range row {
+			binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+			h.Write(buf[:])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Result is what a worker sends back for a sub-population (the JSON field names are the ones the
+// Fire&Forget publisher always used, so older subscribers keep working)
+type Result struct {
+	Version        int       `json:"version" msgpack:"version"`
+	JobID          string    `json:"jobId,omitempty" msgpack:"jobId,omitempty"`
+	Index          int       `json:"index" msgpack:"index"`
+	Workers        int       `json:"workers,omitempty" msgpack:"workers,omitempty"` // Results the job publishes in all
+	BestPosition   []float64 `json:"bestPosition" msgpack:"bestPosition"`
+	BestFitness    float64   `json:"bestFitness" msgpack:"bestFitness"`
+	GlobalConverge []float64 `json:"globalConverge" msgpack:"globalConverge"`
+
+	// Constrained benchmark: the constraint violation of BestPosition, 0 (missing) when feasible
+	Violation float64 `json:"violation,omitempty" msgpack:"violation,omitempty"`
+
+	// Multi-objective benchmark: the archive as the run (or epoch) ends it. BestPosition is then its
+	// member of the smallest sum of objectives, BestFitness that sum.
+	Front *Front `json:"front,omitempty" msgpack:"front,omitempty"`
+
+	// Epoch of a coordinated run and the sub-population as it ends the epoch (for the next one)
+	Epoch      int         `json:"epoch,omitempty" msgpack:"epoch,omitempty"`
+	Population [][]float64 `json:"population,omitempty" msgpack:"population,omitempty"`
+	State      *EpochState `json:"state,omitempty" msgpack:"state,omitempty"` // ... and the optimizer's state, for the next one
+
+	// Asynchronous run: how stale the worker's copies of the global best were
+	Staleness *Staleness `json:"staleness,omitempty" msgpack:"staleness,omitempty"`
+
+	// Checkpointed run: Partial when the budget ran out before T, Iteration is how far it got
+	Partial   bool `json:"partial,omitempty" msgpack:"partial,omitempty"`
+	Iteration int  `json:"iteration,omitempty" msgpack:"iteration,omitempty"`
+
+	// When Task.Stages is set: what every iteration of the run (or epoch) did
+	Stages []Stage `json:"stages,omitempty" msgpack:"stages,omitempty"`
+
+	// When Task.Diversity is set: the diversity of the sub-population after every iteration
+	Diversity []Diversity `json:"diversity,omitempty" msgpack:"diversity,omitempty"`
+
+	// The sub-population collapsed (param min_diversity) and stopped before T, GlobalConverge
+	// repeats its last best fitness from there
+	Collapsed bool `json:"collapsed,omitempty" msgpack:"collapsed,omitempty"`
+
+	// The restarts of the run (or epoch), when the task has a Restart
+	Restarts []RestartEvent `json:"restarts,omitempty" msgpack:"restarts,omitempty"`
+
+	// Trace context, as in Task
+	Trace map[string]string `json:"trace,omitempty" msgpack:"trace,omitempty"`
+
+	// Where the result is published, the task's ReplyTo; the brokers route on it, it isn't sent
+	ReplyTo string `json:"-" msgpack:"-"`
+}
+
+// Staleness of a worker's copies of the global best, measured at each pull
+type Staleness struct {
+	Refreshes  int     `json:"refreshes" msgpack:"refreshes"`   // Pulls of the global best
+	Forced     int     `json:"forced" msgpack:"forced"`         // ... within a chunk because the copy was too old
+	Pushes     int     `json:"pushes" msgpack:"pushes"`         // Improvements the worker pushed
+	Missed     int64   `json:"missed" msgpack:"missed"`         // Improvements made elsewhere between pulls, summed
+	MaxMissed  int64   `json:"maxMissed" msgpack:"maxMissed"`   // ... the most at one pull
+	TotalAgeMs float64 `json:"totalAgeMs" msgpack:"totalAgeMs"` // Age of the copy at each pull, summed
+	MaxAgeMs   float64 `json:"maxAgeMs" msgpack:"maxAgeMs"`     // ... the oldest
+}
+
+// Stage is what one iteration of COA did (coa.Stage)
+type Stage struct {
+	Iteration   int     `json:"iteration" msgpack:"iteration"`
+	Temperature float64 `json:"temperature" msgpack:"temperature"`
+	C           float64 `json:"c" msgpack:"c"`
+	Summer      int     `json:"summer" msgpack:"summer"`           // Crayfish in the summer resort stage
+	Competition int     `json:"competition" msgpack:"competition"` // ... competing for a cave
+	Shredding   int     `json:"shredding" msgpack:"shredding"`     // ... foraging with food larger than FoodSize
+	Eating      int     `json:"eating" msgpack:"eating"`           // ... foraging with food they eat directly
+	FoodMean    float64 `json:"foodMean" msgpack:"foodMean"`       // Food size P over the foraging crayfish
+	FoodMax     float64 `json:"foodMax" msgpack:"foodMax"`
+	Accepted    int     `json:"accepted" msgpack:"accepted"` // Moves kept because they improved the crayfish
+	StepMean    float64 `json:"stepMean" msgpack:"stepMean"` // Euclidean length of the moves
+	StepMax     float64 `json:"stepMax" msgpack:"stepMax"`
+	Variant     int     `json:"variant,omitempty" msgpack:"variant,omitempty"` // Crayfish improved by the task's variant after the moves
+}
+
+// Restart policy of a task (coa.Restart)
+type Restart struct {
+	Strategy   string  `json:"strategy" msgpack:"strategy"`                         // "elite", "ipop" or "opposition"
+	Stagnation int     `json:"stagnation,omitempty" msgpack:"stagnation,omitempty"` // Iterations without improvement, 0: on collapse only
+	Growth     float64 `json:"growth,omitempty" msgpack:"growth,omitempty"`         // ipop: population multiplier, 2 when 0
+	Share      float64 `json:"share,omitempty" msgpack:"share,omitempty"`           // opposition: share reinitialized, 0.5 when 0
+	Max        int     `json:"max,omitempty" msgpack:"max,omitempty"`               // Restarts per run, 0: no limit
+	MaxSize    int     `json:"maxSize,omitempty" msgpack:"maxSize,omitempty"`       // ipop: the largest population, 1000 when 0
+}
+
+// Constraints is how a task handles the constraints of its benchmark (coa.Constraints)
+type Constraints struct {
+	Handling  string  `json:"handling,omitempty" msgpack:"handling,omitempty"`   // "static", "dynamic", "deb" or "epsilon"
+	Penalty   float64 `json:"penalty,omitempty" msgpack:"penalty,omitempty"`     // static: factor, 1e6 when 0; dynamic: C, 0.5 when 0
+	Tolerance float64 `json:"tolerance,omitempty" msgpack:"tolerance,omitempty"` // Of the equalities, 1e-4 when 0
+}
+
+// Pareto archive of a multi-objective task (coa.Pareto)
+type Pareto struct {
+	Size    int    `json:"size,omitempty" msgpack:"size,omitempty"`       // Most members, 100 when 0
+	Pruning string `json:"pruning,omitempty" msgpack:"pruning,omitempty"` // "crowding" (default) or "grid"
+	Grid    int    `json:"grid,omitempty" msgpack:"grid,omitempty"`       // grid: divisions per objective, 10 when 0
+}
+
+// Front is a Pareto front, positions and their objectives row by row (coa.Front)
+type Front struct {
+	Positions   [][]float64 `json:"positions" msgpack:"positions"`
+	Objectives  [][]float64 `json:"objectives" msgpack:"objectives"`
+	Hypervolume float64     `json:"hypervolume,omitempty" msgpack:"hypervolume,omitempty"` // Set by the coordinator
+	IGD         float64     `json:"igd,omitempty" msgpack:"igd,omitempty"`
+}
+
+// RestartEvent is one restart of a worker's run (coa.RestartEvent)
+type RestartEvent struct {
+	Iteration   int     `json:"iteration" msgpack:"iteration"` // Last iteration before the restart
+	Reason      string  `json:"reason" msgpack:"reason"`       // "stagnation" or "diversity"
+	Size        int     `json:"size" msgpack:"size"`           // Crayfish after the restart
+	BestFitness float64 `json:"bestFitness" msgpack:"bestFitness"`
+}
+
+// EpochState of an island's optimizer between two epochs (coa.EpochState)
+type EpochState struct {
+	Stagnant  int      `json:"stagnant,omitempty" msgpack:"stagnant,omitempty"`   // Iterations without a better best position
+	MaxSpread float64  `json:"maxSpread,omitempty" msgpack:"maxSpread,omitempty"` // Largest spread so far, exploration is relative to it
+	Chaos     float64  `json:"chaos,omitempty" msgpack:"chaos,omitempty"`         // chaotic: state of the logistic map
+	CScale    float64  `json:"cScale,omitempty" msgpack:"cScale,omitempty"`       // adaptive: scale of C
+	LSStep    float64  `json:"lsStep,omitempty" msgpack:"lsStep,omitempty"`       // hybrid: step of the pattern search
+	Epsilon   *float64 `json:"epsilon,omitempty" msgpack:"epsilon,omitempty"`     // epsilon constraint handling: ε0 once known
+}
+
+// Diversity of a sub-population after one iteration (coa.Diversity)
+type Diversity struct {
+	Iteration    int       `json:"iteration" msgpack:"iteration"`
+	Pairwise     float64   `json:"pairwise" msgpack:"pairwise"` // Mean distance between two crayfish
+	Centroid     float64   `json:"centroid" msgpack:"centroid"` // Mean distance to the centroid
+	Relative     float64   `json:"relative" msgpack:"relative"` // ... over the diagonal of the bounds
+	Std          []float64 `json:"std" msgpack:"std"`           // Per dimension
+	Spread       float64   `json:"spread" msgpack:"spread"`     // Mean distance to the median, over the dimensions
+	Exploration  float64   `json:"exploration" msgpack:"exploration"`
+	Exploitation float64   `json:"exploitation" msgpack:"exploitation"`
+}
+
+// Gob layouts of the RabbitMQ messages before this schema existed
+type legacyMessage struct {
+	SubPopulation [][]float64
+	Workers       int
+	F             string
+	Index         int
+}
+
+type legacyResult struct {
+	Index          int
+	BestPosition   []float64
+	BestFitness    float64
+	GlobalConverge []float64
+}
+
+// Normalize a content type header ("application/json; charset=utf-8" -> "application/json")
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Supported reports whether the content type can be decoded
+func Supported(contentType string) bool {
+	switch mediaType(contentType) {
+	case ContentTypeJSON, ContentTypeMsgpack, ContentTypeGob:
+		return true
+	}
+	return false
+}
+
+// Sniff guesses the content type of a payload that came without one (Redis Pub/Sub)
+func Sniff(data []byte) string {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return ContentTypeJSON
+	}
+	return ContentTypeMsgpack
+}
+
+func marshal(v interface{}, contentType string) ([]byte, error) {
+	switch mediaType(contentType) {
+	case ContentTypeJSON:
+		return json.Marshal(v)
+	case ContentTypeMsgpack:
+		return msgpack.Marshal(v)
+	}
+	return nil, fmt.Errorf("wire: cannot encode content type %q", contentType)
+}
+
+func unmarshal(data []byte, contentType string, v interface{}) error {
+	switch mediaType(contentType) {
+	case ContentTypeJSON:
+		return json.Unmarshal(data, v)
+	case ContentTypeMsgpack:
+		return msgpack.Unmarshal(data, v)
+	}
+	return fmt.Errorf("wire: cannot decode content type %q", contentType)
+}
+
+func checkVersion(v int) error {
+	if v < 0 || v > SchemaVersion {
+		return fmt.Errorf("wire: unsupported schema version %d (know up to %d)", v, SchemaVersion)
+	}
+	return nil
+}
+
+// EncodeTask encodes a task in the given content type, stamping the schema version
+func EncodeTask(t Task, contentType string) ([]byte, error) {
+	t.Version = SchemaVersion
+	return marshal(t, contentType)
+}
+
+// DecodeTask decodes a task, including the gob messages of the old RabbitMQ publisher
+func DecodeTask(data []byte, contentType string) (Task, error) {
+	var t Task
+	if mediaType(contentType) == ContentTypeGob || contentType == "" {
+		var m legacyMessage
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+			return t, fmt.Errorf("wire: decoding legacy gob task: %w", err)
+		}
+		return Task{Index: m.Index, Workers: m.Workers, Function: m.F, SubPopulation: m.SubPopulation}, nil
+	}
+
+	if err := unmarshal(data, contentType, &t); err != nil {
+		return t, err
+	}
+	return t, checkVersion(t.Version)
+}
+
+// EncodeResult encodes a result in the given content type, stamping the schema version
+func EncodeResult(r Result, contentType string) ([]byte, error) {
+	r.Version = SchemaVersion
+	return marshal(r, contentType)
+}
+
+// DecodeResult decodes a result, including the gob results of the old RabbitMQ consumer
+func DecodeResult(data []byte, contentType string) (Result, error) {
+	var r Result
+	if mediaType(contentType) == ContentTypeGob || contentType == "" {
+		var m legacyResult
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+			return r, fmt.Errorf("wire: decoding legacy gob result: %w", err)
+		}
+		return Result{Index: m.Index, BestPosition: m.BestPosition, BestFitness: m.BestFitness, GlobalConverge: m.GlobalConverge}, nil
+	}
+
+	if err := unmarshal(data, contentType, &r); err != nil {
+		return r, err
+	}
+	return r, checkVersion(r.Version)
+}
@@ -0,0 +1,266 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+// A task using most of the schema
+func fullTask() Task {
+	return Task{
+		JobID:          "job",
+		Index:          2,
+		Workers:        4,
+		Function:       "F6",
+		T:              500,
+		SubPopulation:  [][]float64{{0.5, -1.25}, {3, 1e-9}},
+		Algorithm:      AlgorithmCOA,
+		Variant:        "levy",
+		Params:         Params{"c3": 2.5},
+		Seed:           42,
+		LB:             []float64{-100},
+		UB:             []float64{100},
+		Epoch:          1,
+		Start:          100,
+		Iterations:     100,
+		GlobalPosition: []float64{0.1, 0.2},
+		GlobalFitness:  0.05,
+		State:          &EpochState{Stagnant: 12, MaxSpread: 3.5, CScale: 1.1},
+		Restart:        &Restart{Strategy: "ipop", Stagnation: 20},
+		Constraints:    &Constraints{Handling: "deb"},
+		Pareto:         &Pareto{Size: 50, Pruning: "grid"},
+		Front:          &Front{Positions: [][]float64{{0, 1}}, Objectives: [][]float64{{1, 0}}},
+		ReplyTo:        "optimization_results:job",
+		Trace:          map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}
+}
+
+func fullResult() Result {
+	return Result{
+		JobID:          "job",
+		Index:          2,
+		Workers:        4,
+		BestPosition:   []float64{0.1, -0.2},
+		BestFitness:    0.05,
+		GlobalConverge: []float64{3, 1, 0.05},
+		Violation:      0.25,
+		Epoch:          1,
+		Population:     [][]float64{{0.1, -0.2}},
+		State:          &EpochState{Stagnant: 30, Chaos: 0.3},
+		Staleness:      &Staleness{Refreshes: 3, MaxAgeMs: 12.5},
+		Stages:         []Stage{{Iteration: 0, Temperature: 31, C: 2, Summer: 3}},
+		Diversity:      []Diversity{{Iteration: 0, Pairwise: 1.5, Std: []float64{1, 2}}},
+		Restarts:       []RestartEvent{{Iteration: 40, Reason: "stagnation", Size: 20, BestFitness: 1}},
+		Trace:          map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}
+}
+
+func TestTaskRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		task        Task
+	}{
+		{"json", ContentTypeJSON, fullTask()},
+		{"msgpack", ContentTypeMsgpack, fullTask()},
+		{"json with parameters", "application/json; charset=utf-8", fullTask()},
+		{"msgpack seed form", ContentTypeMsgpack, Task{Function: "F1", Workers: 2, Seed: 7, Size: 5, Dim: 30, PopulationSize: 10, Hash: "ab"}},
+		{"json minimal", ContentTypeJSON, Task{Function: "F1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeTask(tt.task, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeTask(data, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.task
+			want.Version = SchemaVersion
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestResultRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		result      Result
+	}{
+		{"json", ContentTypeJSON, fullResult()},
+		{"msgpack", ContentTypeMsgpack, fullResult()},
+		{"json partial", ContentTypeJSON, Result{Index: 1, BestPosition: []float64{1}, BestFitness: 1, GlobalConverge: []float64{1}, Partial: true, Iteration: 50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeResult(tt.result, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeResult(data, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.result
+			want.Version = SchemaVersion
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+// A result's ReplyTo routes it, it isn't part of the message
+func TestResultReplyToNotSent(t *testing.T) {
+	r := fullResult()
+	r.ReplyTo = "optimization_results:job"
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeMsgpack} {
+		data, err := EncodeResult(r, contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte(r.ReplyTo)) {
+			t.Errorf("%s: the encoded result carries its ReplyTo", contentType)
+		}
+	}
+}
+
+// What the original publishers and workers sent: gob, without a content type on Redis
+func TestLegacyGob(t *testing.T) {
+	var task, result bytes.Buffer
+	if err := gob.NewEncoder(&task).Encode(legacyMessage{SubPopulation: [][]float64{{1, 2}, {3, 4}}, Workers: 3, F: "F9", Index: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewEncoder(&result).Encode(legacyResult{Index: 1, BestPosition: []float64{0.5}, BestFitness: 0.25, GlobalConverge: []float64{1, 0.25}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, contentType := range []string{ContentTypeGob, ""} {
+		gotTask, err := DecodeTask(task.Bytes(), contentType)
+		if err != nil {
+			t.Fatalf("task as %q: %v", contentType, err)
+		}
+		if want := (Task{Index: 1, Workers: 3, Function: "F9", SubPopulation: [][]float64{{1, 2}, {3, 4}}}); !reflect.DeepEqual(gotTask, want) {
+			t.Errorf("task as %q: %+v, want %+v", contentType, gotTask, want)
+		}
+		gotResult, err := DecodeResult(result.Bytes(), contentType)
+		if err != nil {
+			t.Fatalf("result as %q: %v", contentType, err)
+		}
+		if want := (Result{Index: 1, BestPosition: []float64{0.5}, BestFitness: 0.25, GlobalConverge: []float64{1, 0.25}}); !reflect.DeepEqual(gotResult, want) {
+			t.Errorf("result as %q: %+v, want %+v", contentType, gotResult, want)
+		}
+	}
+
+	if _, err := EncodeTask(Task{}, ContentTypeGob); err == nil {
+		t.Error("encoding gob: want an error, it is decode only")
+	}
+	if _, err := DecodeTask([]byte("not gob"), ContentTypeGob); err == nil {
+		t.Error("decoding garbage as gob: want an error")
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		contentType string
+	}{
+		{"future version", `{"version": 2, "function": "F1"}`, ContentTypeJSON},
+		{"negative version", `{"version": -1, "function": "F1"}`, ContentTypeJSON},
+		{"unknown content type", `{}`, "text/plain"},
+		{"malformed json", `{"version": 1`, ContentTypeJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeTask([]byte(tt.data), tt.contentType); err == nil {
+				t.Error("DecodeTask: want an error")
+			}
+			if _, err := DecodeResult([]byte(tt.data), tt.contentType); err == nil {
+				t.Error("DecodeResult: want an error")
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	msgpackTask, err := EncodeTask(fullTask(), ContentTypeMsgpack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonTask, err := EncodeTask(fullTask(), ContentTypeJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"json", jsonTask, ContentTypeJSON},
+		{"json after whitespace", []byte(" \r\n\t{}"), ContentTypeJSON},
+		{"msgpack", msgpackTask, ContentTypeMsgpack},
+		{"empty", nil, ContentTypeMsgpack},
+		{"json array", []byte("[1]"), ContentTypeMsgpack}, // Not a message
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sniff(tt.data); got != tt.want {
+				t.Errorf("Sniff = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// What the Pub/Sub subscriber does with a payload of unknown encoding
+	got, err := DecodeTask(msgpackTask, Sniff(msgpackTask))
+	if err != nil || got.JobID != "job" {
+		t.Errorf("decoding a sniffed msgpack task: %+v, %v", got, err)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for contentType, want := range map[string]bool{
+		ContentTypeJSON:                   true,
+		"Application/MsgPack":             true,
+		"application/json; charset=utf-8": true,
+		ContentTypeGob:                    true,
+		"text/plain":                      false,
+		"":                                false,
+	} {
+		if got := Supported(contentType); got != want {
+			t.Errorf("Supported(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestStreamValues(t *testing.T) {
+	values, err := ResultStreamValues(fullResult(), ContentTypeMsgpack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ResultFromStream(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fullResult()
+	want.Version = SchemaVersion
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v\nwant %+v", got, want)
+	}
+
+	// The old Streams publisher wrote one field per value, printed with %v
+	legacy, err := ResultFromStream(map[string]interface{}{"bestFitness": "0.5", "bestPosition": "[1 2.5]", "globalCov": "[3 0.5]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Result{BestFitness: 0.5, BestPosition: []float64{1, 2.5}, GlobalConverge: []float64{3, 0.5}}); !reflect.DeepEqual(legacy, want) {
+		t.Errorf("legacy entry: %+v, want %+v", legacy, want)
+	}
+}
@@ -2,4 +2,17 @@ module rabbit-test
 
 go 1.21.1
 
-require github.com/streadway/amqp v1.1.0
+require (
+	crayfish v0.0.0
+	github.com/rs/xid v1.5.0
+	github.com/streadway/amqp v1.1.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+)
+
+replace crayfish => ../crayfish-core